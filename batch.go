@@ -0,0 +1,229 @@
+package httpio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// BatchFormat is the wire format of a batch request
+type BatchFormat int
+
+const (
+	// BatchJSONRPC sends the calls as a JSON-RPC 2.0 batch, a JSON array of request objects
+	BatchJSONRPC BatchFormat = iota
+	// BatchOData sends the requests as an OData $batch multipart/mixed body with one
+	// application/http part per request. Change sets are not supported.
+	BatchOData
+)
+
+// BatchRequest collects sub-requests sent to a batch endpoint in a single HTTP call
+type BatchRequest struct {
+	client *Client
+	format BatchFormat
+	path   string
+	items  []batchItem
+}
+
+// batchItem is a JSON-RPC call or an OData sub-request
+type batchItem struct {
+	method string
+	path   string
+	body   interface{}
+}
+
+// BatchResult is the outcome of a single sub-request of a batch
+type BatchResult struct {
+	// StatusCode is the status of an OData sub-response; it is 0 for JSON-RPC results
+	StatusCode int
+	// Header holds the headers of an OData sub-response
+	Header http.Header
+	// Body is the JSON-RPC result or the body of the OData sub-response
+	Body json.RawMessage
+	// Err is the *JSONRPCError returned for a failed JSON-RPC call
+	Err error
+}
+
+// Decode unmarshals the body of the result into v
+func (r BatchResult) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+// JSONRPCError is the error object of a failed JSON-RPC call
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewBatch creates a batch request sent to path in the given format
+func (c *Client) NewBatch(format BatchFormat, path string) *BatchRequest {
+	return &BatchRequest{client: c, format: format, path: path}
+}
+
+// Call adds a JSON-RPC call. Calls are numbered from 1 in the order they are added.
+func (b *BatchRequest) Call(method string, params interface{}) *BatchRequest {
+	b.items = append(b.items, batchItem{method: method, body: params})
+	return b
+}
+
+// Add adds an OData sub-request. path is relative to the service root and body, if not
+// nil, is encoded as JSON.
+func (b *BatchRequest) Add(method, path string, body interface{}) *BatchRequest {
+	b.items = append(b.items, batchItem{method: method, path: path, body: body})
+	return b
+}
+
+// Do sends the batch and returns the result of each sub-request, in the order the
+// sub-requests were added
+func (b *BatchRequest) Do(ctx context.Context) ([]BatchResult, error) {
+	if len(b.items) == 0 {
+		return nil, errors.New("batch: no sub-requests")
+	}
+	switch b.format {
+	case BatchJSONRPC:
+		return b.doJSONRPC(ctx)
+	case BatchOData:
+		return b.doOData(ctx)
+	}
+	return nil, fmt.Errorf("batch: unknown format %d", b.format)
+}
+
+// doJSONRPC sends the calls as a JSON-RPC batch and matches the responses by ID
+func (b *BatchRequest) doJSONRPC(ctx context.Context) ([]BatchResult, error) {
+	type call struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+		ID      int         `json:"id"`
+	}
+	calls := make([]call, len(b.items))
+	for i, item := range b.items {
+		calls[i] = call{JSONRPC: "2.0", Method: item.method, Params: item.body, ID: i + 1}
+	}
+
+	resp, err := b.client.POST(ctx, b.path, calls)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+
+	var replies []struct {
+		Result json.RawMessage `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+		ID     int             `json:"id"`
+	}
+	if err := resp.JSON(&replies); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(calls))
+	answered := make([]bool, len(calls))
+	for _, reply := range replies {
+		if reply.ID < 1 || reply.ID > len(calls) {
+			continue
+		}
+		results[reply.ID-1] = BatchResult{Body: reply.Result}
+		if reply.Error != nil {
+			results[reply.ID-1].Err = reply.Error
+		}
+		answered[reply.ID-1] = true
+	}
+	for i := range results {
+		if !answered[i] {
+			results[i].Err = fmt.Errorf("batch: no response to call %d (%s)", i+1, calls[i].Method)
+		}
+	}
+	return results, nil
+}
+
+// doOData sends the requests as an OData multipart/mixed batch
+func (b *BatchRequest) doOData(ctx context.Context) ([]BatchResult, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, item := range b.items {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"application/http"},
+			"Content-Transfer-Encoding": {"binary"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(part, "%s %s HTTP/1.1\r\n", item.method, item.path)
+		if item.body == nil {
+			fmt.Fprint(part, "\r\n")
+			continue
+		}
+		encoded, err := json.Marshal(item.body)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(part, "Content-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(encoded))
+		part.Write(encoded)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.NewRequest(http.MethodPost, b.path).
+		WithHeader("Content-Type", "multipart/mixed; boundary="+writer.Boundary()).
+		WithBody(body.Bytes()).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		return nil, fmt.Errorf("batch: unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	var results []BatchResult
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sub, err := http.ReadResponse(bufio.NewReader(part), nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(sub.Body)
+		sub.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, BatchResult{StatusCode: sub.StatusCode, Header: sub.Header, Body: data})
+	}
+	if len(results) != len(b.items) {
+		return nil, fmt.Errorf("batch: expected %d responses, got %d", len(b.items), len(results))
+	}
+	return results, nil
+}
@@ -0,0 +1,62 @@
+// Package charset decodes non-UTF-8 response bodies into UTF-8.
+//
+// It lives in its own package so that the golang.org/x/text dependency is only
+// compiled into programs that actually need charset transcoding.
+package charset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anggasct/httpio"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Bytes reads the entire response body and transcodes it to UTF-8 using the charset
+// declared in the Content-Type header. Bodies without a charset are returned as-is.
+func Bytes(resp *httpio.Response) ([]byte, error) {
+	enc, err := lookup(resp.Charset())
+	if err != nil {
+		resp.Close()
+		return nil, err
+	}
+
+	body, err := resp.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return body, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("charset: failed to decode body: %w", err)
+	}
+	return decoded, nil
+}
+
+// String reads the entire response body and returns it as a UTF-8 string, transcoding
+// it from the charset declared in the Content-Type header
+func String(resp *httpio.Response) (string, error) {
+	body, err := Bytes(resp)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// lookup returns the encoding for a charset name, or nil when no transcoding is needed
+func lookup(name string) (encoding.Encoding, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "utf-8") || strings.EqualFold(name, "utf8") {
+		return nil, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("charset: unsupported charset %q", name)
+	}
+	return enc, nil
+}
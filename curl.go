@@ -0,0 +1,178 @@
+package httpio
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/anggasct/httpio/internal/client"
+)
+
+// curlNoopFlags are curl options that only affect curl's own output and are ignored
+var curlNoopFlags = map[string]bool{
+	"-s": true, "--silent": true,
+	"-S": true, "--show-error": true,
+	"-v": true, "--verbose": true,
+	"--compressed": true,
+}
+
+// FromCurl builds a prepared request from a curl command line, e.g. one copied from API
+// documentation. The supported subset is the URL, -X/--request, -H/--header,
+// -d/--data/--data-raw/--data-binary and -u/--user; output options such as -s are
+// ignored and any other option is rejected, as is reading data from a file or stdin
+// with an '@' value. Only --data-raw sends a value starting with '@' literally. As with curl, data without an explicit
+// method makes a POST sent as application/x-www-form-urlencoded unless a Content-Type
+// header is given, and several data options are joined with '&'. A URL without a scheme
+// is resolved against the client's base URL.
+func (c *Client) FromCurl(command string) (*client.Request, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var method, rawURL, user string
+	var headers []string
+	var data []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if curlNoopFlags[arg] {
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if rawURL != "" {
+				return nil, fmt.Errorf("curl: unexpected argument %q", arg)
+			}
+			rawURL = arg
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("curl: option %s requires a value", arg)
+		}
+		value := args[i+1]
+		i++
+
+		switch arg {
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-H", "--header":
+			headers = append(headers, value)
+		case "-d", "--data", "--data-binary":
+			// curl reads a file or stdin for these, which a command copied from
+			// documentation cannot rely on
+			if strings.HasPrefix(value, "@") {
+				return nil, fmt.Errorf("curl: %s %s reads from a file, which is not supported; inline the data or use --data-raw", arg, value)
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "-u", "--user":
+			user = value
+		case "--url":
+			rawURL = value
+		default:
+			return nil, fmt.Errorf("curl: unsupported option %s", arg)
+		}
+	}
+
+	if rawURL == "" {
+		return nil, fmt.Errorf("curl: no URL given")
+	}
+	if method == "" {
+		method = "GET"
+		if len(data) > 0 {
+			method = "POST"
+		}
+	}
+
+	req := c.NewRequest(method, rawURL)
+	if strings.Contains(rawURL, "://") {
+		req.URL = rawURL
+	}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("curl: malformed header %q", header)
+		}
+		req.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if user != "" {
+		req.WithHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+	}
+	if len(data) > 0 {
+		if req.Headers.Get("Content-Type") == "" {
+			req.WithHeader("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.WithBody(strings.Join(data, "&"))
+	}
+	return req, nil
+}
+
+// splitShellWords splits a command line into words the way a POSIX shell would for
+// single quotes, double quotes, backslash escapes and line continuations
+func splitShellWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && quote == '"':
+			// Inside double quotes, a backslash only escapes a few characters
+			if i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+				}
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("curl: unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
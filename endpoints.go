@@ -0,0 +1,122 @@
+package httpio
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WeightedEndpoint is a base URL that receives a share of requests proportional to its weight
+type WeightedEndpoint struct {
+	// URL is the base URL of the endpoint
+	URL string
+	// Weight is the relative share of requests sent to the endpoint
+	Weight int
+	// Healthy reports whether the endpoint may receive requests (default: always), e.g.
+	// backed by a circuit breaker dedicated to the endpoint
+	Healthy func() bool
+}
+
+// endpointBalancer selects endpoints using smooth weighted round-robin, which spreads
+// the requests of each endpoint evenly instead of sending them in bursts
+type endpointBalancer struct {
+	mu        sync.Mutex
+	endpoints []WeightedEndpoint
+	current   []int
+}
+
+// next returns the URL of the next endpoint, skipping unhealthy endpoints unless all are
+func (b *endpointBalancer) next() string {
+	healthy := make([]bool, len(b.endpoints))
+	anyHealthy := false
+	for i, endpoint := range b.endpoints {
+		healthy[i] = endpoint.Weight > 0 && (endpoint.Healthy == nil || endpoint.Healthy())
+		anyHealthy = anyHealthy || healthy[i]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	best, total := -1, 0
+	for i, endpoint := range b.endpoints {
+		if endpoint.Weight <= 0 || (anyHealthy && !healthy[i]) {
+			continue
+		}
+		b.current[i] += endpoint.Weight
+		total += endpoint.Weight
+		if best < 0 || b.current[i] > b.current[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return b.endpoints[0].URL
+	}
+	b.current[best] -= total
+	return b.endpoints[best].URL
+}
+
+// stickyEndpointKey is the context key holding the endpoint pinned to a context
+type stickyEndpointKey struct{}
+
+// stickyEndpoint is the endpoint chosen for the first request made with a sticky context
+type stickyEndpoint struct {
+	once sync.Once
+	url  string
+}
+
+// WithStickyEndpoint returns a context whose requests all go to the weighted endpoint
+// chosen for the first of them, e.g. to keep a session on one replica
+func WithStickyEndpoint(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyEndpointKey{}, &stickyEndpoint{})
+}
+
+// WithWeightedEndpoints spreads requests across several base URLs in proportion to their
+// weights. Each request made with a path relative to the base URL is sent to an endpoint
+// picked when the request is sent, so retries may land on a different endpoint. The first
+// endpoint becomes the base URL. Endpoints reporting themselves unhealthy are skipped
+// unless all of them are.
+func (c *Client) WithWeightedEndpoints(endpoints []WeightedEndpoint) *Client {
+	if len(endpoints) == 0 {
+		c.balancer = nil
+		return c
+	}
+	c.balancer = &endpointBalancer{
+		endpoints: append([]WeightedEndpoint(nil), endpoints...),
+		current:   make([]int, len(endpoints)),
+	}
+	c.baseURL = endpoints[0].URL
+	return c
+}
+
+// withEndpoint returns req addressed to the weighted endpoint selected for it, or req
+// itself if it does not target the base URL
+func (c *Client) withEndpoint(req *http.Request) (*http.Request, error) {
+	reqURL := req.URL.String()
+	if c.balancer == nil || !strings.HasPrefix(reqURL, c.baseURL) {
+		return req, nil
+	}
+
+	var endpoint string
+	if sticky, ok := req.Context().Value(stickyEndpointKey{}).(*stickyEndpoint); ok {
+		sticky.once.Do(func() {
+			sticky.url = c.balancer.next()
+		})
+		endpoint = sticky.url
+	} else {
+		endpoint = c.balancer.next()
+	}
+	if endpoint == c.baseURL {
+		return req, nil
+	}
+
+	target, err := url.Parse(endpoint + strings.TrimPrefix(reqURL, c.baseURL))
+	if err != nil {
+		return nil, err
+	}
+	routed := req.Clone(req.Context())
+	routed.URL = target
+	routed.Host = ""
+	return routed, nil
+}
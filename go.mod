@@ -4,4 +4,7 @@ go 1.24
 
 toolchain go1.24.3
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/google/uuid v1.6.0
+	golang.org/x/text v0.28.0
+)
@@ -3,8 +3,11 @@ package httpio
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/anggasct/httpio/internal/client"
@@ -17,6 +20,46 @@ type Request = client.Request
 // Response wraps the standard http.Response with additional utility methods
 type Response = client.Response
 
+// StatusError is returned by Response.Err for unsuccessful responses
+type StatusError = client.StatusError
+
+// ProblemDetails is an RFC 7807 problem details object returned by Response.Problem
+type ProblemDetails = client.ProblemDetails
+
+// JSONCodec encodes JSON request bodies and decodes JSON response bodies
+type JSONCodec = client.JSONCodec
+
+// StandardJSONCodec is a JSONCodec based on encoding/json, optionally rejecting unknown fields
+type StandardJSONCodec = client.StandardJSONCodec
+
+// Multipart is a multipart/form-data body whose parts are streamed while it is sent
+type Multipart = client.Multipart
+
+// NewMultipart creates an empty multipart/form-data body
+var NewMultipart = client.NewMultipart
+
+// RegisterContentDecoder registers a decoder for responses with the given Content-Encoding,
+// e.g. "zstd"
+var RegisterContentDecoder = client.RegisterContentDecoder
+
+// ErrNoBody is returned when decoding is requested on a response that cannot carry a body
+var ErrNoBody = client.ErrNoBody
+
+// ShortReadError is returned when a response body is shorter than its Content-Length
+type ShortReadError = client.ShortReadError
+
+// ErrNilResponse is returned when a middleware returns neither a response nor an error
+var ErrNilResponse = middleware.ErrNilResponse
+
+// ErrConflictingBody is returned when a request is given both a form body and another body
+var ErrConflictingBody = client.ErrConflictingBody
+
+// ErrUnresolvedPathParam is returned when a request path has {name} placeholders without a value
+var ErrUnresolvedPathParam = client.ErrUnresolvedPathParam
+
+// ErrRequestBodyTooLarge is returned when a request body exceeds the size set by WithMaxRequestBytes
+var ErrRequestBodyTooLarge = client.ErrRequestBodyTooLarge
+
 // Event represents a Server-Sent Event
 type SSEEvent = client.Event
 
@@ -32,6 +75,18 @@ type SSEEventHandlerFunc = client.EventHandlerFunc
 // EventFullHandlerFunc represents a function-based handler with lifecycle support
 type SSEEventFullHandlerFunc = client.EventFullHandlerFunc
 
+// SSEMux routes Server-Sent Events to handlers by event type
+type SSEMux = client.EventMux
+
+// NewSSEMux creates a new Server-Sent Events router
+var NewSSEMux = client.NewEventMux
+
+// ReconnectConfig configures how long-lived streams are resumed after a disconnect
+type ReconnectConfig = client.ReconnectConfig
+
+// DefaultReconnectConfig returns a ReconnectConfig with sensible defaults
+var DefaultReconnectConfig = client.DefaultReconnectConfig
+
 // StreamOption represents options for stream processing
 type StreamOption = client.StreamOption
 
@@ -47,18 +102,59 @@ var WithByteDelimiter = client.WithByteDelimiter
 // WithContentType sets the expected content type for the stream
 var WithContentType = client.WithContentType
 
+// WithLogicalContentType sets the expected content type of a possibly gzip-compressed stream
+var WithLogicalContentType = client.WithLogicalContentType
+
+// WithKeepDelimiter passes lines to the handler with their delimiter intact
+var WithKeepDelimiter = client.WithKeepDelimiter
+
+// WithErrorDetector recognizes JSON records that signal a terminal stream error
+var WithErrorDetector = client.WithErrorDetector
+
+// WithMaxRecords caps the number of records a stream delivers
+var WithMaxRecords = client.WithMaxRecords
+
+// WithMaxBytes caps the number of bytes a stream delivers
+var WithMaxBytes = client.WithMaxBytes
+
+// StreamLimitError is returned when a stream exceeds WithMaxRecords or WithMaxBytes
+type StreamLimitError = client.StreamLimitError
+
+// StreamingContext marks ctx as belonging to a streaming request, which is then bounded by
+// the context deadline alone when it has one
+var StreamingContext = client.StreamingContext
+
+// StreamError is returned when a stream emits a record recognized by WithErrorDetector
+type StreamError = client.StreamError
+
 // Client is a wrapper around http.Client with additional functionality
 type Client struct {
-	client      *http.Client
-	baseURL     string
-	headers     http.Header
-	middlewares []middleware.Middleware
+	client         *http.Client
+	baseURL        string
+	pathPrefix     string
+	headers        http.Header
+	middlewares    []middleware.Middleware
+	safeRetries    int
+	isSuccess      func(*http.Response) bool
+	maxReqBytes    int64
+	routeNamer     func(path string) string
+	checkLength    bool
+	defaultCtx     context.Context
+	balancer       *endpointBalancer
+	finalizers     []func(*http.Request) error
+	jsonCodec      client.JSONCodec
+	autoDecompress bool
+	pool           poolCounters
 }
 
-// New creates a new http Client
+// DefaultTimeout is the total request timeout applied by New. Use WithTimeout(0) for
+// unbounded requests, e.g. long-lived streams without a context deadline.
+const DefaultTimeout = 30 * time.Second
+
+// New creates a new http Client with a total request timeout of DefaultTimeout
 func New() *Client {
 	c := &Client{
-		client:      &http.Client{},
+		client:      &http.Client{Timeout: DefaultTimeout},
 		headers:     make(http.Header),
 		middlewares: make([]middleware.Middleware, 0),
 	}
@@ -68,7 +164,44 @@ func New() *Client {
 
 // Do implements the client.HTTPClient interface
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	return c.client.Do(req)
+	httpClient := c.httpClientFor(req)
+	req, err := c.withEndpoint(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, finalize := range c.finalizers {
+		if err := finalize(req); err != nil {
+			return nil, err
+		}
+	}
+	req = c.withPoolTrace(req)
+	var resp *http.Response
+	if c.safeRetries > 0 && isIdempotentMethod(req.Method) {
+		resp, err = c.doWithSafeRetries(httpClient, req)
+	} else {
+		resp, err = httpClient.Do(req)
+	}
+	if err == nil && c.autoDecompress {
+		if err := client.Decompress(resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, classifyTimeout(err)
+}
+
+// httpClientFor returns the http.Client sending req. Streaming requests whose context has
+// a deadline are bounded by that deadline alone, so the total timeout meant for unary
+// calls does not cut long-lived streams short.
+func (c *Client) httpClientFor(req *http.Request) *http.Client {
+	if c.client.Timeout == 0 || !client.IsStreaming(req.Context()) {
+		return c.client
+	}
+	if _, ok := req.Context().Deadline(); !ok {
+		return c.client
+	}
+	streaming := *c.client
+	streaming.Timeout = 0
+	return &streaming
 }
 
 // GetMiddlewares implements the client.HTTPClient interface
@@ -76,6 +209,35 @@ func (c *Client) GetMiddlewares() []middleware.Middleware {
 	return c.middlewares
 }
 
+// SuccessPredicate returns the predicate configured with WithSuccessPredicate, if any
+func (c *Client) SuccessPredicate() func(*http.Response) bool {
+	return c.isSuccess
+}
+
+// MaxRequestBytes returns the request body size limit configured with WithMaxRequestBytes
+func (c *Client) MaxRequestBytes() int64 {
+	return c.maxReqBytes
+}
+
+// DefaultContext returns the context set with WithDefaultContext, if any
+func (c *Client) DefaultContext() context.Context {
+	return c.defaultCtx
+}
+
+// VerifyContentLength reports whether WithContentLengthCheck is enabled
+func (c *Client) VerifyContentLength() bool {
+	return c.checkLength
+}
+
+// MetricName maps a request path to its metric name using the function configured with
+// WithRouteTemplates, returning an empty string if none is configured
+func (c *Client) MetricName(path string) string {
+	if c.routeNamer == nil {
+		return ""
+	}
+	return c.routeNamer(path)
+}
+
 // GET performs a GET request
 func (c *Client) GET(ctx context.Context, path string) (*client.Response, error) {
 	return c.NewRequest("GET", path).Do(ctx)
@@ -111,12 +273,72 @@ func (c *Client) OPTIONS(ctx context.Context, path string) (*client.Response, er
 	return c.NewRequest("OPTIONS", path).Do(ctx)
 }
 
+// Send performs a request for callers that only need the status code, such as webhooks
+// and pings. The response body is always drained and closed, so the connection is reused.
+func (c *Client) Send(ctx context.Context, method, path string, body interface{}) (int, error) {
+	req := c.NewRequest(method, path)
+	if body != nil {
+		req.WithBody(body)
+	}
+
+	resp, err := req.Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
 // WithBaseURL sets the base URL for all requests
 func (c *Client) WithBaseURL(baseURL string) *Client {
 	c.baseURL = baseURL
 	return c
 }
 
+// WithFinalizer adds a function that is called with the fully formed request immediately
+// before it is handed to the transport, after body encoding, header merging and all
+// middleware have run, e.g. to sign the request. The body can be read through
+// req.GetBody, which is set for []byte, string and JSON bodies. An error aborts the
+// request. Finalizers run in the order they were added, again for each retry made by
+// middleware.
+func (c *Client) WithFinalizer(finalize func(*http.Request) error) *Client {
+	c.finalizers = append(c.finalizers, finalize)
+	return c
+}
+
+// WithAutoDecompress enables decompressing response bodies the transport left compressed,
+// e.g. when the server compressed a response that was not asked to be. gzip and deflate are
+// supported, as well as the encodings added with RegisterContentDecoder.
+// The Content-Encoding header is removed so String, JSON and the streaming methods see the
+// decompressed body.
+func (c *Client) WithAutoDecompress(enabled bool) *Client {
+	c.autoDecompress = enabled
+	return c
+}
+
+// WithJSONCodec sets the codec used to encode JSON request bodies and to decode responses
+// with Response.JSON. Request.WithJSONCodec overrides it for a single request.
+func (c *Client) WithJSONCodec(codec JSONCodec) *Client {
+	c.jsonCodec = codec
+	return c
+}
+
+// JSONCodec returns the codec configured with WithJSONCodec, if any
+func (c *Client) JSONCodec() JSONCodec {
+	return c.jsonCodec
+}
+
+// WithPathPrefix sets a path prefix, such as the mount point behind a gateway, that is
+// inserted between the base URL and the path of every request
+func (c *Client) WithPathPrefix(prefix string) *Client {
+	c.pathPrefix = prefix
+	return c
+}
+
 // WithHeader sets a header for all requests
 func (c *Client) WithHeader(key, value string) *Client {
 	c.headers.Set(key, value)
@@ -131,12 +353,78 @@ func (c *Client) WithHeaders(headers map[string]string) *Client {
 	return c
 }
 
-// WithTimeout sets the timeout for all requests
+// WithTimeout sets the timeout for all requests. A timeout of 0 disables it.
+//
+// When the request context also has a deadline, the earlier of the two applies. Streaming
+// requests, made with the stream methods of Request or with a context marked by
+// StreamingContext, are the exception: if their context has a deadline, it alone applies.
 func (c *Client) WithTimeout(timeout time.Duration) *Client {
 	c.client.Timeout = timeout
 	return c
 }
 
+// Timeout returns the total timeout applied to requests, 0 meaning unbounded
+func (c *Client) Timeout() time.Duration {
+	return c.client.Timeout
+}
+
+// WithCookieJar stores the cookies set by responses in jar and sends them back with later
+// requests to the same host, e.g. to keep the session of an API that logs in with a cookie.
+// Cookies are matched against the final request URL, so with WithBaseURL they apply to
+// every request made with a relative path. With WithWeightedEndpoints each endpoint host
+// has its own cookies. A nil jar disables cookie handling.
+func (c *Client) WithCookieJar(jar http.CookieJar) *Client {
+	c.client.Jar = jar
+	return c
+}
+
+// WithDefaultCookieJar installs an in-memory cookie jar, as WithCookieJar does. The jar has
+// no public suffix list, so it suits known APIs rather than arbitrary untrusted hosts.
+func (c *Client) WithDefaultCookieJar() *Client {
+	// cookiejar.New only fails on invalid options, and nil options are valid
+	jar, _ := cookiejar.New(nil)
+	return c.WithCookieJar(jar)
+}
+
+// WithSuccessPredicate overrides what Response.IsSuccess and Response.Err consider a
+// successful response, e.g. to treat a 200 carrying {"ok":false} as a failure
+func (c *Client) WithSuccessPredicate(predicate func(*http.Response) bool) *Client {
+	c.isSuccess = predicate
+	return c
+}
+
+// WithMaxRequestBytes rejects request bodies larger than n bytes with ErrRequestBodyTooLarge.
+// In-memory bodies are checked before sending; streaming bodies fail once the limit is crossed.
+func (c *Client) WithMaxRequestBytes(n int64) *Client {
+	c.maxReqBytes = n
+	return c
+}
+
+// WithDefaultContext sets the context used by requests made with a nil or context.TODO()
+// context, e.g. the lifetime context of a background daemon, so that cancelling it aborts
+// them. An explicit context always wins over the default one.
+func (c *Client) WithDefaultContext(ctx context.Context) *Client {
+	c.defaultCtx = ctx
+	return c
+}
+
+// WithContentLengthCheck makes Response.Bytes, String and JSON fail with a
+// *ShortReadError when fewer bytes than announced by Content-Length could be read, e.g.
+// because the server closed the connection early. Responses without a Content-Length,
+// such as chunked ones, are not checked.
+func (c *Client) WithContentLengthCheck(enabled bool) *Client {
+	c.checkLength = enabled
+	return c
+}
+
+// WithRouteTemplates sets a function mapping request paths to route templates, e.g.
+// /users/42 to /users/:id, which metrics and tracing middleware use as low-cardinality
+// labels. Request.WithMetricName takes precedence over it.
+func (c *Client) WithRouteTemplates(namer func(path string) string) *Client {
+	c.routeNamer = namer
+	return c
+}
+
 // WithMiddleware adds a middleware to the client's middleware chain
 // Middlewares are applied in the order they are added
 func (c *Client) WithMiddleware(m middleware.Middleware) *Client {
@@ -144,6 +432,12 @@ func (c *Client) WithMiddleware(m middleware.Middleware) *Client {
 	return c
 }
 
+// WithMiddlewareStack adds a reusable middleware stack, built with middleware.Compose,
+// to the client's middleware chain
+func (c *Client) WithMiddlewareStack(stack middleware.Middleware) *Client {
+	return c.WithMiddleware(stack)
+}
+
 // WithMiddlewares allows adding multiple middlewares to the client
 func (c *Client) WithMiddlewares(middlewares ...middleware.Middleware) func(*Client) {
 	return func(c *Client) {
@@ -151,18 +445,10 @@ func (c *Client) WithMiddlewares(middlewares ...middleware.Middleware) func(*Cli
 	}
 }
 
-// WithConnectionPool configures the connection pool settings for the HTTP client
+// WithConnectionPool configures the connection pool settings for the HTTP client.
+// The settings are merged into the existing transport.
 func (c *Client) WithConnectionPool(maxIdleConns, maxConnsPerHost, maxIdleConnsPerHost int, idleConnTimeout time.Duration) *Client {
-	if c.client.Transport == nil {
-		c.client.Transport = &http.Transport{}
-	}
-
-	transport, ok := c.client.Transport.(*http.Transport)
-	if !ok {
-		transport = &http.Transport{}
-		c.client.Transport = transport
-	}
-
+	transport := c.transport()
 	transport.MaxIdleConns = maxIdleConns
 	transport.MaxConnsPerHost = maxConnsPerHost
 	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
@@ -174,7 +460,9 @@ func (c *Client) WithConnectionPool(maxIdleConns, maxConnsPerHost, maxIdleConnsP
 // NewRequest creates a new request with the given method and URL
 func (c *Client) NewRequest(method, path string) *client.Request {
 	reqURL := path
-	if c.baseURL != "" {
+	if c.pathPrefix != "" {
+		reqURL = joinPath(joinPath(c.baseURL, c.pathPrefix), path)
+	} else if c.baseURL != "" {
 		reqURL = c.baseURL + path
 	}
 
@@ -195,6 +483,17 @@ func (c *Client) NewRequest(method, path string) *client.Request {
 	return req
 }
 
+// joinPath joins two URL parts with exactly one slash between them
+func joinPath(base, path string) string {
+	if base == "" {
+		return path
+	}
+	if path == "" {
+		return base
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
 // Middleware defines the interface for HTTP middleware
 type Middleware = middleware.Middleware
 
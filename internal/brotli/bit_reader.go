@@ -0,0 +1,104 @@
+package brotli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// decodeError carries a decoding failure up to Reader.Read, which recovers it. Decoding
+// is deeply nested, so failures unwind the stack rather than being returned at each level.
+type decodeError struct {
+	err error
+}
+
+// corrupt aborts decoding because the stream does not follow the format
+func corrupt(reason string) {
+	panic(decodeError{fmt.Errorf("brotli: corrupt stream: %s", reason)})
+}
+
+// byteReader is the input of the bit reader
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// bitReader reads the stream least significant bit first
+type bitReader struct {
+	r     byteReader
+	val   uint64
+	nbits uint
+	// err is the error of the underlying reader, reported once the buffered bits run out
+	err error
+}
+
+// fill buffers at least n bits, unless the input ends first
+func (br *bitReader) fill(n uint) {
+	for br.nbits < n {
+		b, err := br.r.ReadByte()
+		if err != nil {
+			if br.err == nil {
+				br.err = err
+			}
+			return
+		}
+		br.val |= uint64(b) << br.nbits
+		br.nbits += 8
+	}
+}
+
+// inputError is the error reported when the input ends in the middle of the stream
+func (br *bitReader) inputError() error {
+	if br.err == nil || errors.Is(br.err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return br.err
+}
+
+// peek returns the next n bits without consuming them, padded with zeros past the end
+// of the input
+func (br *bitReader) peek(n uint) uint32 {
+	br.fill(n)
+	return uint32(br.val & (1<<n - 1))
+}
+
+// readBits consumes and returns the next n bits, n being at most 32
+func (br *bitReader) readBits(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+	br.fill(n)
+	if br.nbits < n {
+		panic(decodeError{br.inputError()})
+	}
+	v := uint32(br.val & (1<<n - 1))
+	br.val >>= n
+	br.nbits -= n
+	return v
+}
+
+// alignToByte skips to the next byte boundary. The skipped bits must be zero.
+func (br *bitReader) alignToByte() {
+	if br.readBits(br.nbits%8) != 0 {
+		corrupt("non-zero padding bits")
+	}
+}
+
+// readBytes fills p with the next bytes of the input. The reader must be byte aligned.
+func (br *bitReader) readBytes(p []byte) {
+	for br.nbits >= 8 && len(p) > 0 {
+		p[0] = byte(br.val)
+		br.val >>= 8
+		br.nbits -= 8
+		p = p[1:]
+	}
+	if len(p) == 0 {
+		return
+	}
+	if _, err := io.ReadFull(br.r, p); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(decodeError{err})
+	}
+}
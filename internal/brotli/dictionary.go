@@ -0,0 +1,20 @@
+package brotli
+
+import _ "embed"
+
+// dictionary is the static dictionary of RFC 7932 Appendix A, holding the words of each
+// length from 4 to 24 one after another
+//
+//go:embed dictionary.bin
+var dictionary string
+
+// dictionaryOffsets holds the offset of the words of each length in the dictionary
+var dictionaryOffsets = [25]int{
+	0, 0, 0, 0, 0, 4096, 9216, 21504, 35840, 44032, 53248, 63488, 74752,
+	87040, 93696, 100864, 104704, 106752, 108928, 113536, 115968, 118528, 119872, 121280, 122016,
+}
+
+// dictionarySizeBits holds the log2 of the number of words of each length
+var dictionarySizeBits = [25]uint{
+	0, 0, 0, 0, 10, 10, 11, 11, 10, 10, 10, 10, 10, 9, 9, 8, 7, 7, 8, 7, 7, 6, 6, 5, 5,
+}
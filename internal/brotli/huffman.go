@@ -0,0 +1,254 @@
+package brotli
+
+import "math/bits"
+
+const (
+	// maxCodeLength is the longest prefix code allowed by the format
+	maxCodeLength = 15
+	// rootBits is the number of bits resolved by a single lookup in the root table
+	rootBits = 8
+)
+
+// codeLengthOrder is the order in which the code lengths of the code length alphabet are sent
+var codeLengthOrder = [18]uint8{1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// codeLengthPrefixLength and codeLengthPrefixValue decode the fixed code used for the code
+// lengths of the code length alphabet, indexed by the next 4 bits of the stream
+var (
+	codeLengthPrefixLength = [16]uint8{2, 2, 2, 3, 2, 2, 2, 4, 2, 2, 2, 3, 2, 2, 2, 4}
+	codeLengthPrefixValue  = [16]uint8{0, 4, 3, 2, 0, 4, 3, 1, 0, 4, 3, 2, 0, 4, 3, 5}
+)
+
+// huffmanCode decodes symbols of a canonical prefix code
+type huffmanCode struct {
+	// single is the symbol of a code with a single symbol, which takes no bits, or -1
+	single int
+	// root maps the next rootBits bits to symbol<<4 | length for codes of up to rootBits
+	// bits. Longer codes have a zero entry and are decoded bit by bit.
+	root [1 << rootBits]uint16
+	// counts holds the number of codes of each length
+	counts [maxCodeLength + 1]uint16
+	// symbols lists the symbols ordered by code length, then by value
+	symbols []uint16
+}
+
+// newHuffmanCode builds the canonical prefix code with the given code lengths per symbol
+func newHuffmanCode(lengths []uint8) *huffmanCode {
+	h := &huffmanCode{single: -1}
+
+	var offsets [maxCodeLength + 2]int
+	for _, length := range lengths {
+		h.counts[length]++
+	}
+	h.counts[0] = 0
+	for length := 1; length <= maxCodeLength; length++ {
+		offsets[length+1] = offsets[length] + int(h.counts[length])
+	}
+	h.symbols = make([]uint16, offsets[maxCodeLength+1])
+	for symbol, length := range lengths {
+		if length != 0 {
+			h.symbols[offsets[length]] = uint16(symbol)
+			offsets[length]++
+		}
+	}
+
+	code, index := 0, 0
+	for length := 1; length <= maxCodeLength; length++ {
+		for i := 0; i < int(h.counts[length]); i++ {
+			if length <= rootBits {
+				reversed := int(bits.Reverse16(uint16(code)) >> (16 - length))
+				entry := h.symbols[index]<<4 | uint16(length)
+				for k := reversed; k < len(h.root); k += 1 << length {
+					h.root[k] = entry
+				}
+			}
+			code++
+			index++
+		}
+		code <<= 1
+	}
+	return h
+}
+
+// decode reads the next symbol
+func (h *huffmanCode) decode(br *bitReader) int {
+	if h.single >= 0 {
+		return h.single
+	}
+	if entry := h.root[br.peek(rootBits)]; entry&15 != 0 {
+		br.readBits(uint(entry & 15))
+		return int(entry >> 4)
+	}
+
+	code, first, index := 0, 0, 0
+	for length := 1; length <= maxCodeLength; length++ {
+		code |= int(br.readBits(1))
+		count := int(h.counts[length])
+		if code-first < count {
+			return int(h.symbols[index+code-first])
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	corrupt("invalid prefix code")
+	return 0
+}
+
+// readPrefixCode reads the description of a prefix code over alphabetSize symbols
+func readPrefixCode(br *bitReader, alphabetSize int) *huffmanCode {
+	hskip := br.readBits(2)
+	if hskip == 1 {
+		return readSimplePrefixCode(br, alphabetSize)
+	}
+
+	var codeLengthLengths [18]uint8
+	space, numCodes, last := 32, 0, 0
+	for i := int(hskip); i < len(codeLengthOrder); i++ {
+		next := br.peek(4)
+		br.readBits(uint(codeLengthPrefixLength[next]))
+		length := codeLengthPrefixValue[next]
+		codeLengthLengths[codeLengthOrder[i]] = length
+		if length != 0 {
+			space -= 32 >> length
+			numCodes++
+			last = int(codeLengthOrder[i])
+			if space <= 0 {
+				break
+			}
+		}
+	}
+	if numCodes != 1 && space != 0 {
+		corrupt("invalid code length code")
+	}
+	codeLengthCode := &huffmanCode{single: last}
+	if numCodes != 1 {
+		codeLengthCode = newHuffmanCode(codeLengthLengths[:])
+	}
+
+	lengths := make([]uint8, alphabetSize)
+	symbol, prevLength, repeat, repeatLength := 0, uint8(8), 0, uint8(0)
+	space = 1 << maxCodeLength
+	for symbol < alphabetSize && space > 0 {
+		length := uint8(codeLengthCode.decode(br))
+		if length < 16 {
+			repeat = 0
+			lengths[symbol] = length
+			symbol++
+			if length != 0 {
+				prevLength = length
+				space -= 1 << maxCodeLength >> length
+			}
+			continue
+		}
+
+		extraBits, newLength := uint(2), prevLength
+		if length == 17 {
+			extraBits, newLength = 3, 0
+		}
+		if repeatLength != newLength {
+			repeat, repeatLength = 0, newLength
+		}
+		oldRepeat := repeat
+		if repeat > 0 {
+			repeat = (repeat - 2) << extraBits
+		}
+		repeat += int(br.readBits(extraBits)) + 3
+		delta := repeat - oldRepeat
+		if symbol+delta > alphabetSize {
+			corrupt("code length repeat past the alphabet")
+		}
+		for i := 0; i < delta; i++ {
+			lengths[symbol] = repeatLength
+			symbol++
+		}
+		if repeatLength != 0 {
+			space -= delta << (maxCodeLength - repeatLength)
+		}
+	}
+	if space != 0 {
+		corrupt("incomplete prefix code")
+	}
+	return newHuffmanCode(lengths)
+}
+
+// readSimplePrefixCode reads a prefix code of up to four symbols
+func readSimplePrefixCode(br *bitReader, alphabetSize int) *huffmanCode {
+	numSymbols := int(br.readBits(2)) + 1
+	symbolBits := uint(bits.Len(uint(alphabetSize - 1)))
+
+	var symbols [4]int
+	for i := 0; i < numSymbols; i++ {
+		symbols[i] = int(br.readBits(symbolBits))
+		if symbols[i] >= alphabetSize {
+			corrupt("symbol outside the alphabet")
+		}
+		for j := 0; j < i; j++ {
+			if symbols[j] == symbols[i] {
+				corrupt("duplicate symbol")
+			}
+		}
+	}
+	if numSymbols == 1 {
+		return &huffmanCode{single: symbols[0]}
+	}
+
+	codeLengths := [][4]uint8{2: {1, 1}, 3: {1, 2, 2}, 4: {2, 2, 2, 2}}[numSymbols]
+	if numSymbols == 4 && br.readBits(1) == 1 {
+		codeLengths = [4]uint8{1, 2, 3, 3}
+	}
+	lengths := make([]uint8, alphabetSize)
+	for i := 0; i < numSymbols; i++ {
+		lengths[symbols[i]] = codeLengths[i]
+	}
+	return newHuffmanCode(lengths)
+}
+
+// readContextMap reads a context map of size entries referring to numTrees prefix codes
+func readContextMap(br *bitReader, size, numTrees int) []uint8 {
+	contextMap := make([]uint8, size)
+	if numTrees < 2 {
+		return contextMap
+	}
+
+	maxRunLengthPrefix := 0
+	if br.readBits(1) == 1 {
+		maxRunLengthPrefix = int(br.readBits(4)) + 1
+	}
+	code := readPrefixCode(br, numTrees+maxRunLengthPrefix)
+	for i := 0; i < size; {
+		symbol := code.decode(br)
+		switch {
+		case symbol == 0:
+			i++
+		case symbol <= maxRunLengthPrefix:
+			// Runs of zeros, which the map is initialized with
+			i += 1<<symbol + int(br.readBits(uint(symbol)))
+			if i > size {
+				corrupt("context map run past its end")
+			}
+		default:
+			contextMap[i] = uint8(symbol - maxRunLengthPrefix)
+			i++
+		}
+	}
+
+	if br.readBits(1) == 1 {
+		inverseMoveToFront(contextMap)
+	}
+	return contextMap
+}
+
+// inverseMoveToFront undoes the move-to-front transform applied to a context map
+func inverseMoveToFront(values []uint8) {
+	var mtf [256]uint8
+	for i := range mtf {
+		mtf[i] = uint8(i)
+	}
+	for i, index := range values {
+		value := mtf[index]
+		values[i] = value
+		copy(mtf[1:index+1], mtf[:index])
+		mtf[0] = value
+	}
+}
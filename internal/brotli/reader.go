@@ -0,0 +1,462 @@
+// Package brotli implements a decoder for the Brotli compressed data format of RFC 7932,
+// used for the "br" content coding.
+package brotli
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// ErrClosed is returned when reading from a closed Reader
+var ErrClosed = errors.New("brotli: read from closed reader")
+
+// outputChunk is roughly how much output is decoded at a time before it is handed to Read
+const outputChunk = 64 << 10
+
+// noBlockSwitch is the block count of a category with a single block type
+const noBlockSwitch = 1 << 24
+
+// Block count codes: base value and number of extra bits
+var (
+	blockCountBase = [26]int{
+		1, 5, 9, 13, 17, 25, 33, 41, 49, 65, 81, 97, 113, 145, 177, 209,
+		241, 305, 369, 497, 753, 1265, 2289, 4337, 8433, 16625,
+	}
+	blockCountExtra = [26]uint{2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 6, 6, 7, 8, 9, 10, 11, 12, 13, 24}
+)
+
+// Insert and copy length codes: base value and number of extra bits
+var (
+	insertLengthBase = [24]int{
+		0, 1, 2, 3, 4, 5, 6, 8, 10, 14, 18, 26, 34, 50, 66, 98, 130, 194, 322, 578, 1090, 2114, 6210, 22594,
+	}
+	insertLengthExtra = [24]uint{0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 12, 14, 24}
+	copyLengthBase    = [24]int{
+		2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 14, 18, 22, 30, 38, 54, 70, 102, 134, 198, 326, 582, 1094, 2118,
+	}
+	copyLengthExtra = [24]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 24}
+)
+
+// commandCells holds the first insert and copy length codes of each group of 64
+// insert-and-copy symbols. Symbols below 128 reuse the last distance.
+var commandCells = [11]struct{ insert, copy int }{
+	{0, 0}, {0, 8}, {0, 0}, {0, 8}, {8, 0}, {8, 8}, {0, 16}, {16, 0}, {8, 16}, {16, 8}, {16, 16},
+}
+
+// Distance codes below 16 refer to a recent distance, by index, plus a delta
+var (
+	shortDistanceIndex = [16]int{0, 1, 2, 3, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1}
+	shortDistanceDelta = [16]int{0, 0, 0, 0, -1, 1, -2, 2, -3, 3, -1, 1, -2, 2, -3, 3}
+)
+
+// blockState tracks the block type and remaining block length of one category of symbols
+type blockState struct {
+	numTypes  int
+	typeCode  *huffmanCode
+	countCode *huffmanCode
+	// current and previous are the last and second-to-last block types
+	current, previous int
+	remaining         int
+}
+
+// readBlockState reads the block switching header of a category
+func readBlockState(br *bitReader) blockState {
+	state := blockState{numTypes: readVarLenUint8(br) + 1, previous: 1, remaining: noBlockSwitch}
+	if state.numTypes >= 2 {
+		state.typeCode = readPrefixCode(br, state.numTypes+2)
+		state.countCode = readPrefixCode(br, len(blockCountBase))
+		state.remaining = state.readCount(br)
+	}
+	return state
+}
+
+// readCount reads the length of the next block
+func (s *blockState) readCount(br *bitReader) int {
+	symbol := s.countCode.decode(br)
+	return blockCountBase[symbol] + int(br.readBits(blockCountExtra[symbol]))
+}
+
+// next accounts for one symbol of the category, switching block type first if the
+// current block is exhausted
+func (s *blockState) next(br *bitReader) {
+	if s.remaining == 0 {
+		if s.numTypes < 2 {
+			s.remaining = noBlockSwitch
+		} else {
+			var blockType int
+			switch symbol := s.typeCode.decode(br); symbol {
+			case 0:
+				blockType = s.previous
+			case 1:
+				blockType = s.current + 1
+			default:
+				blockType = symbol - 2
+			}
+			if blockType >= s.numTypes {
+				blockType -= s.numTypes
+			}
+			s.previous, s.current = s.current, blockType
+			s.remaining = s.readCount(br)
+		}
+	}
+	s.remaining--
+}
+
+// readVarLenUint8 reads a number from 0 to 255 in its variable length encoding
+func readVarLenUint8(br *bitReader) int {
+	if br.readBits(1) == 0 {
+		return 0
+	}
+	n := uint(br.readBits(3))
+	if n == 0 {
+		return 1
+	}
+	return 1<<n + int(br.readBits(n))
+}
+
+// Reader decompresses a Brotli stream as it is read
+type Reader struct {
+	br  bitReader
+	err error
+
+	started    bool
+	windowSize int
+	// history holds the output, of which the part from readPos on has not been read yet.
+	// At least the last windowSize bytes are kept for back references.
+	history []byte
+	readPos int
+	// total counts the bytes of output so far
+	total int
+	// distances holds the last four distances, the most recent first
+	distances [4]int
+
+	// State of the current meta-block
+	last          bool
+	remaining     int
+	uncompressed  bool
+	literals      blockState
+	commands      blockState
+	dists         blockState
+	postfixBits   uint
+	directCodes   int
+	contextModes  []uint8
+	literalMap    []uint8
+	distanceMap   []uint8
+	literalCodes  []*huffmanCode
+	commandCodes  []*huffmanCode
+	distanceCodes []*huffmanCode
+}
+
+// NewReader returns a Reader decompressing the Brotli stream read from r
+func NewReader(r io.Reader) *Reader {
+	input, ok := r.(byteReader)
+	if !ok {
+		input = bufio.NewReader(r)
+	}
+	return &Reader{
+		br:        bitReader{r: input},
+		distances: [4]int{4, 11, 15, 16},
+	}
+}
+
+// Read implements io.Reader
+func (d *Reader) Read(p []byte) (int, error) {
+	for d.readPos == len(d.history) {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.decode()
+	}
+	n := copy(p, d.history[d.readPos:])
+	d.readPos += n
+	return n, nil
+}
+
+// Close releases the decoding state. Reading afterwards returns ErrClosed; the
+// underlying reader is not closed.
+func (d *Reader) Close() error {
+	*d = Reader{err: ErrClosed}
+	return nil
+}
+
+// decode decodes the next part of the stream into the history
+func (d *Reader) decode() {
+	defer func() {
+		if r := recover(); r != nil {
+			failure, ok := r.(decodeError)
+			if !ok {
+				panic(r)
+			}
+			d.err = failure.err
+		}
+	}()
+
+	d.compact()
+	if !d.started {
+		d.readStreamHeader()
+		d.started = true
+	}
+
+	if d.remaining == 0 {
+		if d.last {
+			d.br.alignToByte()
+			d.err = io.EOF
+			return
+		}
+		d.readMetaBlockHeader()
+		return
+	}
+
+	if d.uncompressed {
+		n := min(d.remaining, outputChunk)
+		start := len(d.history)
+		d.history = append(d.history, make([]byte, n)...)
+		d.br.readBytes(d.history[start:])
+		d.total += n
+		d.remaining -= n
+		return
+	}
+
+	limit := len(d.history) + outputChunk
+	for d.remaining > 0 && len(d.history) < limit {
+		d.decodeCommand()
+	}
+}
+
+// compact drops history that is neither unread nor within reach of back references
+func (d *Reader) compact() {
+	cut := min(len(d.history)-d.windowSize, d.readPos)
+	if cut < max(d.windowSize, outputChunk) {
+		return
+	}
+	d.history = d.history[:copy(d.history, d.history[cut:])]
+	d.readPos -= cut
+}
+
+// readStreamHeader reads the window size
+func (d *Reader) readStreamHeader() {
+	windowBits := uint(16)
+	if d.br.readBits(1) == 1 {
+		if n := d.br.readBits(3); n != 0 {
+			windowBits = 17 + uint(n)
+		} else {
+			switch n := d.br.readBits(3); n {
+			case 0:
+				windowBits = 17
+			case 1:
+				corrupt("large windows are not supported")
+			default:
+				windowBits = 8 + uint(n)
+			}
+		}
+	}
+	d.windowSize = 1<<windowBits - 16
+}
+
+// readMetaBlockHeader reads the header of the next meta-block and, for compressed ones,
+// the prefix codes and context maps used in it
+func (d *Reader) readMetaBlockHeader() {
+	br := &d.br
+	d.last = br.readBits(1) == 1
+	if d.last && br.readBits(1) == 1 {
+		return
+	}
+
+	nibbles := [4]int{4, 5, 6, 0}[br.readBits(2)]
+	if nibbles == 0 {
+		d.skipMetadata()
+		return
+	}
+	length := 0
+	for i := 0; i < nibbles; i++ {
+		nibble := int(br.readBits(4))
+		if i == nibbles-1 && nibbles > 4 && nibble == 0 {
+			corrupt("meta-block length with a leading zero nibble")
+		}
+		length |= nibble << (4 * i)
+	}
+	d.remaining = length + 1
+
+	d.uncompressed = !d.last && br.readBits(1) == 1
+	if d.uncompressed {
+		br.alignToByte()
+		return
+	}
+
+	d.literals = readBlockState(br)
+	d.commands = readBlockState(br)
+	d.dists = readBlockState(br)
+
+	d.postfixBits = uint(br.readBits(2))
+	d.directCodes = int(br.readBits(4)) << d.postfixBits
+
+	d.contextModes = make([]uint8, d.literals.numTypes)
+	for i := range d.contextModes {
+		d.contextModes[i] = uint8(br.readBits(2))
+	}
+
+	literalTrees := readVarLenUint8(br) + 1
+	d.literalMap = readContextMap(br, 64*d.literals.numTypes, literalTrees)
+	distanceTrees := readVarLenUint8(br) + 1
+	d.distanceMap = readContextMap(br, 4*d.dists.numTypes, distanceTrees)
+
+	d.literalCodes = make([]*huffmanCode, literalTrees)
+	for i := range d.literalCodes {
+		d.literalCodes[i] = readPrefixCode(br, 256)
+	}
+	d.commandCodes = make([]*huffmanCode, d.commands.numTypes)
+	for i := range d.commandCodes {
+		d.commandCodes[i] = readPrefixCode(br, 704)
+	}
+	d.distanceCodes = make([]*huffmanCode, distanceTrees)
+	for i := range d.distanceCodes {
+		d.distanceCodes[i] = readPrefixCode(br, 16+d.directCodes+48<<d.postfixBits)
+	}
+}
+
+// skipMetadata skips a metadata meta-block, which carries no output
+func (d *Reader) skipMetadata() {
+	br := &d.br
+	if br.readBits(1) != 0 {
+		corrupt("reserved bit set")
+	}
+	skipBytes := int(br.readBits(2))
+	skipLength := 0
+	for i := 0; i < skipBytes; i++ {
+		b := int(br.readBits(8))
+		if i == skipBytes-1 && skipBytes > 1 && b == 0 {
+			corrupt("metadata length with a leading zero byte")
+		}
+		skipLength |= b << (8 * i)
+	}
+	if skipBytes > 0 {
+		skipLength++
+	}
+
+	br.alignToByte()
+	var discard [256]byte
+	for skipLength > 0 {
+		n := min(skipLength, len(discard))
+		br.readBytes(discard[:n])
+		skipLength -= n
+	}
+}
+
+// decodeCommand decodes an insert-and-copy command: a run of literals followed by a copy
+// from earlier output or from the static dictionary
+func (d *Reader) decodeCommand() {
+	br := &d.br
+
+	d.commands.next(br)
+	symbol := d.commandCodes[d.commands.current].decode(br)
+	cell := commandCells[symbol>>6]
+	insertCode := cell.insert + symbol>>3&7
+	copyCode := cell.copy + symbol&7
+	insertLength := insertLengthBase[insertCode] + int(br.readBits(insertLengthExtra[insertCode]))
+	copyLength := copyLengthBase[copyCode] + int(br.readBits(copyLengthExtra[copyCode]))
+
+	if insertLength > d.remaining {
+		corrupt("literals past the end of the meta-block")
+	}
+	for i := 0; i < insertLength; i++ {
+		d.literals.next(br)
+		var p1, p2 byte
+		if n := len(d.history); n >= 2 {
+			p1, p2 = d.history[n-1], d.history[n-2]
+		} else if n == 1 {
+			p1 = d.history[0]
+		}
+		context := contextID(d.contextModes[d.literals.current], p1, p2)
+		code := d.literalCodes[d.literalMap[d.literals.current<<6|int(context)]]
+		d.history = append(d.history, byte(code.decode(br)))
+	}
+	d.total += insertLength
+	d.remaining -= insertLength
+	if d.remaining == 0 {
+		// The copy length of the last command of a meta-block is ignored
+		return
+	}
+
+	distanceCode := 0
+	distance := d.distances[0]
+	if symbol >= 128 {
+		d.dists.next(br)
+		context := min(copyLength-2, 3)
+		distanceCode = d.distanceCodes[d.distanceMap[d.dists.current<<2|context]].decode(br)
+		distance = d.readDistance(distanceCode)
+	}
+
+	maxDistance := min(d.total, d.windowSize)
+	if distance > maxDistance {
+		// Commands read no bits when every code has a single symbol, so one without
+		// output would be repeated forever
+		if d.copyDictionaryWord(distance-maxDistance-1, copyLength) == 0 && insertLength == 0 {
+			corrupt("command without output")
+		}
+		return
+	}
+
+	if copyLength > d.remaining {
+		corrupt("copy past the end of the meta-block")
+	}
+	start := len(d.history) - distance
+	if distance >= copyLength {
+		d.history = append(d.history, d.history[start:start+copyLength]...)
+	} else {
+		for i := 0; i < copyLength; i++ {
+			d.history = append(d.history, d.history[start+i])
+		}
+	}
+	d.total += copyLength
+	d.remaining -= copyLength
+	if distanceCode != 0 {
+		d.distances = [4]int{distance, d.distances[0], d.distances[1], d.distances[2]}
+	}
+}
+
+// readDistance reads the extra bits of a distance code and returns the distance
+func (d *Reader) readDistance(code int) int {
+	if code < 16 {
+		distance := d.distances[shortDistanceIndex[code]] + shortDistanceDelta[code]
+		if distance <= 0 {
+			corrupt("invalid distance")
+		}
+		return distance
+	}
+	if code < 16+d.directCodes {
+		return code - 15
+	}
+
+	code -= 16 + d.directCodes
+	extraBits := 1 + uint(code>>(d.postfixBits+1))
+	high := code >> d.postfixBits
+	low := code & (1<<d.postfixBits - 1)
+	offset := (2+high&1)<<extraBits - 4
+	return (offset+int(d.br.readBits(extraBits)))<<d.postfixBits + low + d.directCodes + 1
+}
+
+// copyDictionaryWord appends the transformed static dictionary word with the given ID and
+// returns the number of bytes appended
+func (d *Reader) copyDictionaryWord(wordID, length int) int {
+	if length < 4 || length > 24 {
+		corrupt("invalid dictionary word length")
+	}
+	sizeBits := dictionarySizeBits[length]
+	transformID := wordID >> sizeBits
+	if transformID >= len(transforms) {
+		corrupt("invalid dictionary reference")
+	}
+	offset := dictionaryOffsets[length] + (wordID&(1<<sizeBits-1))*length
+	word := dictionary[offset : offset+length]
+
+	start := len(d.history)
+	d.history = transforms[transformID].apply(d.history, word)
+	n := len(d.history) - start
+	if n > d.remaining {
+		corrupt("dictionary word past the end of the meta-block")
+	}
+	d.total += n
+	d.remaining -= n
+	return n
+}
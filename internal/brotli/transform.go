@@ -0,0 +1,205 @@
+package brotli
+
+// Kinds of word transforms applied to static dictionary references
+const (
+	transformIdentity = iota
+	transformOmitLast1
+	transformOmitLast2
+	transformOmitLast3
+	transformOmitLast4
+	transformOmitLast5
+	transformOmitLast6
+	transformOmitLast7
+	transformOmitLast8
+	transformOmitLast9
+	transformUppercaseFirst
+	transformUppercaseAll
+	transformOmitFirst1
+	transformOmitFirst2
+	transformOmitFirst3
+	transformOmitFirst4
+	transformOmitFirst5
+	transformOmitFirst6
+	transformOmitFirst7
+	transformOmitFirst8
+	transformOmitFirst9
+)
+
+// transform turns a dictionary word into the bytes it stands for
+type transform struct {
+	prefix string
+	kind   int
+	suffix string
+}
+
+// apply appends the transformed word to dst
+func (t transform) apply(dst []byte, word string) []byte {
+	dst = append(dst, t.prefix...)
+	switch {
+	case t.kind >= transformOmitLast1 && t.kind <= transformOmitLast9:
+		word = word[:max(len(word)-t.kind, 0)]
+	case t.kind >= transformOmitFirst1:
+		word = word[min(t.kind-transformOmitFirst1+1, len(word)):]
+	}
+
+	start := len(dst)
+	dst = append(dst, word...)
+	switch t.kind {
+	case transformUppercaseFirst:
+		if len(dst) > start {
+			toUpper(dst[start:])
+		}
+	case transformUppercaseAll:
+		for i := start; i < len(dst); {
+			i += toUpper(dst[i:])
+		}
+	}
+	return append(dst, t.suffix...)
+}
+
+// toUpper uppercases the character at the start of w the way the format defines it, which only
+// roughly matches Unicode for multi-byte characters, and returns its length
+func toUpper(w []byte) int {
+	switch {
+	case w[0] < 0xc0:
+		if w[0] >= 'a' && w[0] <= 'z' {
+			w[0] ^= 32
+		}
+		return 1
+	case w[0] < 0xe0:
+		if len(w) > 1 {
+			w[1] ^= 32
+		}
+		return 2
+	default:
+		if len(w) > 2 {
+			w[2] ^= 5
+		}
+		return 3
+	}
+}
+
+// transforms lists the word transforms of RFC 7932 Appendix B, by transform ID
+var transforms = [...]transform{
+	{"", transformIdentity, ""},
+	{"", transformIdentity, " "},
+	{" ", transformIdentity, " "},
+	{"", transformOmitFirst1, ""},
+	{"", transformUppercaseFirst, " "},
+	{"", transformIdentity, " the "},
+	{" ", transformIdentity, ""},
+	{"s ", transformIdentity, " "},
+	{"", transformIdentity, " of "},
+	{"", transformUppercaseFirst, ""},
+	{"", transformIdentity, " and "},
+	{"", transformOmitFirst2, ""},
+	{"", transformOmitLast1, ""},
+	{", ", transformIdentity, " "},
+	{"", transformIdentity, ", "},
+	{" ", transformUppercaseFirst, " "},
+	{"", transformIdentity, " in "},
+	{"", transformIdentity, " to "},
+	{"e ", transformIdentity, " "},
+	{"", transformIdentity, "\""},
+	{"", transformIdentity, "."},
+	{"", transformIdentity, "\">"},
+	{"", transformIdentity, "\n"},
+	{"", transformOmitLast3, ""},
+	{"", transformIdentity, "]"},
+	{"", transformIdentity, " for "},
+	{"", transformOmitFirst3, ""},
+	{"", transformOmitLast2, ""},
+	{"", transformIdentity, " a "},
+	{"", transformIdentity, " that "},
+	{" ", transformUppercaseFirst, ""},
+	{"", transformIdentity, ". "},
+	{".", transformIdentity, ""},
+	{" ", transformIdentity, ", "},
+	{"", transformOmitFirst4, ""},
+	{"", transformIdentity, " with "},
+	{"", transformIdentity, "'"},
+	{"", transformIdentity, " from "},
+	{"", transformIdentity, " by "},
+	{"", transformOmitFirst5, ""},
+	{"", transformOmitFirst6, ""},
+	{" the ", transformIdentity, ""},
+	{"", transformOmitLast4, ""},
+	{"", transformIdentity, ". The "},
+	{"", transformUppercaseAll, ""},
+	{"", transformIdentity, " on "},
+	{"", transformIdentity, " as "},
+	{"", transformIdentity, " is "},
+	{"", transformOmitLast7, ""},
+	{"", transformOmitLast1, "ing "},
+	{"", transformIdentity, "\n\t"},
+	{"", transformIdentity, ":"},
+	{" ", transformIdentity, ". "},
+	{"", transformIdentity, "ed "},
+	{"", transformOmitFirst9, ""},
+	{"", transformOmitFirst7, ""},
+	{"", transformOmitLast6, ""},
+	{"", transformIdentity, "("},
+	{"", transformUppercaseFirst, ", "},
+	{"", transformOmitLast8, ""},
+	{"", transformIdentity, " at "},
+	{"", transformIdentity, "ly "},
+	{" the ", transformIdentity, " of "},
+	{"", transformOmitLast5, ""},
+	{"", transformOmitLast9, ""},
+	{" ", transformUppercaseFirst, ", "},
+	{"", transformUppercaseFirst, "\""},
+	{".", transformIdentity, "("},
+	{"", transformUppercaseAll, " "},
+	{"", transformUppercaseFirst, "\">"},
+	{"", transformIdentity, "=\""},
+	{" ", transformIdentity, "."},
+	{".com/", transformIdentity, ""},
+	{" the ", transformIdentity, " of the "},
+	{"", transformUppercaseFirst, "'"},
+	{"", transformIdentity, ". This "},
+	{"", transformIdentity, ","},
+	{".", transformIdentity, " "},
+	{"", transformUppercaseFirst, "("},
+	{"", transformUppercaseFirst, "."},
+	{"", transformIdentity, " not "},
+	{" ", transformIdentity, "=\""},
+	{"", transformIdentity, "er "},
+	{" ", transformUppercaseAll, " "},
+	{"", transformIdentity, "al "},
+	{" ", transformUppercaseAll, ""},
+	{"", transformIdentity, "='"},
+	{"", transformUppercaseAll, "\""},
+	{"", transformUppercaseFirst, ". "},
+	{" ", transformIdentity, "("},
+	{"", transformIdentity, "ful "},
+	{" ", transformUppercaseFirst, ". "},
+	{"", transformIdentity, "ive "},
+	{"", transformIdentity, "less "},
+	{"", transformUppercaseAll, "'"},
+	{"", transformIdentity, "est "},
+	{" ", transformUppercaseFirst, "."},
+	{"", transformUppercaseAll, "\">"},
+	{" ", transformIdentity, "='"},
+	{"", transformUppercaseFirst, ","},
+	{"", transformIdentity, "ize "},
+	{"", transformUppercaseAll, "."},
+	{"\xc2\xa0", transformIdentity, ""},
+	{" ", transformIdentity, ","},
+	{"", transformUppercaseFirst, "=\""},
+	{"", transformUppercaseAll, "=\""},
+	{"", transformIdentity, "ous "},
+	{"", transformUppercaseAll, ", "},
+	{"", transformUppercaseFirst, "='"},
+	{" ", transformUppercaseFirst, ","},
+	{" ", transformUppercaseAll, "=\""},
+	{" ", transformUppercaseAll, ", "},
+	{"", transformUppercaseAll, ","},
+	{"", transformUppercaseAll, "("},
+	{"", transformUppercaseAll, ". "},
+	{" ", transformUppercaseAll, "."},
+	{"", transformUppercaseAll, "='"},
+	{" ", transformUppercaseAll, ". "},
+	{" ", transformUppercaseFirst, "=\""},
+	{" ", transformUppercaseAll, "='"},
+	{" ", transformUppercaseFirst, "='"},
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/anggasct/httpio/internal/brotli"
+)
+
+// contentDecoders maps a lowercase content coding to a function wrapping a reader of data
+// in that coding with one that decodes it
+var (
+	contentDecoders = map[string]func(io.Reader) (io.Reader, error){
+		"gzip":    newGzipReader,
+		"x-gzip":  newGzipReader,
+		"deflate": newDeflateReader,
+		"br":      newBrotliReader,
+	}
+	contentDecodersMu sync.RWMutex
+)
+
+// RegisterContentDecoder registers a decoder for responses with the given Content-Encoding,
+// e.g. "zstd", replacing any decoder registered for it before. The reader returned by
+// factory is closed with the response body if it implements io.Closer.
+//
+// gzip, deflate and br are supported out of the box:
+//
+//	httpio.RegisterContentDecoder("x-snappy-framed", func(r io.Reader) io.Reader {
+//		return snappy.NewReader(r)
+//	})
+func RegisterContentDecoder(encoding string, factory func(io.Reader) io.Reader) {
+	contentDecodersMu.Lock()
+	defer contentDecodersMu.Unlock()
+	contentDecoders[strings.ToLower(encoding)] = func(r io.Reader) (io.Reader, error) {
+		return factory(r), nil
+	}
+}
+
+// contentDecoder returns the decoder registered for encoding, if any
+func contentDecoder(encoding string) (func(io.Reader) (io.Reader, error), bool) {
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+	decoder, ok := contentDecoders[encoding]
+	return decoder, ok
+}
+
+// BodyReader returns a reader over the response body that decompresses it according to
+// its Content-Encoding as it is read, without buffering the whole body. gzip, deflate, br
+// and the encodings added with RegisterContentDecoder are supported, including several
+// encodings applied in sequence. Bodies the transport already decompressed, which have no
+// Content-Encoding left, are returned as is. Closing the reader closes the response body.
+func (r *Response) BodyReader() (io.ReadCloser, error) {
+	return decodeBody(r.Body, r.Header.Get("Content-Encoding"))
+}
+
+// Decompress replaces the body of resp with one decompressed according to its
+// Content-Encoding, as net/http does for the compression it requests itself. The header is
+// removed and ContentLength set to -1, since the decompressed length is not known up front.
+// Responses without an encoding, or with one that has no registered decoder, are left
+// untouched.
+func Decompress(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	if resp.Body == nil || resp.Body == http.NoBody || !supportedEncoding(encoding) {
+		return nil
+	}
+	body, err := decodeBody(resp.Body, encoding)
+	if err != nil {
+		return err
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// supportedEncoding reports whether contentEncoding needs decoding and every encoding
+// listed in it can be decoded
+func supportedEncoding(contentEncoding string) bool {
+	needsDecoding := false
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		switch encoding = strings.ToLower(strings.TrimSpace(encoding)); encoding {
+		case "", "identity":
+		default:
+			if _, ok := contentDecoder(encoding); !ok {
+				return false
+			}
+			needsDecoding = true
+		}
+	}
+	return needsDecoding
+}
+
+// decodeBody wraps body in the decoders for contentEncoding. body is closed if decoding
+// cannot start.
+func decodeBody(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
+	encodings := strings.Split(contentEncoding, ",")
+
+	var reader io.Reader = body
+	closers := []io.Closer{body}
+	// Encodings are listed in the order they were applied
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+		decoder, ok := contentDecoder(encoding)
+		if !ok {
+			closeAll(closers)
+			return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+		decoded, err := decoder(reader)
+		if err != nil {
+			closeAll(closers)
+			return nil, err
+		}
+		reader = decoded
+		if closer, ok := decoded.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+	}
+
+	return &decodingReader{Reader: reader, closers: closers}, nil
+}
+
+// newGzipReader decodes gzip bodies
+func newGzipReader(reader io.Reader) (io.Reader, error) {
+	return gzip.NewReader(reader)
+}
+
+// newDeflateReader decodes deflate bodies, which are meant to be zlib streams but are
+// sent as raw deflate data by some servers
+func newDeflateReader(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	if header, err := buffered.Peek(2); err == nil && isZlibHeader(header) {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// newBrotliReader decodes br bodies
+func newBrotliReader(reader io.Reader) (io.Reader, error) {
+	return brotli.NewReader(reader), nil
+}
+
+// isZlibHeader reports whether header starts a zlib stream using deflate compression
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// decodingReader reads decompressed data and closes every layer of decoding on Close
+type decodingReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer
+func (d *decodingReader) Close() error {
+	return closeAll(d.closers)
+}
+
+// closeAll closes closers from the last to the first, returning the first error
+func closeAll(closers []io.Closer) error {
+	var err error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if closeErr := closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// JSONCodec encodes JSON request bodies and decodes JSON response bodies
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StandardJSONCodec is a JSONCodec based on encoding/json
+type StandardJSONCodec struct {
+	// DisallowUnknownFields rejects objects with fields that do not match the target struct
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface{} values as json.Number instead of float64
+	UseNumber bool
+}
+
+// Marshal implements JSONCodec
+func (c StandardJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements JSONCodec. Like json.Unmarshal, it rejects trailing data.
+func (c StandardJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if c.UseNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// jsonCodecProvider is implemented by clients with a custom JSON codec
+type jsonCodecProvider interface {
+	JSONCodec() JSONCodec
+}
+
+// WithJSONCodec sets the codec used to encode the body of this request and to decode its
+// response with Response.JSON, overriding the client's codec
+func (r *Request) WithJSONCodec(codec JSONCodec) *Request {
+	r.codec = codec
+	return r
+}
+
+// jsonCodec returns the codec of the request, falling back to the client's
+func (r *Request) jsonCodec() JSONCodec {
+	if r.codec != nil {
+		return r.codec
+	}
+	if provider, ok := r.Client.(jsonCodecProvider); ok {
+		return provider.JSONCodec()
+	}
+	return nil
+}
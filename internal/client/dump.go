@@ -0,0 +1,41 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httputil"
+	"strings"
+)
+
+// Dump writes the status line, headers and, if withBody is set, the body of the response
+// to w for debugging, indenting JSON bodies. The body is restored afterwards so the
+// response can still be read.
+func (r *Response) Dump(w io.Writer, withBody bool) error {
+	head, err := httputil.DumpResponse(r.Response, false)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+	if !withBody || r.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if mediaType, _ := r.ContentType(); mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", "  ") == nil {
+			body = append(indented.Bytes(), '\n')
+		}
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// WithFileBody sets the request body to the contents of the file at path. The file is
+// opened when the request is sent and closed once it completes. Content-Length is set
+// from the file size and, unless already set, Content-Type is inferred from the file
+// extension or, failing that, from the file contents.
+func (r *Request) WithFileBody(path string) *Request {
+	r.filePath = path
+	r.Body = nil
+	return r
+}
+
+// openFileBody opens the file body of the request, returning it along with its size
+func (r *Request) openFileBody() (*os.File, int64, error) {
+	file, err := os.Open(r.filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	if r.Headers.Get("Content-Type") == "" {
+		contentType, err := detectFileContentType(file)
+		if err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		r.Headers.Set("Content-Type", contentType)
+	}
+
+	return file, info.Size(), nil
+}
+
+// detectFileContentType infers the content type of file from its extension or its first
+// 512 bytes, leaving the file positioned at its start
+func detectFileContentType(file *os.File) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(file.Name())); contentType != "" {
+		return contentType, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
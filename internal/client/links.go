@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Links parses the RFC 8288 (formerly RFC 5988) Link header of the response and returns
+// the target of each link keyed by relation type, such as "next" or "last". Relative
+// targets are resolved against the request URL. When several links share a relation
+// type, the first one wins.
+func (r *Response) Links() map[string]string {
+	links := make(map[string]string)
+	for _, header := range r.Header.Values("Link") {
+		for _, link := range splitLinks(header) {
+			target, rels, ok := parseLink(link)
+			if !ok {
+				continue
+			}
+			if r.Request != nil && r.Request.URL != nil {
+				if ref, err := url.Parse(target); err == nil {
+					target = r.Request.URL.ResolveReference(ref).String()
+				}
+			}
+			for _, rel := range rels {
+				if _, exists := links[rel]; !exists {
+					links[rel] = target
+				}
+			}
+		}
+	}
+	return links
+}
+
+// splitLinks splits a Link header on the commas separating links, ignoring commas
+// inside the <target> or quoted parameter values
+func splitLinks(header string) []string {
+	var links []string
+	inTarget, inQuote := false, false
+	start := 0
+	for i, c := range header {
+		switch {
+		case c == '"' && !inTarget:
+			inQuote = !inQuote
+		case c == '<' && !inQuote:
+			inTarget = true
+		case c == '>' && !inQuote:
+			inTarget = false
+		case c == ',' && !inTarget && !inQuote:
+			links = append(links, header[start:i])
+			start = i + 1
+		}
+	}
+	return append(links, header[start:])
+}
+
+// parseLink parses a single `<target>; rel="a b"` link
+func parseLink(link string) (string, []string, bool) {
+	link = strings.TrimSpace(link)
+	if !strings.HasPrefix(link, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(link, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+	target := link[1:end]
+
+	var rels []string
+	for _, param := range strings.Split(link[end+1:], ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		for _, rel := range strings.Fields(value) {
+			rels = append(rels, strings.ToLower(rel))
+		}
+	}
+	return target, rels, len(rels) > 0
+}
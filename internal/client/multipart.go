@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"sync"
+)
+
+// Multipart is a multipart/form-data body whose parts are written lazily while the
+// request is sent, so file parts stream from their readers without being buffered
+type Multipart struct {
+	writer *multipart.Writer
+	parts  []multipartPart
+}
+
+// multipartPart is a form field or a file part
+type multipartPart struct {
+	name     string
+	filename string
+	value    string
+	reader   io.Reader
+}
+
+// NewMultipart creates an empty multipart/form-data body
+func NewMultipart() *Multipart {
+	return &Multipart{writer: multipart.NewWriter(io.Discard)}
+}
+
+// Field adds a form field
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{name: name, value: value})
+	return m
+}
+
+// File adds a file part whose content is read from r while the request is sent. If r is
+// an io.Closer, it is closed once consumed.
+func (m *Multipart) File(name, filename string, r io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{name: name, filename: filename, reader: r})
+	return m
+}
+
+// ContentType returns the Content-Type of the body, including its boundary
+func (m *Multipart) ContentType() string {
+	return m.writer.FormDataContentType()
+}
+
+// reader returns a reader producing the encoded body. Parts are encoded in a goroutine
+// started by the first read and writing through an io.Pipe, so they are only read as
+// fast as the body is sent. The goroutine stops when ctx is done or the reader is closed.
+func (m *Multipart) reader(ctx context.Context) io.ReadCloser {
+	pr, pw := io.Pipe()
+	return &multipartReader{
+		pipe: pr,
+		start: func() {
+			go m.encode(ctx, pw)
+		},
+	}
+}
+
+// encode writes the parts to pw, closing it with the first error encountered
+func (m *Multipart) encode(ctx context.Context, pw *io.PipeWriter) {
+	stop := context.AfterFunc(ctx, func() {
+		pw.CloseWithError(ctx.Err())
+	})
+	defer stop()
+
+	writer := multipart.NewWriter(pw)
+	if err := writer.SetBoundary(m.writer.Boundary()); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+
+	for _, part := range m.parts {
+		if err := writePart(writer, part); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
+	pw.CloseWithError(writer.Close())
+}
+
+// writePart writes a single part, closing its reader if it is an io.Closer
+func writePart(writer *multipart.Writer, part multipartPart) error {
+	if part.reader == nil {
+		return writer.WriteField(part.name, part.value)
+	}
+	if closer, ok := part.reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	w, err := writer.CreateFormFile(part.name, part.filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, part.reader)
+	return err
+}
+
+// multipartReader starts encoding on the first read, so that requests rejected before
+// being sent leave no goroutine behind
+type multipartReader struct {
+	pipe  *io.PipeReader
+	once  sync.Once
+	start func()
+}
+
+// Read implements io.Reader
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.pipe.Read(p)
+}
+
+// Close implements io.Closer, stopping the encoding goroutine
+func (r *multipartReader) Close() error {
+	return r.pipe.Close()
+}
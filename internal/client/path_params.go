@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedPathParam is returned by Do when the request path still contains {name}
+// placeholders without a value
+var ErrUnresolvedPathParam = errors.New("request path has unresolved placeholders")
+
+// placeholderPattern matches a {name} placeholder in a path
+var placeholderPattern = regexp.MustCompile(`\{[^{}/]+\}`)
+
+// WithPathParam sets the value of the {name} placeholder in the request path. The value is
+// escaped, so it may contain slashes, spaces or other reserved characters.
+func (r *Request) WithPathParam(name, value string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]string)
+	}
+	r.pathParams[name] = value
+	return r
+}
+
+// WithPathParams sets the values of several path placeholders, as WithPathParam does
+func (r *Request) WithPathParams(params map[string]string) *Request {
+	for name, value := range params {
+		r.WithPathParam(name, value)
+	}
+	return r
+}
+
+// resolvedURL returns the request URL with its path placeholders replaced by the escaped
+// path parameters. The query string and fragment are left untouched.
+func (r *Request) resolvedURL() (string, error) {
+	path, rest := r.URL, ""
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path, rest = path[:i], path[i:]
+	}
+
+	for name, value := range r.pathParams {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	if unresolved := placeholderPattern.FindAllString(path, -1); len(unresolved) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrUnresolvedPathParam, strings.Join(unresolved, ", "))
+	}
+	return path + rest, nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProblemDetails is an RFC 7807 problem details object, sent by servers as an
+// application/problem+json body to describe an error
+type ProblemDetails struct {
+	// Type is a URI reference identifying the problem type
+	Type string `json:"type,omitempty"`
+	// Title is a short, human-readable summary of the problem type
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code generated by the origin server
+	Status int `json:"status,omitempty"`
+	// Detail is a human-readable explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence of the problem
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds the members of the object not defined by RFC 7807
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// Error implements the error interface
+func (p *ProblemDetails) Error() string {
+	switch {
+	case p.Title != "" && p.Detail != "":
+		return p.Title + ": " + p.Detail
+	case p.Title != "":
+		return p.Title
+	case p.Detail != "":
+		return p.Detail
+	}
+	return fmt.Sprintf("problem with status %d", p.Status)
+}
+
+// UnmarshalJSON decodes the standard members and collects the others in Extensions
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type standard ProblemDetails
+	if err := json.Unmarshal(data, (*standard)(p)); err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, name := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, name)
+	}
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+// problemMediaType is the media type of RFC 7807 problem details
+const problemMediaType = "application/problem+json"
+
+// Problem decodes an application/problem+json body into a *ProblemDetails. It reports
+// false if the response has another content type or the body cannot be decoded. The body
+// is consumed by the first call; later calls return the same result.
+func (r *Response) Problem() (*ProblemDetails, bool) {
+	r.problemOnce.Do(func() {
+		if mediaType, _ := r.ContentType(); mediaType != problemMediaType {
+			return
+		}
+		var problem ProblemDetails
+		if err := r.JSON(&problem); err == nil {
+			r.problem = &problem
+		}
+	})
+	return r.problem, r.problem != nil
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeType is the reflect.Type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// WithQueryStruct adds the fields of the struct v, or of the struct it points to, as query
+// parameters. Fields are named by their `url` tag, falling back to the field name; a tag
+// of "-" skips the field and the omitempty option skips zero values. Slices and arrays
+// become repeated keys and time.Time values are formatted as RFC 3339. Fields of embedded
+// structs are added as if they were fields of v.
+//
+// Fields of unsupported types, such as maps or other structs, make Do fail.
+func (r *Request) WithQueryStruct(v interface{}) *Request {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return r
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		r.queryErr = fmt.Errorf("WithQueryStruct requires a struct, got %T", v)
+		return r
+	}
+	if err := r.addQueryFields(value); err != nil {
+		r.queryErr = err
+	}
+	return r
+}
+
+// addQueryFields adds the exported fields of the struct value to the query
+func (r *Request) addQueryFields(value reflect.Value) error {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		omitEmpty := opts == "omitempty"
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && name == "" && fieldValue.Kind() == reflect.Struct && field.Type != timeType {
+			if err := r.addQueryFields(fieldValue); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		for fieldValue.Kind() == reflect.Pointer {
+			if fieldValue.IsNil() {
+				break
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		if fieldValue.Kind() == reflect.Pointer {
+			r.Query.Add(name, "")
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Slice || fieldValue.Kind() == reflect.Array {
+			for j := 0; j < fieldValue.Len(); j++ {
+				formatted, err := formatQueryValue(fieldValue.Index(j))
+				if err != nil {
+					return fmt.Errorf("query field %s: %w", field.Name, err)
+				}
+				r.Query.Add(name, formatted)
+			}
+			continue
+		}
+
+		formatted, err := formatQueryValue(fieldValue)
+		if err != nil {
+			return fmt.Errorf("query field %s: %w", field.Name, err)
+		}
+		r.Query.Add(name, formatted)
+	}
+	return nil
+}
+
+// formatQueryValue formats a single query parameter value
+func formatQueryValue(value reflect.Value) (string, error) {
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	case reflect.Pointer:
+		if value.IsNil() {
+			return "", nil
+		}
+		return formatQueryValue(value.Elem())
+	default:
+		return "", fmt.Errorf("unsupported type %s", value.Type())
+	}
+}
@@ -0,0 +1,130 @@
+// Package client implements the internal HTTP request/response handling
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// ReconnectConfig configures how long-lived streams are resumed after a disconnect
+type ReconnectConfig struct {
+	// MaxRetries is the maximum number of consecutive reconnect attempts without
+	// receiving a record before giving up
+	MaxRetries int
+	// BaseDelay is the base delay for exponential backoff between reconnects
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between reconnects
+	MaxDelay time.Duration
+	// Resume builds the request used to reconnect, typically setting a cursor or offset
+	// derived from the last record received. If nil, the original request is re-issued.
+	// lastRecord is nil when no record has been received yet.
+	Resume func(lastRecord json.RawMessage) *Request
+}
+
+// DefaultReconnectConfig returns a ReconnectConfig with sensible defaults
+func DefaultReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		MaxRetries: 5,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// StreamJSONWithReconnect streams JSON objects like StreamJSON, re-issuing the request with
+// exponential backoff whenever the stream is interrupted. Handler errors and stream limit
+// errors are returned as-is and never trigger a reconnect. A stream that ends cleanly is
+// not resumed.
+func (r *Request) StreamJSONWithReconnect(ctx context.Context, handler func(json.RawMessage) error, config ReconnectConfig, opts ...StreamOption) error {
+	return r.streamWithReconnect(ctx, config, func(req *Request, onRecord func([]byte) error) error {
+		return req.StreamJSON(ctx, func(raw json.RawMessage) error {
+			return onRecord(raw)
+		}, opts...)
+	}, func(record []byte) error {
+		return handler(record)
+	})
+}
+
+// StreamLinesWithReconnect streams lines like StreamLines, re-issuing the request with
+// exponential backoff whenever the stream is interrupted. The last line received is
+// passed to Resume as the last record.
+func (r *Request) StreamLinesWithReconnect(ctx context.Context, handler func([]byte) error, config ReconnectConfig, opts ...StreamOption) error {
+	return r.streamWithReconnect(ctx, config, func(req *Request, onRecord func([]byte) error) error {
+		return req.StreamLines(ctx, onRecord, opts...)
+	}, handler)
+}
+
+// streamWithReconnect drives the reconnect loop shared by the reconnecting stream helpers
+func (r *Request) streamWithReconnect(
+	ctx context.Context,
+	config ReconnectConfig,
+	stream func(req *Request, onRecord func([]byte) error) error,
+	handler func([]byte) error,
+) error {
+	ctx = r.context(ctx)
+	var lastRecord json.RawMessage
+	req := r
+	failures := 0
+
+	for {
+		received := false
+		var handlerErr error
+
+		err := stream(req, func(record []byte) error {
+			received = true
+			if err := handler(record); err != nil {
+				handlerErr = err
+				return err
+			}
+			lastRecord = append(lastRecord[:0], record...)
+			return nil
+		})
+
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if err == nil {
+			return nil
+		}
+		var streamErr *StreamError
+		var limitErr *StreamLimitError
+		if errors.As(err, &streamErr) || errors.As(err, &limitErr) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if received {
+			failures = 0
+		}
+		if failures >= config.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(reconnectBackoff(config, failures)):
+		}
+		failures++
+
+		req = r
+		if config.Resume != nil {
+			if resumed := config.Resume(lastRecord); resumed != nil {
+				req = resumed
+			}
+		}
+	}
+}
+
+// reconnectBackoff calculates the exponential backoff delay for a reconnect attempt
+func reconnectBackoff(config ReconnectConfig, attempt int) time.Duration {
+	delay := float64(config.BaseDelay) * math.Pow(2, float64(attempt))
+	if config.MaxDelay > 0 && delay > float64(config.MaxDelay) {
+		delay = float64(config.MaxDelay)
+	}
+	return time.Duration(delay)
+}
@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"time"
 
 	"github.com/anggasct/httpio/middleware"
@@ -23,6 +25,14 @@ type Request struct {
 	Client      HTTPClient
 	middlewares []middleware.Middleware
 	timeout     *time.Duration
+	metricName  string
+	metricTags  map[string]string
+	filePath    string
+	trailer     http.Header
+	codec       JSONCodec
+	formBody    url.Values
+	pathParams  map[string]string
+	queryErr    error
 }
 
 // HTTPClient defines the interface for the HTTP client
@@ -31,6 +41,41 @@ type HTTPClient interface {
 	GetMiddlewares() []middleware.Middleware
 }
 
+// successPredicateProvider is implemented by clients that customize which responses
+// are considered successful
+type successPredicateProvider interface {
+	SuccessPredicate() func(*http.Response) bool
+}
+
+// metricNamer is implemented by clients that map request paths to low-cardinality
+// metric names, such as route templates
+type metricNamer interface {
+	MetricName(path string) string
+}
+
+// contentLengthVerifier is implemented by clients that check response bodies against
+// their Content-Length
+type contentLengthVerifier interface {
+	VerifyContentLength() bool
+}
+
+// defaultContextProvider is implemented by clients that supply a context for requests
+// made without one
+type defaultContextProvider interface {
+	DefaultContext() context.Context
+}
+
+// maxRequestBytesProvider is implemented by clients that cap the size of request bodies
+type maxRequestBytesProvider interface {
+	MaxRequestBytes() int64
+}
+
+// ErrConflictingBody is returned when a request is given more than one kind of body
+var ErrConflictingBody = errors.New("request has both a form body and another body")
+
+// ErrRequestBodyTooLarge is returned when a request body exceeds the client's maximum size
+var ErrRequestBodyTooLarge = errors.New("request body exceeds maximum size")
+
 // WithHeader sets a header for this request
 func (r *Request) WithHeader(key, value string) *Request {
 	r.Headers.Set(key, value)
@@ -45,6 +90,32 @@ func (r *Request) WithHeaders(headers map[string]string) *Request {
 	return r
 }
 
+// WithTrailer sets a trailer that is sent after the request body. Trailers require the
+// body to be sent in chunks, so a request with trailers is sent without Content-Length:
+// over HTTP/1.1 it uses chunked transfer encoding and over HTTP/2 the trailers follow the
+// DATA frames. Requests without a body cannot carry trailers.
+func (r *Request) WithTrailer(key, value string) *Request {
+	if r.trailer == nil {
+		r.trailer = make(http.Header)
+	}
+	r.trailer.Set(key, value)
+	return r
+}
+
+// WithIfNoneMatch makes the request conditional on the resource no longer matching etag,
+// e.g. one remembered from a previous response, so an unchanged resource is answered
+// with 304 Not Modified
+func (r *Request) WithIfNoneMatch(etag string) *Request {
+	r.Headers.Set("If-None-Match", etag)
+	return r
+}
+
+// WithIfModifiedSince makes the request conditional on the resource having changed after t
+func (r *Request) WithIfModifiedSince(t time.Time) *Request {
+	r.Headers.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	return r
+}
+
 // WithQuery adds a query parameter to the request
 func (r *Request) WithQuery(key, value string) *Request {
 	r.Query.Add(key, value)
@@ -59,12 +130,22 @@ func (r *Request) WithQueryMap(params map[string]string) *Request {
 	return r
 }
 
-// WithBody sets the request body
+// WithBody sets the request body. []byte and string bodies are sent as-is, io.Reader
+// and *Multipart bodies are streamed and anything else is encoded as JSON.
 func (r *Request) WithBody(body interface{}) *Request {
 	r.Body = body
 	return r
 }
 
+// WithFormBody sets the request body to values encoded as application/x-www-form-urlencoded
+// and sets the Content-Type accordingly. The form body is separate from the query string
+// set with WithQuery. Combining it with WithBody or WithFileBody makes Do fail with
+// ErrConflictingBody.
+func (r *Request) WithFormBody(values url.Values) *Request {
+	r.formBody = values
+	return r
+}
+
 // WithMiddleware adds middleware specific to this request
 func (r *Request) WithMiddleware(m middleware.Middleware) *Request {
 	if r.middlewares == nil {
@@ -83,14 +164,69 @@ func (r *Request) WithMiddlewares(middlewares ...middleware.Middleware) *Request
 	return r
 }
 
+// WithMetricName sets the low-cardinality name, such as /users/:id, that metrics and
+// tracing middleware use to label this request instead of its raw URL
+func (r *Request) WithMetricName(name string) *Request {
+	r.metricName = name
+	return r
+}
+
+// WithMetricTags adds labels, such as tenant or feature, that metrics and tracing
+// middleware attach to this request. Tags must have few distinct values: each combination
+// creates a new time series, so IDs or other unbounded values must not be used.
+func (r *Request) WithMetricTags(tags map[string]string) *Request {
+	if r.metricTags == nil {
+		r.metricTags = make(map[string]string, len(tags))
+	}
+	for k, v := range tags {
+		r.metricTags[k] = v
+	}
+	return r
+}
+
+// streamingKey is the context key marking requests whose response is streamed
+type streamingKey struct{}
+
+// StreamingContext marks ctx as belonging to a request whose response is streamed. When
+// such a context has a deadline, the client bounds the request by that deadline alone
+// rather than also by its total timeout, which is meant for unary calls. The stream
+// methods of Request mark their context automatically.
+func StreamingContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingKey{}, true)
+}
+
+// IsStreaming reports whether ctx was marked with StreamingContext
+func IsStreaming(ctx context.Context) bool {
+	streaming, _ := ctx.Value(streamingKey{}).(bool)
+	return streaming
+}
+
 // WithTimeout sets a timeout specific to this request
 func (r *Request) WithTimeout(timeout time.Duration) *Request {
 	r.timeout = &timeout
 	return r
 }
 
-// Do executes the request and returns the response
+// context returns ctx, or the client's default context if ctx is nil or context.TODO()
+func (r *Request) context(ctx context.Context) context.Context {
+	if ctx != nil && ctx != context.TODO() {
+		return ctx
+	}
+	if provider, ok := r.Client.(defaultContextProvider); ok {
+		if defaultCtx := provider.DefaultContext(); defaultCtx != nil {
+			return defaultCtx
+		}
+	}
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// Do executes the request and returns the response. A nil or context.TODO() context is
+// replaced by the client's default context, if it has one.
 func (r *Request) Do(ctx context.Context) (*Response, error) {
+	ctx = r.context(ctx)
 	if r.timeout != nil {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, *r.timeout)
@@ -98,7 +234,14 @@ func (r *Request) Do(ctx context.Context) (*Response, error) {
 	}
 
 	client := r.Client
-	parsedURL, err := url.Parse(r.URL)
+	if r.queryErr != nil {
+		return nil, r.queryErr
+	}
+	rawURL, err := r.resolvedURL()
+	if err != nil {
+		return nil, err
+	}
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
@@ -111,10 +254,37 @@ func (r *Request) Do(ctx context.Context) (*Response, error) {
 	}
 	parsedURL.RawQuery = query.Encode()
 
+	metricName := r.metricName
+	if namer, ok := client.(metricNamer); ok && metricName == "" {
+		metricName = namer.MetricName(parsedURL.Path)
+	}
+	if metricName != "" {
+		ctx = middleware.WithMetricName(ctx, metricName)
+	}
+	if len(r.metricTags) > 0 {
+		ctx = middleware.WithMetricTags(ctx, r.metricTags)
+	}
+
 	var bodyReader io.Reader
 	var rawBody []byte
+	var file *os.File
+	var fileSize int64
 
-	if r.Body != nil {
+	if r.formBody != nil {
+		if r.Body != nil || r.filePath != "" {
+			return nil, ErrConflictingBody
+		}
+		rawBody = []byte(r.formBody.Encode())
+		bodyReader = bytes.NewReader(rawBody)
+		r.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else if r.filePath != "" {
+		file, fileSize, err = r.openFileBody()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		bodyReader = file
+	} else if r.Body != nil {
 		switch b := r.Body.(type) {
 		case []byte:
 			rawBody = b
@@ -122,8 +292,19 @@ func (r *Request) Do(ctx context.Context) (*Response, error) {
 		case string:
 			rawBody = []byte(b)
 			bodyReader = bytes.NewReader(rawBody)
+		case *Multipart:
+			bodyReader = b.reader(ctx)
+			if r.Headers.Get("Content-Type") == "" {
+				r.Headers.Set("Content-Type", b.ContentType())
+			}
+		case io.Reader:
+			bodyReader = b
 		default:
-			jsonBody, err := json.Marshal(r.Body)
+			marshal := json.Marshal
+			if codec := r.jsonCodec(); codec != nil {
+				marshal = codec.Marshal
+			}
+			jsonBody, err := marshal(r.Body)
 			if err != nil {
 				return nil, err
 			}
@@ -135,12 +316,42 @@ func (r *Request) Do(ctx context.Context) (*Response, error) {
 		}
 	}
 
+	if provider, ok := client.(maxRequestBytesProvider); ok && bodyReader != nil {
+		if limit := provider.MaxRequestBytes(); limit > 0 {
+			if rawBody != nil && int64(len(rawBody)) > limit {
+				return nil, ErrRequestBodyTooLarge
+			}
+			if file != nil && fileSize > limit {
+				return nil, ErrRequestBodyTooLarge
+			}
+			// Readers of a known size, such as *bytes.Reader and *strings.Reader, are left
+			// unwrapped so that http.NewRequest still sets their ContentLength and GetBody
+			sized, isSized := bodyReader.(interface{ Len() int })
+			if isSized && int64(sized.Len()) > limit {
+				return nil, ErrRequestBodyTooLarge
+			}
+			if rawBody == nil && file == nil && !isSized {
+				bodyReader = &maxBytesReader{reader: bodyReader, remaining: limit}
+			}
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, r.Method, parsedURL.String(), bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header = r.Headers
+	if file != nil {
+		req.ContentLength = fileSize
+		req.GetBody = func() (io.ReadCloser, error) {
+			return os.Open(r.filePath)
+		}
+	}
+	if len(r.trailer) > 0 && req.Body != nil && req.Body != http.NoBody {
+		req.Trailer = r.trailer.Clone()
+		req.ContentLength = -1
+	}
 
 	baseHandler := func(ctx context.Context, req *http.Request) (*http.Response, error) {
 		return client.Do(req)
@@ -153,21 +364,73 @@ func (r *Request) Do(ctx context.Context) (*Response, error) {
 		handler = middleware.Chain(baseHandler, allMiddlewares...)
 	}
 
+	start := time.Now()
 	resp, err := handler(ctx, req)
+	duration := time.Since(start)
 	if err != nil {
 		if resp != nil {
 			resp.Body.Close()
 		}
 		return nil, err
 	}
+	if resp == nil {
+		return nil, middleware.ErrNilResponse
+	}
 
 	response := &Response{
 		Response: resp,
+		duration: duration,
+	}
+	if provider, ok := client.(successPredicateProvider); ok {
+		response.successPredicate = provider.SuccessPredicate()
 	}
+	if verifier, ok := client.(contentLengthVerifier); ok {
+		response.verifyLength = verifier.VerifyContentLength()
+	}
+	response.codec = r.jsonCodec()
 
 	return response, nil
 }
 
+// maxBytesReader fails with ErrRequestBodyTooLarge once more than the allowed number of
+// bytes has been read from a streaming body. The bytes within the limit are returned
+// first and the error on the following read.
+type maxBytesReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+// Read implements io.Reader
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.reader.Read(p)
+	if int64(n) > m.remaining {
+		n = int(m.remaining)
+		m.remaining = 0
+		m.err = ErrRequestBodyTooLarge
+		if n == 0 {
+			return 0, m.err
+		}
+		return n, nil
+	}
+	m.remaining -= int64(n)
+	return n, err
+}
+
+// Close implements io.Closer, closing the underlying reader if it is an io.Closer
+func (m *maxBytesReader) Close() error {
+	if closer, ok := m.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // buildMiddlewareChain combines client middlewares with request-specific middlewares
 func (r *Request) buildMiddlewareChain() []middleware.Middleware {
 	clientMiddlewares := r.Client.GetMiddlewares()
@@ -186,7 +449,7 @@ func (r *Request) buildMiddlewareChain() []middleware.Middleware {
 
 // Stream executes the request and streams the response as raw bytes
 func (r *Request) Stream(ctx context.Context, handler func([]byte) error, opts ...StreamOption) error {
-	resp, err := r.Do(ctx)
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
 	if err != nil {
 		return err
 	}
@@ -195,7 +458,7 @@ func (r *Request) Stream(ctx context.Context, handler func([]byte) error, opts .
 
 // StreamLines executes the request and streams the response line by line
 func (r *Request) StreamLines(ctx context.Context, handler func([]byte) error, opts ...StreamOption) error {
-	resp, err := r.Do(ctx)
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
 	if err != nil {
 		return err
 	}
@@ -203,28 +466,46 @@ func (r *Request) StreamLines(ctx context.Context, handler func([]byte) error, o
 }
 
 // StreamJSON executes the request and streams the response as JSON objects
-func (r *Request) StreamJSON(ctx context.Context, handler func(json.RawMessage) error) error {
-	resp, err := r.Do(ctx)
+func (r *Request) StreamJSON(ctx context.Context, handler func(json.RawMessage) error, opts ...StreamOption) error {
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
 	if err != nil {
 		return err
 	}
-	return resp.StreamJSON(handler)
+	return resp.StreamJSON(handler, opts...)
 }
 
 // StreamInto executes the request and unmarshals each JSON object into the specified type
-func (r *Request) StreamInto(ctx context.Context, handler interface{}) error {
-	resp, err := r.Do(ctx)
+func (r *Request) StreamInto(ctx context.Context, handler interface{}, opts ...StreamOption) error {
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
 	if err != nil {
 		return err
 	}
-	return resp.StreamInto(handler)
+	return resp.StreamInto(handler, opts...)
 }
 
 // StreamSSE executes the request and streams the response as Server-Sent Events
-func (r *Request) StreamSSE(ctx context.Context, handler EventSourceHandler) error {
-	resp, err := r.Do(ctx)
+func (r *Request) StreamSSE(ctx context.Context, handler EventSourceHandler, opts ...StreamOption) error {
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
+	if err != nil {
+		return err
+	}
+	return resp.StreamSSE(handler, opts...)
+}
+
+// StreamSSEMux executes the request and routes Server-Sent Events through the mux
+func (r *Request) StreamSSEMux(ctx context.Context, mux *EventMux, opts ...StreamOption) error {
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
+	if err != nil {
+		return err
+	}
+	return resp.StreamSSEMux(mux, opts...)
+}
+
+// StreamSSEBatch executes the request and processes Server-Sent Events in batches
+func (r *Request) StreamSSEBatch(ctx context.Context, handler func([]Event) error, maxBatch int, maxWait time.Duration, opts ...StreamOption) error {
+	resp, err := r.Do(StreamingContext(r.context(ctx)))
 	if err != nil {
 		return err
 	}
-	return resp.StreamSSE(handler)
+	return resp.StreamSSEBatch(handler, maxBatch, maxWait, opts...)
 }
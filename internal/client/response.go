@@ -2,22 +2,116 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Response wraps the standard http.Response with additional utility methods
 type Response struct {
 	*http.Response
+	successPredicate func(*http.Response) bool
+	verifyLength     bool
+	codec            JSONCodec
+	duration         time.Duration
+
+	closeOnce sync.Once
+	closeErr  error
+
+	problemOnce sync.Once
+	problem     *ProblemDetails
+}
+
+// maxDrainBytes bounds how much of an unread body Close discards so the underlying
+// connection can be reused. Larger remainders are cheaper to abandon than to read.
+const maxDrainBytes = 64 << 10
+
+// ErrNoBody is returned when decoding is requested on a response that cannot carry a body
+var ErrNoBody = errors.New("response has no body")
+
+// StatusError is returned by Err for responses that are not considered successful
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// Detail holds the decoded error body when the error was produced by AsError
+	Detail interface{}
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	if e.Status != "" {
+		return "unexpected response status: " + e.Status
+	}
+	return fmt.Sprintf("unexpected response status: %d", e.StatusCode)
+}
+
+// Unwrap returns Detail if it is an error, such as a *ProblemDetails
+func (e *StatusError) Unwrap() error {
+	if err, ok := e.Detail.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// ShortReadError is returned when the connection ended before the number of bytes
+// announced by Content-Length was read. It matches io.ErrUnexpectedEOF.
+type ShortReadError struct {
+	// Expected is the Content-Length of the response
+	Expected int64
+	// Read is the number of bytes actually read
+	Read int64
+}
+
+// Error implements the error interface
+func (e *ShortReadError) Error() string {
+	return fmt.Sprintf("truncated response body: read %d of %d bytes", e.Read, e.Expected)
 }
 
-// Bytes reads the entire response body and returns it as a byte slice
+// Unwrap allows errors.Is(err, io.ErrUnexpectedEOF)
+func (e *ShortReadError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// HasBody reports whether the response can carry a body. Responses to HEAD requests and
+// 1xx, 204 No Content and 304 Not Modified responses never have one.
+func (r *Response) HasBody() bool {
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return false
+	}
+	switch {
+	case r.StatusCode >= 100 && r.StatusCode < 200:
+		return false
+	case r.StatusCode == http.StatusNoContent, r.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// Bytes reads the entire response body and returns it as a byte slice.
+// For responses without a body it returns an empty slice.
 func (r *Response) Bytes() ([]byte, error) {
 	defer r.Body.Close()
-	return io.ReadAll(r.Body)
+	if !r.HasBody() {
+		return []byte{}, nil
+	}
+	if !r.checksLength() {
+		return io.ReadAll(r.Body)
+	}
+
+	counter := &countingReader{reader: r.Body}
+	data, err := io.ReadAll(counter)
+	if lengthErr := r.verifyRead(counter.n, err); lengthErr != nil {
+		return nil, lengthErr
+	}
+	return data, err
 }
 
 // String reads the entire response body and returns it as a string
@@ -30,15 +124,172 @@ func (r *Response) String() (string, error) {
 	return string(bytes), nil
 }
 
-// JSON unmarshals the response body into the provided interface
+// JSON unmarshals the response body into the provided interface, using the JSON codec of
+// the request or client if one is set. For responses without a body it returns nil and
+// leaves v untouched.
 func (r *Response) JSON(v interface{}) error {
+	if r.codec != nil {
+		if !r.HasBody() {
+			r.Body.Close()
+			return nil
+		}
+		data, err := r.Bytes()
+		if err != nil {
+			return err
+		}
+		return r.codec.Unmarshal(data, v)
+	}
+
 	defer r.Body.Close()
-	return json.NewDecoder(r.Body).Decode(v)
+	if !r.HasBody() {
+		return nil
+	}
+	if !r.checksLength() {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+
+	// Decoding stops after the first value, so the rest of the body is read to make sure
+	// the response was not cut short
+	counter := &countingReader{reader: r.Body}
+	err := json.NewDecoder(counter).Decode(v)
+	if err == nil {
+		_, err = io.Copy(io.Discard, counter)
+	}
+	if lengthErr := r.verifyRead(counter.n, err); lengthErr != nil {
+		return lengthErr
+	}
+	return err
+}
+
+// checksLength reports whether the body must be checked against Content-Length
+func (r *Response) checksLength() bool {
+	return r.verifyLength && r.ContentLength >= 0
+}
+
+// verifyRead returns a *ShortReadError if fewer than ContentLength bytes were read,
+// either because reading failed with an unexpected EOF or because it ended early
+func (r *Response) verifyRead(n int64, err error) error {
+	if n >= r.ContentLength {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return &ShortReadError{Expected: r.ContentLength, Read: n}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
 }
 
-// Close closes the response body
+// Read implements io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// DecodeFirst decodes the first JSON value from the response body into v and returns a
+// response whose body continues right after that value, so the rest of the stream can
+// still be consumed with the streaming helpers. A single line terminator following the
+// value is skipped so NDJSON records can be streamed line by line.
+func (r *Response) DecodeFirst(v interface{}) (*Response, error) {
+	if !r.HasBody() {
+		r.Body.Close()
+		return nil, ErrNoBody
+	}
+
+	counter := &countingReader{reader: r.Body}
+	decoder := json.NewDecoder(counter)
+	if err := decoder.Decode(v); err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+
+	buffered, err := io.ReadAll(decoder.Buffered())
+	if err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+	buffered = bytes.TrimPrefix(buffered, []byte("\r"))
+	buffered = bytes.TrimPrefix(buffered, []byte("\n"))
+
+	restHTTP := *r.Response
+	restHTTP.Body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(buffered), r.Body),
+		Closer: r.Body,
+	}
+	// The remaining length keeps the Content-Length check meaningful for the rest
+	if restHTTP.ContentLength >= 0 {
+		restHTTP.ContentLength += int64(len(buffered)) - counter.n
+	}
+
+	return &Response{
+		Response:         &restHTTP,
+		successPredicate: r.successPredicate,
+		verifyLength:     r.verifyLength,
+		codec:            r.codec,
+		duration:         r.duration,
+	}, nil
+}
+
+// JSONAll decodes every top-level JSON value of a body made of concatenated documents, such
+// as {"a":1}{"a":2}, appending each to the slice v points to. Values may be separated by
+// whitespace, so NDJSON bodies are decoded as well. The whole body is read; use StreamJSON
+// to handle the values one at a time instead.
+func (r *Response) JSONAll(v interface{}) error {
+	slice := reflect.ValueOf(v)
+	if slice.Kind() != reflect.Pointer || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		r.Body.Close()
+		return fmt.Errorf("JSONAll requires a non-nil pointer to a slice, got %T", v)
+	}
+	slice = slice.Elem()
+
+	data, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+
+	unmarshal := json.Unmarshal
+	if r.codec != nil {
+		unmarshal = r.codec.Unmarshal
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		elem := reflect.New(slice.Type().Elem())
+		if err := unmarshal(raw, elem.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+}
+
+// readCloser combines a reader with the closer of the underlying body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Close drains up to maxDrainBytes of the remaining body, so the connection can be
+// reused, and closes it. It is safe to call Close multiple times and concurrently;
+// subsequent calls return the result of the first.
 func (r *Response) Close() error {
-	return r.Body.Close()
+	r.closeOnce.Do(func() {
+		if r.Body == nil {
+			return
+		}
+		_, _ = io.CopyN(io.Discard, r.Body, maxDrainBytes)
+		r.closeErr = r.Body.Close()
+	})
+	return r.closeErr
 }
 
 // WriteTo implements io.WriterTo, streaming the response body to the provided writer
@@ -47,6 +298,26 @@ func (r *Response) WriteTo(w io.Writer) (int64, error) {
 	return io.Copy(w, r.Body)
 }
 
+// TransformLines reads the response body line by line, passes each line through transform
+// and writes the result to w followed by a newline, flushing after each line if w is an
+// http.Flusher. A nil result drops the line. Line splitting follows StreamLines.
+func (r *Response) TransformLines(w io.Writer, transform func(line []byte) ([]byte, error), opts ...StreamOption) error {
+	flusher, _ := w.(http.Flusher)
+	return r.StreamLines(func(line []byte) error {
+		out, err := transform(line)
+		if err != nil || out == nil {
+			return err
+		}
+		if _, err := w.Write(append(out, '\n')); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}, opts...)
+}
+
 // Pipe allows for piping the response body to the provided channel
 func (r *Response) Pipe(ch chan<- []byte) error {
 	defer r.Body.Close()
@@ -70,11 +341,108 @@ func (r *Response) Consume() error {
 	return err
 }
 
-// IsSuccess returns true if the status code is between 200 and 299
+// ContentType parses the Content-Type header and returns the lowercase media type and its
+// parameters. If the header cannot be fully parsed, the media type is still returned
+// with an empty parameter map.
+func (r *Response) ContentType() (mediaType string, params map[string]string) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return "", map[string]string{}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(header, ";")
+		return strings.ToLower(strings.TrimSpace(mediaType)), map[string]string{}
+	}
+	return mediaType, params
+}
+
+// Charset returns the lowercase charset parameter of the Content-Type header, or an
+// empty string if none is set
+func (r *Response) Charset() string {
+	_, params := r.ContentType()
+	return strings.ToLower(params["charset"])
+}
+
+// Cookies parses and returns the cookies set by the Set-Cookie headers
+func (r *Response) Cookies() []*http.Cookie {
+	return r.Response.Cookies()
+}
+
+// Cookie returns the cookie with the given name set by the response, if any
+func (r *Response) Cookie(name string) (*http.Cookie, bool) {
+	for _, cookie := range r.Response.Cookies() {
+		if cookie.Name == name {
+			return cookie, true
+		}
+	}
+	return nil, false
+}
+
+// IsSuccess returns true if the status code is between 200 and 299, or, when the client
+// was configured with WithSuccessPredicate, if the predicate accepts the response
 func (r *Response) IsSuccess() bool {
+	if r.successPredicate != nil {
+		return r.successPredicate(r.Response)
+	}
 	return r.StatusCode >= 200 && r.StatusCode <= 299
 }
 
+// Err returns a *StatusError if the response is not successful according to IsSuccess.
+// For application/problem+json responses, the body is decoded and the resulting
+// *ProblemDetails is set as the error's Detail.
+func (r *Response) Err() error {
+	if r.IsSuccess() {
+		return nil
+	}
+	statusErr := &StatusError{StatusCode: r.StatusCode, Status: r.Status}
+	if problem, ok := r.Problem(); ok {
+		statusErr.Detail = problem
+	}
+	return statusErr
+}
+
+// AsError decodes the body of an unsuccessful response into target, a pointer to an
+// error-shaped struct. If the decoded value implements error it is returned as-is,
+// otherwise it is returned wrapped in a *StatusError as its Detail. Successful responses
+// return nil and leave the body untouched.
+func (r *Response) AsError(target interface{}) error {
+	if r.IsSuccess() {
+		return nil
+	}
+
+	statusErr := &StatusError{StatusCode: r.StatusCode, Status: r.Status}
+	if err := r.JSON(target); err != nil {
+		return fmt.Errorf("%w: failed to decode error body: %v", statusErr, err)
+	}
+
+	if err, ok := target.(error); ok {
+		return err
+	}
+	if value := reflect.ValueOf(target); value.Kind() == reflect.Ptr && !value.IsNil() {
+		if err, ok := value.Elem().Interface().(error); ok {
+			return err
+		}
+	}
+
+	statusErr.Detail = target
+	return statusErr
+}
+
+// Duration returns the time from sending the request to receiving the response headers,
+// excluding reading the body. It covers the whole middleware chain, so retries and their
+// backoff are included.
+func (r *Response) Duration() time.Duration {
+	return r.duration
+}
+
+// NotModified reports whether the response is a 304 Not Modified answer to a conditional
+// request, meaning the copy the caller already has is still current
+func (r *Response) NotModified() bool {
+	return r.StatusCode == http.StatusNotModified
+}
+
 // IsRedirect returns true if the status code is 3xx
 func (r *Response) IsRedirect() bool {
 	return r.StatusCode >= 300 && r.StatusCode <= 399
@@ -118,11 +486,21 @@ func (r *Response) StreamInto(handler interface{}, opts ...StreamOption) error {
 }
 
 // StreamSSE processes a Server-Sent Events stream with the provided handler function.
-func (r *Response) StreamSSE(handler EventSourceHandler) error {
-	if !strings.Contains(r.Header.Get("Content-Type"), "text/event-stream") {
+func (r *Response) StreamSSE(handler EventSourceHandler, opts ...StreamOption) error {
+	if mediaType, _ := r.ContentType(); mediaType != "text/event-stream" {
 		r.Close()
 		return errors.New("unexpected content type for SSE: " + r.Header.Get("Content-Type"))
 	}
 
-	return StreamSSE(r.Body, handler)
+	defer closeOnCancel(r)()
+	if err := StreamSSE(r.Body, handler, opts...); err != nil {
+		return cancellationErr(r, err)
+	}
+	return nil
+}
+
+// StreamSSEMux processes a Server-Sent Events stream, dispatching each event to the
+// handler registered on the mux for its event type.
+func (r *Response) StreamSSEMux(mux *EventMux, opts ...StreamOption) error {
+	return r.StreamSSE(mux, opts...)
 }
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"time"
+)
+
+// errBatchStopped stops the underlying SSE stream once batching has ended
+var errBatchStopped = errors.New("sse batch stopped")
+
+// StreamSSEBatch processes a Server-Sent Events stream, delivering events to the handler
+// in batches of up to maxBatch events. A partial batch is delivered once maxWait has
+// passed since its first event (0 = wait for a full batch) and when the stream ends.
+func (r *Response) StreamSSEBatch(handler func([]Event) error, maxBatch int, maxWait time.Duration, opts ...StreamOption) error {
+	if maxBatch <= 0 {
+		maxBatch = 1
+	}
+
+	events := make(chan Event)
+	done := make(chan struct{})
+	streamErr := make(chan error, 1)
+
+	go func() {
+		streamErr <- r.StreamSSE(EventHandlerFunc(func(event Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-done:
+				return errBatchStopped
+			}
+		}), opts...)
+	}()
+
+	var batch []Event
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	flush := func() error {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		delivered := batch
+		batch = nil
+		return handler(delivered)
+	}
+
+	// stop ends the stream early, unblocking a pending read by closing the body
+	stop := func(err error) error {
+		close(done)
+		r.Body.Close()
+		<-streamErr
+		return err
+	}
+
+	for {
+		select {
+		case event := <-events:
+			batch = append(batch, event)
+			if len(batch) == 1 && maxWait > 0 {
+				timer = time.NewTimer(maxWait)
+				timeout = timer.C
+			}
+			if len(batch) >= maxBatch {
+				if err := flush(); err != nil {
+					return stop(err)
+				}
+			}
+		case <-timeout:
+			if err := flush(); err != nil {
+				return stop(err)
+			}
+		case err := <-streamErr:
+			if flushErr := flush(); flushErr != nil {
+				return flushErr
+			}
+			return err
+		}
+	}
+}
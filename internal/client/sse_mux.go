@@ -0,0 +1,58 @@
+// Package client implements the internal HTTP request/response handling
+package client
+
+import "sync"
+
+// defaultEventType is the event type assumed by the SSE spec when no event field is sent
+const defaultEventType = "message"
+
+// EventMux routes Server-Sent Events to handlers registered by event type
+type EventMux struct {
+	mu             sync.RWMutex
+	handlers       map[string]EventHandlerFunc
+	defaultHandler EventHandlerFunc
+}
+
+// NewEventMux creates a new, empty event router
+func NewEventMux() *EventMux {
+	return &EventMux{
+		handlers: make(map[string]EventHandlerFunc),
+	}
+}
+
+// On registers a handler for the given event type. Events sent without an
+// event field are routed as "message", as defined by the SSE specification.
+func (m *EventMux) On(eventType string, handler EventHandlerFunc) *EventMux {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[eventType] = handler
+	return m
+}
+
+// Default registers the handler used for events without a dedicated handler
+func (m *EventMux) Default(handler EventHandlerFunc) *EventMux {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultHandler = handler
+	return m
+}
+
+// OnEvent implements EventSourceHandler interface
+func (m *EventMux) OnEvent(event Event) error {
+	eventType := event.Event
+	if eventType == "" {
+		eventType = defaultEventType
+	}
+
+	m.mu.RLock()
+	handler, ok := m.handlers[eventType]
+	if !ok {
+		handler = m.defaultHandler
+	}
+	m.mu.RUnlock()
+
+	if handler == nil {
+		return nil
+	}
+	return handler(event)
+}
@@ -3,10 +3,15 @@ package client
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -17,8 +22,15 @@ type StreamOption func(*streamOptions)
 type streamOptions struct {
 	buffSize      int
 	contentType   string
+	logicalType   string
 	delimiterStr  string
 	delimiterByte byte
+	errorDetector func(json.RawMessage) bool
+	keepDelimiter bool
+	maxRecords    int
+	maxBytes      int64
+	records       int
+	bytes         int64
 }
 
 // WithBufferSize sets the buffer size for stream reading
@@ -50,6 +62,153 @@ func WithContentType(contentType string) StreamOption {
 	}
 }
 
+// WithLogicalContentType sets the expected content type of the decoded stream, for
+// servers that send compressed streams such as gzipped NDJSON. A response whose
+// Content-Type matches passes the check as usual; one sent as application/gzip or with a
+// gzip Content-Encoding that the transport did not remove is transparently decompressed
+// and trusted to carry the logical type.
+func WithLogicalContentType(contentType string) StreamOption {
+	return func(o *streamOptions) {
+		o.logicalType = contentType
+	}
+}
+
+// WithKeepDelimiter makes StreamLines pass each line to the handler with its delimiter
+// intact, e.g. for byte-accurate proxying or offset tracking
+func WithKeepDelimiter() StreamOption {
+	return func(o *streamOptions) {
+		o.keepDelimiter = true
+	}
+}
+
+// WithMaxRecords stops streaming with a *StreamLimitError before delivering more than n
+// records (chunks, lines, JSON objects or events) to the handler
+func WithMaxRecords(n int) StreamOption {
+	return func(o *streamOptions) {
+		o.maxRecords = n
+	}
+}
+
+// WithMaxBytes stops streaming with a *StreamLimitError before the records delivered to
+// the handler would exceed n bytes in total
+func WithMaxBytes(n int64) StreamOption {
+	return func(o *streamOptions) {
+		o.maxBytes = n
+	}
+}
+
+// StreamLimitError is returned when a stream exceeds WithMaxRecords or WithMaxBytes
+type StreamLimitError struct {
+	// Limit is the limit that was exceeded, "records" or "bytes"
+	Limit string
+	// Max is the configured maximum
+	Max int64
+}
+
+// Error implements the error interface
+func (e *StreamLimitError) Error() string {
+	return fmt.Sprintf("stream exceeded maximum of %d %s", e.Max, e.Limit)
+}
+
+// admit accounts for a record about to be delivered, returning a *StreamLimitError if
+// it would exceed the configured limits
+func (o *streamOptions) admit(record []byte) error {
+	if o.maxRecords > 0 && o.records >= o.maxRecords {
+		return &StreamLimitError{Limit: "records", Max: int64(o.maxRecords)}
+	}
+	if o.maxBytes > 0 && o.bytes+int64(len(record)) > o.maxBytes {
+		return &StreamLimitError{Limit: "bytes", Max: o.maxBytes}
+	}
+	o.records++
+	o.bytes += int64(len(record))
+	return nil
+}
+
+// WithErrorDetector recognizes records that signal a terminal error, such as an
+// {"error": ...} object emitted by a server that fails mid-stream. Matching records are
+// not passed to the handler; streaming stops and a *StreamError is returned instead.
+func WithErrorDetector(detect func(json.RawMessage) bool) StreamOption {
+	return func(o *streamOptions) {
+		o.errorDetector = detect
+	}
+}
+
+// StreamError is returned when a record recognized by WithErrorDetector is received
+type StreamError struct {
+	// Raw is the JSON error record sent by the server
+	Raw json.RawMessage
+}
+
+// Error implements the error interface
+func (e *StreamError) Error() string {
+	return "stream error: " + string(e.Raw)
+}
+
+// detectStreamError returns a *StreamError if data is a JSON record matched by the
+// configured error detector
+func (o *streamOptions) detectStreamError(data []byte) error {
+	if o.errorDetector == nil || !json.Valid(data) {
+		return nil
+	}
+	if !o.errorDetector(data) {
+		return nil
+	}
+	raw := make(json.RawMessage, len(data))
+	copy(raw, data)
+	return &StreamError{Raw: raw}
+}
+
+// gzipContentTypes are the content types of gzip containers
+var gzipContentTypes = []string{"application/gzip", "application/x-gzip"}
+
+// body checks the content type of r against the configured expectations and returns the
+// reader the stream must be consumed from
+func (o *streamOptions) body(r *Response) (io.Reader, error) {
+	contentType := r.Header.Get("Content-Type")
+	if o.contentType != "" && !strings.Contains(contentType, o.contentType) {
+		return nil, errors.New("unexpected content type: " + contentType)
+	}
+	if o.logicalType == "" {
+		return r.Body, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	compressed := slices.Contains(gzipContentTypes, mediaType) ||
+		strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip")
+	if !compressed {
+		if !strings.Contains(contentType, o.logicalType) {
+			return nil, errors.New("unexpected content type: " + contentType)
+		}
+		return r.Body, nil
+	}
+	return gzip.NewReader(r.Body)
+}
+
+// closeOnCancel closes the body of r as soon as the context of its request is done, so
+// that a read blocked on a connection that stopped delivering data returns even when
+// the transport or a middleware-provided body does not watch the context. The returned
+// function stops watching.
+func closeOnCancel(r *Response) func() {
+	if r.Request == nil {
+		return func() {}
+	}
+	stop := context.AfterFunc(r.Request.Context(), func() {
+		r.Body.Close()
+	})
+	return func() { stop() }
+}
+
+// cancellationErr returns the context error of the request of r in place of err if the
+// context is done, since err is then the result of the body being closed
+func cancellationErr(r *Response, err error) error {
+	if r.Request != nil {
+		if ctxErr := r.Request.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
+
 // defaultStreamOptions returns the default stream options
 func defaultStreamOptions() *streamOptions {
 	return &streamOptions{
@@ -68,25 +227,27 @@ func Stream(r *Response, handler func([]byte) error, opts ...StreamOption) error
 		return errors.New("response body is nil")
 	}
 	defer r.Body.Close()
+	defer closeOnCancel(r)()
 
 	options := defaultStreamOptions()
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	if options.contentType != "" {
-		contentType := r.Header.Get("Content-Type")
-		if !strings.Contains(contentType, options.contentType) {
-			return errors.New("unexpected content type: " + contentType)
-		}
+	body, err := options.body(r)
+	if err != nil {
+		return err
 	}
 
 	buffer := make([]byte, options.buffSize)
 
 	for {
-		n, err := r.Body.Read(buffer)
+		n, err := body.Read(buffer)
 
 		if n > 0 {
+			if limitErr := options.admit(buffer[:n]); limitErr != nil {
+				return limitErr
+			}
 			if handlerErr := handler(buffer[:n]); handlerErr != nil {
 				return handlerErr
 			}
@@ -96,7 +257,7 @@ func Stream(r *Response, handler func([]byte) error, opts ...StreamOption) error
 			if err == io.EOF {
 				return nil
 			}
-			return err
+			return cancellationErr(r, err)
 		}
 	}
 }
@@ -109,27 +270,30 @@ func StreamLines(r *Response, handler func([]byte) error, opts ...StreamOption)
 		return errors.New("response body is nil")
 	}
 	defer r.Body.Close()
+	defer closeOnCancel(r)()
 
 	options := defaultStreamOptions()
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	if options.contentType != "" {
-		contentType := r.Header.Get("Content-Type")
-		if !strings.Contains(contentType, options.contentType) {
-			return errors.New("unexpected content type: " + contentType)
-		}
+	body, err := options.body(r)
+	if err != nil {
+		return err
 	}
 
-	scanner := bufio.NewScanner(r.Body)
-	if options.delimiterStr != "\n" {
+	scanner := bufio.NewScanner(body)
+	if options.delimiterStr != "\n" || options.keepDelimiter {
 		scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
 			if atEOF && len(data) == 0 {
 				return 0, nil, nil
 			}
 			if i := strings.Index(string(data), options.delimiterStr); i >= 0 {
-				return i + len(options.delimiterStr), data[0:i], nil
+				end := i + len(options.delimiterStr)
+				if options.keepDelimiter {
+					return end, data[0:end], nil
+				}
+				return end, data[0:i], nil
 			}
 			if atEOF {
 				return len(data), data, nil
@@ -140,13 +304,19 @@ func StreamLines(r *Response, handler func([]byte) error, opts ...StreamOption)
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
+		if streamErr := options.detectStreamError(line); streamErr != nil {
+			return streamErr
+		}
+		if limitErr := options.admit(line); limitErr != nil {
+			return limitErr
+		}
 		if handlerErr := handler(line); handlerErr != nil {
 			return handlerErr
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		return cancellationErr(r, err)
 	}
 	return nil
 }
@@ -159,13 +329,19 @@ func StreamJSON(r *Response, handler func(json.RawMessage) error, opts ...Stream
 		return errors.New("response body is nil")
 	}
 	defer r.Body.Close()
+	defer closeOnCancel(r)()
 
 	options := defaultStreamOptions()
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	decoder := json.NewDecoder(r.Body)
+	body, err := options.body(r)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(body)
 	for {
 		var raw json.RawMessage
 		err := decoder.Decode(&raw)
@@ -173,7 +349,15 @@ func StreamJSON(r *Response, handler func(json.RawMessage) error, opts ...Stream
 			if err == io.EOF {
 				return nil
 			}
-			return err
+			return cancellationErr(r, err)
+		}
+
+		if streamErr := options.detectStreamError(raw); streamErr != nil {
+			return streamErr
+		}
+
+		if limitErr := options.admit(raw); limitErr != nil {
+			return limitErr
 		}
 
 		if handlerErr := handler(raw); handlerErr != nil {
@@ -189,6 +373,7 @@ func StreamInto(r *Response, handler interface{}, opts ...StreamOption) error {
 		return errors.New("response body is nil")
 	}
 	defer r.Body.Close()
+	defer closeOnCancel(r)()
 
 	options := defaultStreamOptions()
 	for _, opt := range opts {
@@ -208,7 +393,12 @@ func StreamInto(r *Response, handler interface{}, opts ...StreamOption) error {
 	elemType := handlerType.In(0)
 	isPtr := elemType.Kind() == reflect.Ptr
 
-	decoder := json.NewDecoder(r.Body)
+	body, err := options.body(r)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(body)
 	for {
 		var elem reflect.Value
 		if isPtr {
@@ -217,11 +407,28 @@ func StreamInto(r *Response, handler interface{}, opts ...StreamOption) error {
 			elem = reflect.New(elemType).Elem()
 		}
 
-		err := decoder.Decode(elem.Interface())
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
 		if err != nil {
 			if err == io.EOF {
 				return nil
 			}
+			return cancellationErr(r, err)
+		}
+
+		if streamErr := options.detectStreamError(raw); streamErr != nil {
+			return streamErr
+		}
+
+		if limitErr := options.admit(raw); limitErr != nil {
+			return limitErr
+		}
+
+		target := elem
+		if !isPtr {
+			target = elem.Addr()
+		}
+		if err := json.Unmarshal(raw, target.Interface()); err != nil {
 			return err
 		}
 
@@ -241,6 +448,14 @@ type Event struct {
 	Retry int
 }
 
+// JSON unmarshals the event data into the provided interface
+func (e Event) JSON(v interface{}) error {
+	if !json.Valid([]byte(e.Data)) {
+		return fmt.Errorf("sse event data is not valid JSON: %q", e.Data)
+	}
+	return json.Unmarshal([]byte(e.Data), v)
+}
+
 // EventSourceHandler handles incoming Server-Sent Events
 type EventSourceHandler interface {
 	OnEvent(event Event) error
@@ -293,9 +508,14 @@ func (l *EventFullHandlerFunc) OnClose() error {
 }
 
 // StreamSSE processes a Server-Sent Events stream with the provided handler.
-func StreamSSE(reader io.ReadCloser, handler EventSourceHandler) error {
+func StreamSSE(reader io.ReadCloser, handler EventSourceHandler, opts ...StreamOption) error {
 	defer reader.Close()
 
+	options := defaultStreamOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	if lifecycleHandler, ok := handler.(EventSourceFullHandler); ok {
 		if handlerErr := lifecycleHandler.OnOpen(); handlerErr != nil {
 			return handlerErr
@@ -313,6 +533,12 @@ func StreamSSE(reader io.ReadCloser, handler EventSourceHandler) error {
 		if line == "" {
 			if data.Len() > 0 {
 				event.Data = data.String()
+				if streamErr := options.detectStreamError([]byte(event.Data)); streamErr != nil {
+					return streamErr
+				}
+				if limitErr := options.admit([]byte(event.Data)); limitErr != nil {
+					return limitErr
+				}
 				if handlerErr := handler.OnEvent(event); handlerErr != nil {
 					return handlerErr
 				}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// XML unmarshals the response body into v, mirroring JSON. For responses without a body
+// it returns nil and leaves v untouched.
+//
+// The body is read in the charset declared by the Content-Type header, or else by the XML
+// declaration, defaulting to UTF-8. UTF-8, US-ASCII and ISO-8859-1 are supported; use
+// the charset package to transcode bodies in other charsets first.
+func (r *Response) XML(v interface{}) error {
+	defer r.Body.Close()
+	if !r.HasBody() {
+		return nil
+	}
+
+	var body io.Reader = r.Body
+	var counter *countingReader
+	if r.checksLength() {
+		counter = &countingReader{reader: r.Body}
+		body = counter
+	}
+
+	var decoder *xml.Decoder
+	if name := r.Charset(); name != "" {
+		transcoded, err := xmlCharsetReader(name, body)
+		if err != nil {
+			return err
+		}
+		decoder = xml.NewDecoder(transcoded)
+		// The Content-Type charset takes precedence over the XML declaration
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	} else {
+		decoder = xml.NewDecoder(body)
+		decoder.CharsetReader = xmlCharsetReader
+	}
+
+	err := decoder.Decode(v)
+	if counter == nil {
+		return err
+	}
+	// Decoding stops after the root element, so the rest of the body is read to make sure
+	// the response was not cut short
+	if err == nil {
+		_, err = io.Copy(io.Discard, counter)
+	}
+	if lengthErr := r.verifyRead(counter.n, err); lengthErr != nil {
+		return lengthErr
+	}
+	return err
+}
+
+// xmlCharsetReader returns a reader transcoding input from the named charset to UTF-8
+func xmlCharsetReader(name string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return &latin1Reader{input: input}, nil
+	}
+	return nil, fmt.Errorf("unsupported XML charset %q", name)
+}
+
+// latin1Reader transcodes ISO-8859-1 to UTF-8
+type latin1Reader struct {
+	input   io.Reader
+	pending []byte
+}
+
+// Read implements io.Reader
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		// Each byte expands to at most two, so reading half of p keeps up with it
+		buf := make([]byte, max(len(p)/2, 1))
+		n, err := l.input.Read(buf)
+		for _, b := range buf[:n] {
+			l.pending = utf8.AppendRune(l.pending, rune(b))
+		}
+		if n == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
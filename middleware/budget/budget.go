@@ -0,0 +1,98 @@
+// Package budget provides a middleware that enforces request time budgets.
+//
+// Outbound calls made while serving a request with an overall deadline are doomed when
+// the remaining budget is too small for them to plausibly succeed. The middleware fails
+// such calls immediately with an *Error instead of letting them run into the deadline,
+// and can propagate the remaining budget to the server in a request header.
+//
+// Requests whose context has no deadline are passed through unchanged.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// ErrInsufficientBudget is matched by errors returned when a request is rejected
+// because too little time is left before the context deadline
+var ErrInsufficientBudget = errors.New("insufficient time budget")
+
+// Error is returned when the remaining budget is below the configured minimum
+type Error struct {
+	// Remaining is the time left before the context deadline
+	Remaining time.Duration
+	// MinRemaining is the configured minimum budget
+	MinRemaining time.Duration
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("insufficient time budget: %v remaining, need at least %v", e.Remaining, e.MinRemaining)
+}
+
+// Unwrap allows errors.Is(err, ErrInsufficientBudget)
+func (e *Error) Unwrap() error {
+	return ErrInsufficientBudget
+}
+
+// Config represents the configuration for the budget middleware
+type Config struct {
+	// MinRemaining is the minimum time that must be left before the context deadline for
+	// a request to be sent
+	MinRemaining time.Duration
+	// DeadlineHeader, if set, is the header used to send the remaining budget in
+	// milliseconds to the server, e.g. "X-Request-Timeout-Ms"
+	DeadlineHeader string
+}
+
+// DefaultConfig returns a configuration requiring at least 100ms of remaining budget
+func DefaultConfig() *Config {
+	return &Config{
+		MinRemaining: 100 * time.Millisecond,
+	}
+}
+
+// Middleware is the budget middleware implementation
+type Middleware struct {
+	config *Config
+}
+
+// New creates a new budget middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return next(ctx, req)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining < m.config.MinRemaining {
+			return nil, &Error{Remaining: remaining, MinRemaining: m.config.MinRemaining}
+		}
+
+		if m.config.DeadlineHeader != "" {
+			// The header map may be shared with the caller, e.g. the Headers of an
+			// httpio.Request, which must not carry this budget into later sends
+			req.Header = req.Header.Clone()
+			req.Header.Set(m.config.DeadlineHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+		}
+
+		return next(ctx, req)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
 	"io"
@@ -26,7 +27,8 @@ func init() {
 	gob.Register(&http.Request{})
 }
 
-// CacheableStatus defines HTTP status codes that can be cached
+// CacheableStatus defines HTTP status codes that can be cached. Set
+// Config.CacheableStatusCodes to use a different set for a single middleware.
 var CacheableStatus = map[int]bool{
 	http.StatusOK:                   true,
 	http.StatusNonAuthoritativeInfo: true,
@@ -54,6 +56,15 @@ type CachedResponse struct {
 	ExpiresAt time.Time
 }
 
+// newResponse builds a fresh response from the cached entry, so that concurrent
+// readers never share a header map or body reader
+func (c *CachedResponse) newResponse() *http.Response {
+	resp := *c.Response
+	resp.Header = c.Response.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.Body))
+	return &resp
+}
+
 // Cache defines the interface that all cache implementations must satisfy
 type Cache interface {
 	Get(ctx context.Context, key string) (*CachedResponse, bool)
@@ -71,6 +82,10 @@ type Middleware struct {
 	config *Config
 	// keyStrategy defines how cache keys are generated
 	keyStrategy KeyStrategy
+	// inflight coalesces concurrent misses for the same key
+	inflight flightGroup
+	// warmClient fetches responses for Warm
+	warmClient Doer
 }
 
 // NewMiddleware creates a new cache middleware instance with the specified cache and config
@@ -81,14 +96,12 @@ func NewMiddleware(cache Cache, config *Config) *Middleware {
 
 	var keyStrategy KeyStrategy
 	switch config.KeyStrategy {
-	case KeyByURLAndMethod:
-		keyStrategy = NewMethodURLKeyStrategy()
 	case KeyByFullRequest:
 		keyStrategy = NewFullRequestKeyStrategy()
 	case KeyByURLOnly:
-		keyStrategy = NewURLOnlyKeyStrategy()
+		keyStrategy = &URLOnlyKeyStrategy{Hash: config.HashKeys}
 	default:
-		keyStrategy = NewMethodURLKeyStrategy()
+		keyStrategy = &MethodURLKeyStrategy{Hash: config.HashKeys}
 	}
 
 	return &Middleware{
@@ -113,55 +126,49 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 
 		if cachedResp, found := m.cache.Get(ctx, key); found {
 			if m.isFresh(cachedResp, req) {
-				resp := cachedResp.Response
-				resp.Body = io.NopCloser(bytes.NewReader(cachedResp.Body))
-				return resp, nil
+				return cachedResp.newResponse(), nil
 			}
 
 			m.cache.Delete(ctx, key)
 		}
 
+		call, leader := m.inflight.join(key)
+		if !leader {
+			if err := call.wait(ctx); err != nil {
+				return nil, err
+			}
+			if call.cached != nil {
+				return call.cached.newResponse(), nil
+			}
+			return next(ctx, req)
+		}
+		storing := false
+		defer func() {
+			if !storing {
+				m.inflight.finish(key, call)
+			}
+		}()
+
 		resp, err := next(ctx, req)
 		if err != nil || resp == nil {
 			return resp, err
 		}
 
 		if m.isCacheable(resp) {
-			bodyBytes, err := io.ReadAll(resp.Body)
+			cachedResp, err := m.newCachedResponse(req, resp)
 			if err != nil {
 				return resp, nil
 			}
 
-			resp.Body.Close()
-			resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-
-			expiresAt := calculateExpiration(resp, m.config.DefaultTTL)
-
-			respCopy := &http.Response{
-				Status:           resp.Status,
-				StatusCode:       resp.StatusCode,
-				Proto:            resp.Proto,
-				ProtoMajor:       resp.ProtoMajor,
-				ProtoMinor:       resp.ProtoMinor,
-				Header:           resp.Header.Clone(),
-				ContentLength:    resp.ContentLength,
-				TransferEncoding: resp.TransferEncoding,
-				Close:            resp.Close,
-				Uncompressed:     resp.Uncompressed,
-				Trailer:          resp.Trailer.Clone(),
-			}
-
-			cachedResp := &CachedResponse{
-				Response:     respCopy,
-				Body:         bodyBytes,
-				RequestURL:   req.URL.String(),
-				LastAccessed: time.Now(),
-				CreatedAt:    time.Now(),
-				ExpiresAt:    expiresAt,
-			}
+			call.cached = cachedResp
+			call.release()
+			storing = true
 
+			// The call stays in the group until the entry is stored, so misses that
+			// arrive meanwhile are served from it instead of reaching the origin
 			go func() {
 				m.cache.Set(context.Background(), key, cachedResp)
+				m.inflight.forget(key, call)
 			}()
 		}
 
@@ -169,6 +176,43 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 	}
 }
 
+// newCachedResponse reads the response body into a cache entry and replaces the body so
+// the response can still be returned to the caller
+func (m *Middleware) newCachedResponse(req *http.Request, resp *http.Response) (*CachedResponse, error) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	expiresAt := clampExpiration(calculateExpiration(resp, m.config), m.config)
+
+	respCopy := &http.Response{
+		Status:           resp.Status,
+		StatusCode:       resp.StatusCode,
+		Proto:            resp.Proto,
+		ProtoMajor:       resp.ProtoMajor,
+		ProtoMinor:       resp.ProtoMinor,
+		Header:           resp.Header.Clone(),
+		ContentLength:    resp.ContentLength,
+		TransferEncoding: resp.TransferEncoding,
+		Close:            resp.Close,
+		Uncompressed:     resp.Uncompressed,
+		Trailer:          resp.Trailer.Clone(),
+	}
+
+	return &CachedResponse{
+		Response:     respCopy,
+		Body:         bodyBytes,
+		RequestURL:   req.URL.String(),
+		LastAccessed: time.Now(),
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
 // KeyStrategy defines how cache keys are generated from HTTP requests
 type KeyStrategy interface {
 	GenerateKey(req *http.Request) string
@@ -187,7 +231,10 @@ const (
 )
 
 // MethodURLKeyStrategy generates keys based on HTTP method + URL
-type MethodURLKeyStrategy struct{}
+type MethodURLKeyStrategy struct {
+	// Hash replaces the key with its SHA-256 digest, bounding its length for long URLs
+	Hash bool
+}
 
 // NewMethodURLKeyStrategy creates a new MethodURLKeyStrategy
 func NewMethodURLKeyStrategy() *MethodURLKeyStrategy {
@@ -195,20 +242,42 @@ func NewMethodURLKeyStrategy() *MethodURLKeyStrategy {
 }
 
 func (s *MethodURLKeyStrategy) GenerateKey(req *http.Request) string {
+	return digestKey(s.RawKey(req), s.Hash)
+}
+
+// RawKey returns the unhashed key for req, for debugging
+func (s *MethodURLKeyStrategy) RawKey(req *http.Request) string {
 	return req.Method + ":" + req.URL.String()
 }
 
 // URLOnlyKeyStrategy generates keys based only on URL
-type URLOnlyKeyStrategy struct{}
+type URLOnlyKeyStrategy struct {
+	// Hash replaces the key with its SHA-256 digest, bounding its length for long URLs
+	Hash bool
+}
 
 func NewURLOnlyKeyStrategy() *URLOnlyKeyStrategy {
 	return &URLOnlyKeyStrategy{}
 }
 
 func (s *URLOnlyKeyStrategy) GenerateKey(req *http.Request) string {
+	return digestKey(s.RawKey(req), s.Hash)
+}
+
+// RawKey returns the unhashed key for req, for debugging
+func (s *URLOnlyKeyStrategy) RawKey(req *http.Request) string {
 	return req.URL.String()
 }
 
+// digestKey returns the hex-encoded SHA-256 digest of key if enabled, and key otherwise
+func digestKey(key string, enabled bool) string {
+	if !enabled {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
 // FullRequestKeyStrategy generates keys based on method, URL, headers, and body
 type FullRequestKeyStrategy struct{}
 
@@ -304,7 +373,11 @@ func (m *Middleware) isFresh(cachedResp *CachedResponse, req *http.Request) bool
 }
 
 func (m *Middleware) isCacheable(resp *http.Response) bool {
-	if !CacheableStatus[resp.StatusCode] {
+	cacheable := CacheableStatus
+	if m.config.CacheableStatusCodes != nil {
+		cacheable = m.config.CacheableStatusCodes
+	}
+	if !cacheable[resp.StatusCode] {
 		return false
 	}
 
@@ -323,7 +396,14 @@ func (m *Middleware) isCacheable(resp *http.Response) bool {
 	return true
 }
 
-func calculateExpiration(resp *http.Response, defaultTTL time.Duration) time.Time {
+// heuristicFraction is the fraction of the time since last modification used as the
+// heuristic time-to-live, as suggested by RFC 9111
+const heuristicFraction = 10
+
+// calculateExpiration determines when a response expires from its max-age or Expires
+// header, falling back to the TTL configured for its status code, heuristic freshness
+// when enabled and then to the default TTL
+func calculateExpiration(resp *http.Response, config *Config) time.Time {
 	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "" {
 		directives := strings.Split(cacheControl, ",")
 		for _, directive := range directives {
@@ -343,5 +423,47 @@ func calculateExpiration(resp *http.Response, defaultTTL time.Duration) time.Tim
 		}
 	}
 
-	return time.Now().Add(defaultTTL)
+	if ttl, ok := config.StatusTTL[resp.StatusCode]; ok {
+		return time.Now().Add(ttl)
+	}
+
+	if config.HeuristicFreshness {
+		if ttl, ok := heuristicTTL(resp); ok {
+			return time.Now().Add(ttl)
+		}
+	}
+
+	return time.Now().Add(config.DefaultTTL)
+}
+
+// heuristicTTL computes a time-to-live of 10% of the time between Last-Modified and the
+// response Date, or now if the response has no Date header
+func heuristicTTL(resp *http.Response) (time.Duration, bool) {
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return 0, false
+	}
+
+	date := time.Now()
+	if parsed, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		date = parsed
+	}
+
+	age := date.Sub(lastModified)
+	if age <= 0 {
+		return 0, false
+	}
+	return age / heuristicFraction, true
+}
+
+// clampExpiration bounds the expiration time by the configured MinTTL and MaxTTL
+func clampExpiration(expiresAt time.Time, config *Config) time.Time {
+	now := time.Now()
+	if config.MinTTL > 0 && expiresAt.Before(now.Add(config.MinTTL)) {
+		return now.Add(config.MinTTL)
+	}
+	if config.MaxTTL > 0 && expiresAt.After(now.Add(config.MaxTTL)) {
+		return now.Add(config.MaxTTL)
+	}
+	return expiresAt
 }
@@ -10,22 +10,40 @@ type Config struct {
 	Enabled bool
 	// DefaultTTL is the default time-to-live for cached responses
 	DefaultTTL time.Duration
+	// MinTTL is the lower bound applied to the computed time-to-live (0 = no bound)
+	MinTTL time.Duration
+	// MaxTTL is the upper bound applied to the computed time-to-live (0 = no bound)
+	MaxTTL time.Duration
 	// RespectCacheControl determines whether to respect Cache-Control headers
 	RespectCacheControl bool
+	// HeuristicFreshness derives the time-to-live of responses that have a Last-Modified
+	// header but no max-age or Expires from 10% of the time since their last modification
+	HeuristicFreshness bool
 	// IncludePatterns is a list of URL patterns to cache (if empty, all URLs are cached)
 	IncludePatterns []string
 	// ExcludePatterns is a list of URL patterns to exclude from caching
 	ExcludePatterns []string
 	// ExcludeHosts is a list of hosts to exclude from caching
 	ExcludeHosts []string
+	// WarmConcurrency is the maximum number of concurrent fetches made by Warm
+	WarmConcurrency int
 	// CleanupInterval is the interval at which expired cache entries are cleaned up
 	CleanupInterval time.Duration
 	// KeyStrategy defines how cache keys are generated
 	KeyStrategy KeyStrategyType
+	// HashKeys stores entries under the SHA-256 digest of the URL-based keys, keeping key
+	// length constant for long URLs. KeyByFullRequest keys are always hashed.
+	HashKeys bool
 	// DomainTTLRules allows specifying different TTLs for different domains
 	DomainTTLRules map[string]time.Duration
 	// PathTTLRules allows specifying different TTLs for different URL path patterns
 	PathTTLRules map[string]time.Duration
+	// StatusTTL overrides DefaultTTL for responses with the given status codes. Explicit
+	// max-age or Expires headers still take precedence.
+	StatusTTL map[int]time.Duration
+	// CacheableStatusCodes replaces the package-wide CacheableStatus set for this middleware,
+	// e.g. {200: true} to cache only successful responses (if nil, CacheableStatus is used)
+	CacheableStatusCodes map[int]bool
 }
 
 // DefaultConfig returns a default configuration for the cache middleware
@@ -36,11 +54,13 @@ func DefaultConfig() *Config {
 		RespectCacheControl: true,
 		KeyStrategy:         KeyByURLAndMethod,
 		CleanupInterval:     30 * time.Minute,
+		WarmConcurrency:     4,
 		IncludePatterns:     []string{},
 		ExcludePatterns:     []string{},
 		ExcludeHosts:        []string{},
 		DomainTTLRules:      make(map[string]time.Duration),
 		PathTTLRules:        make(map[string]time.Duration),
+		StatusTTL:           make(map[int]time.Duration),
 	}
 }
 
@@ -56,12 +76,31 @@ func (c *Config) WithDefaultTTL(ttl time.Duration) *Config {
 	return c
 }
 
+// WithMinTTL sets the lower bound for the time-to-live of cached responses
+func (c *Config) WithMinTTL(ttl time.Duration) *Config {
+	c.MinTTL = ttl
+	return c
+}
+
+// WithMaxTTL sets the upper bound for the time-to-live of cached responses
+func (c *Config) WithMaxTTL(ttl time.Duration) *Config {
+	c.MaxTTL = ttl
+	return c
+}
+
 // WithRespectCacheControl sets whether to respect Cache-Control headers
 func (c *Config) WithRespectCacheControl(respect bool) *Config {
 	c.RespectCacheControl = respect
 	return c
 }
 
+// WithHeuristicFreshness sets whether to derive the time-to-live from Last-Modified when
+// the response carries no explicit freshness information
+func (c *Config) WithHeuristicFreshness(enabled bool) *Config {
+	c.HeuristicFreshness = enabled
+	return c
+}
+
 // WithIncludePatterns sets URL patterns to include in caching
 func (c *Config) WithIncludePatterns(patterns ...string) *Config {
 	c.IncludePatterns = patterns
@@ -86,6 +125,12 @@ func (c *Config) WithKeyStrategy(strategy KeyStrategyType) *Config {
 	return c
 }
 
+// WithHashKeys sets whether URL-based cache keys are hashed
+func (c *Config) WithHashKeys(enabled bool) *Config {
+	c.HashKeys = enabled
+	return c
+}
+
 // WithDomainTTL sets a specific TTL for a domain
 func (c *Config) WithDomainTTL(domain string, ttl time.Duration) *Config {
 	c.DomainTTLRules[domain] = ttl
@@ -98,6 +143,21 @@ func (c *Config) WithPathTTL(pathPattern string, ttl time.Duration) *Config {
 	return c
 }
 
+// WithStatusTTL sets a specific TTL for responses with the given status code
+func (c *Config) WithStatusTTL(status int, ttl time.Duration) *Config {
+	if c.StatusTTL == nil {
+		c.StatusTTL = make(map[int]time.Duration)
+	}
+	c.StatusTTL[status] = ttl
+	return c
+}
+
+// WithWarmConcurrency sets the maximum number of concurrent fetches made by Warm
+func (c *Config) WithWarmConcurrency(concurrency int) *Config {
+	c.WarmConcurrency = concurrency
+	return c
+}
+
 // WithCleanupInterval sets the interval for cleaning up expired cache entries
 func (c *Config) WithCleanupInterval(interval time.Duration) *Config {
 	c.CleanupInterval = interval
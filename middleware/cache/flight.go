@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// flightCall represents an in-flight origin fetch for a cache key
+type flightCall struct {
+	done   chan struct{}
+	cached *CachedResponse
+}
+
+// flightGroup coalesces concurrent cache misses for the same key so that only one
+// request reaches the origin while the others wait for it to populate the cache
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// join returns the in-flight call for key, and whether the caller is its leader and
+// must perform the fetch
+func (g *flightGroup) join(key string) (*flightCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		return c, false
+	}
+
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	return c, true
+}
+
+// finish publishes the leader's result and releases the waiters
+func (g *flightGroup) finish(key string, c *flightCall) {
+	g.forget(key, c)
+	c.release()
+}
+
+// release publishes the leader's result to current and later waiters while the call
+// stays in the group
+func (c *flightCall) release() {
+	close(c.done)
+}
+
+// forget removes a released call from the group, so the next miss starts a new fetch
+func (g *flightGroup) forget(key string, c *flightCall) {
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+}
+
+// wait blocks until the leader finishes or the context is done
+func (c *flightCall) wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"container/list"
 	"context"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -13,8 +14,12 @@ type MemoryCache struct {
 	data map[string]*list.Element
 	// lruList maintains the LRU order
 	lruList *list.List
-	// capacity is the maximum number of entries
+	// capacity is the maximum number of entries (0 = unlimited)
 	capacity int
+	// maxBytes is the maximum total size of the entries in bytes (0 = unlimited)
+	maxBytes int64
+	// usedBytes is the current total size of the entries in bytes
+	usedBytes int64
 	// mutex protects concurrent access
 	mutex sync.RWMutex
 }
@@ -25,6 +30,8 @@ type cacheEntry struct {
 	key string
 	// response is the cached response
 	response *CachedResponse
+	// size is the accounted size of the entry in bytes
+	size int64
 }
 
 func NewMemoryCache(capacity int) *MemoryCache {
@@ -39,6 +46,42 @@ func NewMemoryCache(capacity int) *MemoryCache {
 	}
 }
 
+// NewMemoryCacheBytes creates a memory cache bounded by the total size of the cached
+// bodies and headers rather than by the number of entries. The least recently used
+// entries are evicted once maxBytes is exceeded.
+func NewMemoryCacheBytes(maxBytes int64) *MemoryCache {
+	if maxBytes <= 0 {
+		maxBytes = 64 * 1024 * 1024
+	}
+
+	return &MemoryCache{
+		data:     make(map[string]*list.Element),
+		lruList:  list.New(),
+		maxBytes: maxBytes,
+	}
+}
+
+// entrySize returns the accounted size of a cached response: its body plus headers
+func entrySize(response *CachedResponse) int64 {
+	size := int64(len(response.Body))
+	if response.Response != nil {
+		for name, values := range response.Response.Header {
+			for _, value := range values {
+				size += int64(len(name) + len(value))
+			}
+		}
+	}
+	return size
+}
+
+// removeElement removes an entry from the cache; the caller must hold the write lock
+func (c *MemoryCache) removeElement(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	delete(c.data, entry.key)
+	c.lruList.Remove(element)
+	c.usedBytes -= entry.size
+}
+
 func (c *MemoryCache) Get(ctx context.Context, key string) (*CachedResponse, bool) {
 	c.mutex.RLock()
 	element, exists := c.data[key]
@@ -51,12 +94,16 @@ func (c *MemoryCache) Get(ctx context.Context, key string) (*CachedResponse, boo
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	// The entry may have been removed or replaced while no lock was held
+	if current, ok := c.data[key]; !ok || current != element {
+		return nil, false
+	}
+
 	c.lruList.MoveToFront(element)
 	entry := element.Value.(*cacheEntry)
 
 	if time.Now().After(entry.response.ExpiresAt) {
-		c.lruList.Remove(element)
-		delete(c.data, key)
+		c.removeElement(element)
 		return nil, false
 	}
 
@@ -68,39 +115,61 @@ func (c *MemoryCache) Set(ctx context.Context, key string, response *CachedRespo
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	size := entrySize(response)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return fmt.Errorf("cache entry of %d bytes exceeds the %d bytes limit", size, c.maxBytes)
+	}
+
 	if element, exists := c.data[key]; exists {
 		c.lruList.MoveToFront(element)
 		entry := element.Value.(*cacheEntry)
 		entry.response = response
+		c.usedBytes += size - entry.size
+		entry.size = size
+		c.evict(element)
 		return nil
 	}
 
-	if c.lruList.Len() >= c.capacity {
-		oldest := c.lruList.Back()
-		if oldest != nil {
-			entry := oldest.Value.(*cacheEntry)
-			delete(c.data, entry.key)
-			c.lruList.Remove(oldest)
+	if c.capacity > 0 && c.lruList.Len() >= c.capacity {
+		if oldest := c.lruList.Back(); oldest != nil {
+			c.removeElement(oldest)
 		}
 	}
 
 	entry := &cacheEntry{
 		key:      key,
 		response: response,
+		size:     size,
 	}
 	element := c.lruList.PushFront(entry)
 	c.data[key] = element
+	c.usedBytes += size
+	c.evict(element)
 
 	return nil
 }
 
+// evict removes least recently used entries, other than keep, until the cache fits
+// within its byte limit; the caller must hold the write lock
+func (c *MemoryCache) evict(keep *list.Element) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.usedBytes > c.maxBytes {
+		oldest := c.lruList.Back()
+		if oldest == nil || oldest == keep {
+			return
+		}
+		c.removeElement(oldest)
+	}
+}
+
 func (c *MemoryCache) Delete(ctx context.Context, key string) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	if element, exists := c.data[key]; exists {
-		c.lruList.Remove(element)
-		delete(c.data, key)
+		c.removeElement(element)
 	}
 
 	return nil
@@ -112,6 +181,7 @@ func (c *MemoryCache) Clear(ctx context.Context) error {
 
 	c.data = make(map[string]*list.Element)
 	c.lruList.Init()
+	c.usedBytes = 0
 
 	return nil
 }
@@ -122,6 +192,7 @@ func (c *MemoryCache) Close() error {
 
 	c.data = nil
 	c.lruList = nil
+	c.usedBytes = 0
 
 	return nil
 }
@@ -133,6 +204,14 @@ func (c *MemoryCache) Size() int {
 	return c.lruList.Len()
 }
 
+// UsedBytes returns the current total size of the cached entries in bytes
+func (c *MemoryCache) UsedBytes() int64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.usedBytes
+}
+
 func (c *MemoryCache) StartCleanupTask(interval time.Duration) {
 	if interval <= 0 {
 		interval = 10 * time.Minute
@@ -159,8 +238,7 @@ func (c *MemoryCache) cleanup() {
 		nextElement := element.Next()
 
 		if now.After(entry.response.ExpiresAt) {
-			delete(c.data, entry.key)
-			c.lruList.Remove(element)
+			c.removeElement(element)
 		}
 
 		element = nextElement
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Doer sends HTTP requests, e.g. *http.Client or *httpio.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// defaultWarmClient fetches responses for Warm when no client is set. Unlike
+// http.DefaultClient it has a timeout, matching httpio.DefaultTimeout, so a stalled
+// origin cannot hold Warm forever when the context has no deadline.
+var defaultWarmClient = &http.Client{Timeout: 30 * time.Second}
+
+// WithWarmClient sets the client used by Warm to fetch responses (default a client with
+// a 30 second timeout)
+func (m *Middleware) WithWarmClient(client Doer) *Middleware {
+	m.warmClient = client
+	return m
+}
+
+// Warm pre-populates the cache by fetching each request and storing the cacheable
+// responses, running at most Config.WarmConcurrency fetches at a time. Requests that
+// are excluded from caching are skipped. The returned error joins all fetch and store
+// failures.
+func (m *Middleware) Warm(ctx context.Context, reqs []*http.Request) error {
+	client := m.warmClient
+	if client == nil {
+		client = defaultWarmClient
+	}
+
+	concurrency := m.config.WarmConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, concurrency)
+	)
+
+	for _, req := range reqs {
+		if !isCacheableMethod(req.Method) || !m.shouldCache(req) {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+
+		wg.Add(1)
+		go func(req *http.Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := m.warm(ctx, client, req); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("warm %s: %w", req.URL, err))
+				mu.Unlock()
+			}
+		}(req)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// warm fetches a single request and stores the response if it is cacheable
+func (m *Middleware) warm(ctx context.Context, client Doer, req *http.Request) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !m.isCacheable(resp) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	cachedResp, err := m.newCachedResponse(req, resp)
+	if err != nil {
+		return err
+	}
+
+	return m.cache.Set(ctx, m.keyStrategy.GenerateKey(req), cachedResp)
+}
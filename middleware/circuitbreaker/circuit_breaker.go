@@ -47,6 +47,25 @@ func (s CircuitBreakerState) String() string {
 	}
 }
 
+// bypassKey is the context key marking requests that skip the circuit breaker
+type bypassKey struct{}
+
+// Bypass returns a context whose requests are let through the circuit breaker regardless
+// of its state, and whose outcome is not recorded.
+//
+// Use this sparingly, e.g. for health checks that must reach the upstream even while the
+// circuit is open. Bypassed requests are sent to a backend the breaker considers unhealthy
+// and therefore add load to it; they also never help the circuit recover.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypassed reports whether the context was marked with Bypass
+func IsBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(bypassKey{}).(bool)
+	return bypassed
+}
+
 // Config holds the configuration for a circuit breaker
 type Config struct {
 	// FailureThreshold is the number of consecutive failures required to trip the circuit
@@ -60,6 +79,9 @@ type Config struct {
 	// ErrorPredicate is used to determine if a response should count as a failure
 	// Default: returns true for any non-nil error or any status code >= 500
 	ErrorPredicate func(resp *http.Response, err error) bool
+	// PerHost keeps a separate circuit breaker for each request host, so that one failing
+	// upstream does not reject requests to the others
+	PerHost bool
 }
 
 // DefaultConfig returns a Config with sensible default values
@@ -92,6 +114,25 @@ type CircuitBreaker struct {
 	lastAttempt       time.Time
 	halfOpenCalls     int
 	onStateChange     func(from, to CircuitBreakerState)
+	requests          int64
+	failures          int64
+	rejected          int64
+}
+
+// Stats is a snapshot of the state and counters of a circuit breaker
+type Stats struct {
+	// State is the current state of the circuit
+	State CircuitBreakerState
+	// ConsecutiveErrors is the current run of failures
+	ConsecutiveErrors int
+	// Requests is the number of requests let through
+	Requests int64
+	// Failures is the number of requests that counted as failures
+	Failures int64
+	// Rejected is the number of requests rejected without being sent
+	Rejected int64
+	// LastAttempt is when the outcome of a request was last recorded
+	LastAttempt time.Time
 }
 
 // transitionState changes the circuit breaker state and triggers the state change notification
@@ -122,6 +163,20 @@ func (cb *CircuitBreaker) GetConsecutiveErrors() int {
 	return cb.consecutiveErrors
 }
 
+// Stats returns a consistent snapshot of the state and counters of the circuit breaker
+func (cb *CircuitBreaker) Stats() Stats {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return Stats{
+		State:             cb.state,
+		ConsecutiveErrors: cb.consecutiveErrors,
+		Requests:          cb.requests,
+		Failures:          cb.failures,
+		Rejected:          cb.rejected,
+		LastAttempt:       cb.lastAttempt,
+	}
+}
+
 // Reset resets the circuit breaker to closed state
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
@@ -156,13 +211,52 @@ func (cb *CircuitBreaker) String() string {
 // Middleware wraps the circuit breaker as an httpio middleware
 type Middleware struct {
 	cb *CircuitBreaker
+
+	mu    sync.Mutex
+	hosts map[string]*CircuitBreaker
 }
 
 // NewMiddleware creates a new circuit breaker middleware with the given configuration
 func New(config *Config) *Middleware {
-	return &Middleware{
-		cb: NewCircuitBreaker(config),
+	cb := NewCircuitBreaker(config)
+	m := &Middleware{cb: cb}
+	if cb.config.PerHost {
+		m.hosts = make(map[string]*CircuitBreaker)
 	}
+	return m
+}
+
+// breakerFor returns the circuit breaker guarding requests to the host of req
+func (m *Middleware) breakerFor(req *http.Request) *CircuitBreaker {
+	if m.hosts == nil {
+		return m.cb
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cb, ok := m.hosts[req.URL.Host]
+	if !ok {
+		cb = NewCircuitBreaker(m.cb.config)
+		m.hosts[req.URL.Host] = cb
+	}
+	return cb
+}
+
+// AllStats returns the stats of every circuit breaker keyed by host, e.g. for an admin
+// endpoint showing which upstreams are tripped. Without PerHost, the single breaker is
+// reported under the empty host.
+func (m *Middleware) AllStats() map[string]Stats {
+	if m.hosts == nil {
+		return map[string]Stats{"": m.cb.Stats()}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[string]Stats, len(m.hosts))
+	for host, cb := range m.hosts {
+		stats[host] = cb.Stats()
+	}
+	return stats
 }
 
 // NewCircuitBreaker creates a new circuit breaker with the given configuration
@@ -196,79 +290,93 @@ func NewCircuitBreaker(config *Config) *CircuitBreaker {
 // Handle implements the MiddlewareHandler interface
 func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
-		modifiedReq, err := m.processRequest(ctx, req)
+		if IsBypassed(ctx) {
+			return next(ctx, req)
+		}
+
+		cb := m.breakerFor(req)
+		modifiedReq, err := m.processRequest(cb, req)
 		if err != nil {
 			return nil, err
 		}
 
-		return m.processResponse(next(ctx, modifiedReq))
+		resp, err := next(ctx, modifiedReq)
+		return m.processResponse(cb, resp, err)
 	}
 }
 
 // ProcessRequest checks if the request can proceed based on circuit breaker state
-func (m *Middleware) processRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
-	m.cb.mu.RLock()
-	state := m.cb.state
-	m.cb.mu.RUnlock()
+func (m *Middleware) processRequest(cb *CircuitBreaker, req *http.Request) (*http.Request, error) {
+	cb.mu.RLock()
+	state := cb.state
+	cb.mu.RUnlock()
 
 	switch state {
 	case StateOpen:
-		if time.Since(m.cb.lastAttempt) > m.cb.config.RecoveryTimeout {
-			m.cb.mu.Lock()
-			if m.cb.state == StateOpen {
-				m.cb.transitionState(StateHalfOpen)
-				m.cb.halfOpenCalls = 0
+		if time.Since(cb.lastAttempt) > cb.config.RecoveryTimeout {
+			cb.mu.Lock()
+			if cb.state == StateOpen {
+				cb.transitionState(StateHalfOpen)
+				cb.halfOpenCalls = 0
 			}
-			m.cb.mu.Unlock()
+			cb.mu.Unlock()
 		} else {
+			cb.mu.Lock()
+			cb.rejected++
+			cb.mu.Unlock()
 			return req, errors.New("circuit breaker is open - request rejected")
 		}
 
 	case StateHalfOpen:
-		m.cb.mu.Lock()
-		defer m.cb.mu.Unlock()
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
 
-		if m.cb.halfOpenCalls >= m.cb.config.HalfOpenMaxCalls {
+		if cb.halfOpenCalls >= cb.config.HalfOpenMaxCalls {
+			cb.rejected++
 			return req, errors.New("circuit breaker is half-open and maximum test requests reached")
 		}
-		m.cb.halfOpenCalls++
+		cb.halfOpenCalls++
 	}
 
 	return req, nil
 }
 
 // ProcessResponse records the success or failure of a request
-func (m *Middleware) processResponse(resp *http.Response, err error) (*http.Response, error) {
-	m.cb.mu.Lock()
-	defer m.cb.mu.Unlock()
+func (m *Middleware) processResponse(cb *CircuitBreaker, resp *http.Response, err error) (*http.Response, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
 
-	predicate := m.cb.config.ErrorPredicate
+	predicate := cb.config.ErrorPredicate
 	if predicate == nil {
 		predicate = defaultErrorPredicate
 	}
 
 	isFailure := predicate(resp, err)
-	m.cb.lastAttempt = time.Now()
+	cb.lastAttempt = time.Now()
+	cb.requests++
+	if isFailure {
+		cb.failures++
+	}
 
-	switch m.cb.state {
+	switch cb.state {
 	case StateClosed:
 		if isFailure {
-			m.cb.consecutiveErrors++
-			if m.cb.consecutiveErrors >= m.cb.config.FailureThreshold {
-				m.cb.transitionState(StateOpen)
+			cb.consecutiveErrors++
+			if cb.consecutiveErrors >= cb.config.FailureThreshold {
+				cb.transitionState(StateOpen)
 			}
 		} else {
-			m.cb.consecutiveErrors = 0
+			cb.consecutiveErrors = 0
 		}
 
 	case StateHalfOpen:
 		if isFailure {
-			m.cb.transitionState(StateOpen)
+			cb.transitionState(StateOpen)
 		} else {
-			m.cb.consecutiveErrors = 0
+			cb.consecutiveErrors = 0
 
-			if m.cb.halfOpenCalls >= m.cb.config.HalfOpenMaxCalls {
-				m.cb.transitionState(StateClosed)
+			if cb.halfOpenCalls >= cb.config.HalfOpenMaxCalls {
+				cb.transitionState(StateClosed)
 			}
 		}
 	}
@@ -276,7 +384,8 @@ func (m *Middleware) processResponse(resp *http.Response, err error) (*http.Resp
 	return resp, err
 }
 
-// GetCircuitBreaker returns the underlying CircuitBreaker for state inspection
+// GetCircuitBreaker returns the underlying CircuitBreaker for state inspection. With
+// PerHost, the per-host breakers are only reported by AllStats.
 func (m *Middleware) GetCircuitBreaker() *CircuitBreaker {
 	return m.cb
 }
@@ -0,0 +1,98 @@
+// Package clockskew provides a middleware that stamps requests with a Date header and
+// detects clock skew between the client and the server.
+//
+// Signed requests and cache freshness calculations both rely on accurate clocks. The
+// middleware sets the Date header of outgoing requests when it is absent and compares
+// the Date header of each response with the local time, reporting the difference to a
+// callback when it exceeds a threshold.
+package clockskew
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// Config represents the configuration for the clock skew middleware
+type Config struct {
+	// SetDate sets the Date header of requests that have none
+	SetDate bool
+	// Threshold is the skew above which OnSkew is called
+	Threshold time.Duration
+	// OnSkew is called with the server time minus the local time when its magnitude
+	// exceeds Threshold
+	OnSkew func(req *http.Request, skew time.Duration)
+	// Now returns the local time (default: time.Now)
+	Now func() time.Time
+}
+
+// DefaultConfig returns a configuration that sets the Date header and tolerates 30s of skew
+func DefaultConfig() *Config {
+	return &Config{
+		SetDate:   true,
+		Threshold: 30 * time.Second,
+	}
+}
+
+// Middleware is the clock skew middleware implementation
+type Middleware struct {
+	config *Config
+}
+
+// New creates a new clock skew middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if m.config.SetDate && req.Header.Get("Date") == "" {
+			// The header map may be shared with the caller, e.g. the Headers of an
+			// httpio.Request, which must not keep this Date when it is sent again
+			req.Header = req.Header.Clone()
+			req.Header.Set("Date", m.config.Now().UTC().Format(http.TimeFormat))
+		}
+
+		sent := m.config.Now()
+		resp, err := next(ctx, req)
+		if err != nil || resp == nil || m.config.OnSkew == nil {
+			return resp, err
+		}
+
+		serverDate, parseErr := http.ParseTime(resp.Header.Get("Date"))
+		if parseErr != nil {
+			return resp, err
+		}
+
+		// The server stamped its Date somewhere between sending and receiving; comparing
+		// with the midpoint keeps slow round trips from being mistaken for skew.
+		received := m.config.Now()
+		local := sent.Add(received.Sub(sent) / 2)
+		skew := serverDate.Sub(local)
+		// Date has a one second resolution
+		if abs(skew) > m.config.Threshold+time.Second {
+			m.config.OnSkew(req, skew.Round(time.Second))
+		}
+
+		return resp, err
+	}
+}
+
+// abs returns the absolute value of d
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,177 @@
+// Package coalesce provides a middleware that debounces identical GET requests.
+//
+// Interactive clients often fire the same request several times in quick succession.
+// Within a configurable window, the middleware lets only the first of a set of identical
+// GETs reach the origin and hands every caller its own copy of the same result, whether
+// the original request is still in flight or completed moments ago.
+//
+// Unlike the cache middleware, coalescing is purely time based: it ignores
+// Cache-Control and applies to any response, including error statuses. Only responses
+// are shared, though: when the first request fails, e.g. because its context was
+// canceled, the callers waiting on it send their own requests. Streaming responses
+// (Server-Sent Events and NDJSON) are not buffered or shared either.
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// Config represents the configuration for the coalescing middleware
+type Config struct {
+	// Window is how long a completed result keeps being shared with identical requests
+	Window time.Duration
+	// KeyFunc identifies identical requests (default: method and URL)
+	KeyFunc func(req *http.Request) string
+	// StreamingContentTypes are the media types of responses that are passed through
+	// to the first caller instead of being buffered and shared (default: Server-Sent
+	// Events and NDJSON)
+	StreamingContentTypes []string
+}
+
+// defaultStreamingContentTypes are the streaming media types used when none are configured
+var defaultStreamingContentTypes = []string{"text/event-stream", "application/x-ndjson"}
+
+// DefaultConfig returns a configuration with a 100ms window
+func DefaultConfig() *Config {
+	return &Config{
+		Window:                100 * time.Millisecond,
+		StreamingContentTypes: defaultStreamingContentTypes,
+	}
+}
+
+// call is a shared request outcome. resp is nil once done if there is nothing to share.
+type call struct {
+	done     chan struct{}
+	resp     *http.Response
+	body     []byte
+	finished time.Time
+}
+
+// Middleware is the coalescing middleware implementation
+type Middleware struct {
+	config *Config
+	mu     sync.Mutex
+	calls  map[string]*call
+}
+
+// New creates a new coalescing middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = defaultKey
+	}
+	if config.StreamingContentTypes == nil {
+		config.StreamingContentTypes = defaultStreamingContentTypes
+	}
+	return &Middleware{
+		config: config,
+		calls:  make(map[string]*call),
+	}
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return next(ctx, req)
+		}
+
+		key := m.config.KeyFunc(req)
+		c, leader := m.join(key)
+		if leader {
+			return m.run(ctx, req, next, key, c)
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if c.resp == nil {
+			return next(ctx, req)
+		}
+		return c.response(req), nil
+	}
+}
+
+// join returns the shared call for key and whether the caller must perform it
+func (m *Middleware) join(key string) (*call, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, c := range m.calls {
+		if !c.finished.IsZero() && now.Sub(c.finished) > m.config.Window {
+			delete(m.calls, k)
+		}
+	}
+
+	if c, ok := m.calls[key]; ok {
+		return c, false
+	}
+
+	c := &call{done: make(chan struct{})}
+	m.calls[key] = c
+	return c, true
+}
+
+// run performs the request for the first caller and publishes its buffered response to
+// the waiters. Failures, which may come from the first caller's own context, and
+// streaming responses are returned to the first caller only.
+func (m *Middleware) run(ctx context.Context, req *http.Request, next middleware.Handler, key string, c *call) (*http.Response, error) {
+	resp, err := next(ctx, req)
+	shared := err == nil && resp != nil && !m.isStreaming(resp)
+	if shared {
+		c.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp, shared = nil, false
+		}
+	}
+
+	m.mu.Lock()
+	if shared {
+		c.resp = resp
+		c.finished = time.Now()
+	} else {
+		delete(m.calls, key)
+	}
+	m.mu.Unlock()
+	close(c.done)
+
+	if !shared {
+		return resp, err
+	}
+	return c.response(req), nil
+}
+
+// isStreaming reports whether the response has one of the configured streaming content types
+func (m *Middleware) isStreaming(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return slices.Contains(m.config.StreamingContentTypes, mediaType)
+}
+
+// response returns a private copy of the shared response
+func (c *call) response(req *http.Request) *http.Response {
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	resp.Request = req
+	return &resp
+}
+
+// defaultKey identifies requests by method and URL
+func defaultKey(req *http.Request) string {
+	return req.Method + ":" + req.URL.String()
+}
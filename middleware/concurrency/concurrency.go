@@ -0,0 +1,203 @@
+// Package concurrency provides a middleware that limits the number of requests in flight.
+//
+// With a static limit, requests beyond the limit wait for a slot or until their context
+// is done. In adaptive mode the limit tunes itself AIMD-style, like TCP congestion
+// control: it grows by about one slot per limit's worth of healthy responses and is cut
+// multiplicatively when a request fails or its latency spikes, probing for the highest
+// concurrency the upstream sustains without degrading.
+package concurrency
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// Config represents the configuration for the concurrency middleware
+type Config struct {
+	// Limit is the maximum number of requests in flight, and the initial limit in adaptive mode
+	Limit int
+	// Adaptive adjusts the limit based on the observed latency and error rate
+	Adaptive bool
+	// MinLimit is the lowest adaptive limit (default: 1)
+	MinLimit int
+	// MaxLimit is the highest adaptive limit (default: 1000)
+	MaxLimit int
+	// Backoff is the factor the adaptive limit is multiplied by on a failure or latency
+	// spike (default: 0.5)
+	Backoff float64
+	// LatencyTolerance is how many times the typical latency a request may take before
+	// counting as a latency spike (default: 2)
+	LatencyTolerance float64
+	// IsFailure reports whether an outcome should shrink the adaptive limit (default:
+	// errors, 429 and 5xx responses)
+	IsFailure func(resp *http.Response, err error) bool
+}
+
+// DefaultConfig returns a configuration with a static limit of 10 requests
+func DefaultConfig() *Config {
+	return &Config{
+		Limit:            10,
+		MinLimit:         1,
+		MaxLimit:         1000,
+		Backoff:          0.5,
+		LatencyTolerance: 2,
+	}
+}
+
+// baselineWeight is the weight of each successful latency in the moving average the
+// baseline is tracked as, so that the baseline follows the upstream over time and a
+// single outlier barely moves it
+const baselineWeight = 0.1
+
+// Middleware is the concurrency limiting middleware implementation
+type Middleware struct {
+	config *Config
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{}
+	// baseline is the typical latency of successful requests
+	baseline float64
+}
+
+// New creates a new concurrency middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Limit <= 0 {
+		config.Limit = 10
+	}
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = 1000
+	}
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = 0.5
+	}
+	if config.LatencyTolerance <= 1 {
+		config.LatencyTolerance = 2
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = defaultIsFailure
+	}
+	return &Middleware{
+		config: config,
+		limit:  float64(config.Limit),
+	}
+}
+
+// Limit returns the current limit on requests in flight
+func (m *Middleware) Limit() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int(m.limit)
+}
+
+// InFlight returns the number of requests currently in flight
+func (m *Middleware) InFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inFlight
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if err := m.acquire(ctx); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := next(ctx, req)
+		m.release(time.Since(start), m.config.IsFailure(resp, err))
+
+		return resp, err
+	}
+}
+
+// acquire waits for a slot or until ctx is done
+func (m *Middleware) acquire(ctx context.Context) error {
+	m.mu.Lock()
+	if m.inFlight < int(m.limit) {
+		m.inFlight++
+		m.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	m.waiters = append(m.waiters, ready)
+	m.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, waiter := range m.waiters {
+			if waiter == ready {
+				m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was handed over concurrently with the cancellation
+		m.inFlight--
+		m.wake()
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a completed request and adapts the limit to its outcome
+func (m *Middleware) release(latency time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight--
+	if m.config.Adaptive {
+		m.adapt(latency, failed)
+	}
+	m.wake()
+}
+
+// adapt applies additive increase or multiplicative decrease to the limit
+func (m *Middleware) adapt(latency time.Duration, failed bool) {
+	spike := m.baseline > 0 && float64(latency) > m.baseline*m.config.LatencyTolerance
+	if !failed {
+		if m.baseline == 0 {
+			m.baseline = float64(latency)
+		} else {
+			m.baseline += (float64(latency) - m.baseline) * baselineWeight
+		}
+	}
+
+	if failed || spike {
+		m.limit = math.Max(float64(m.config.MinLimit), m.limit*m.config.Backoff)
+	} else {
+		m.limit = math.Min(float64(m.config.MaxLimit), m.limit+1/m.limit)
+	}
+}
+
+// wake hands free slots to waiting requests in arrival order
+func (m *Middleware) wake() {
+	for len(m.waiters) > 0 && m.inFlight < int(m.limit) {
+		m.inFlight++
+		close(m.waiters[0])
+		m.waiters = m.waiters[1:]
+	}
+}
+
+// defaultIsFailure treats errors, 429 and 5xx responses as failures
+func defaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil || resp == nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
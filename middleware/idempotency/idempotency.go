@@ -0,0 +1,99 @@
+// Package idempotency provides a middleware that attaches idempotency keys to
+// non-idempotent requests for httpio.
+//
+// Servers supporting idempotency keys (e.g. the Idempotency-Key header) use them to
+// deduplicate requests, which makes it safe to retry POST and PATCH requests. The key
+// is generated once per logical request and written to the request headers, so every
+// retry attempt of that request carries the same key, whether this middleware is
+// placed before or after the retry middleware.
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/anggasct/httpio/middleware"
+	"github.com/google/uuid"
+)
+
+// keyContextKey is the context key for application-provided idempotency keys
+type keyContextKey struct{}
+
+// Config defines the configuration for the idempotency middleware.
+type Config struct {
+	// HeaderName is the header carrying the idempotency key.
+	HeaderName string
+	// Methods are the HTTP methods that receive an idempotency key.
+	Methods []string
+	// KeyGenerator creates a new idempotency key.
+	KeyGenerator func() string
+}
+
+// DefaultConfig returns a configuration with sensible defaults.
+func DefaultConfig() *Config {
+	return &Config{
+		HeaderName:   "Idempotency-Key",
+		Methods:      []string{http.MethodPost, http.MethodPatch},
+		KeyGenerator: uuid.NewString,
+	}
+}
+
+// Middleware implements idempotency key injection
+type Middleware struct {
+	config *Config
+}
+
+// New creates a new idempotency middleware with the provided configuration.
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "Idempotency-Key"
+	}
+	if config.Methods == nil {
+		config.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if config.KeyGenerator == nil {
+		config.KeyGenerator = uuid.NewString
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// WithKey returns a context carrying an application-provided idempotency key, used
+// instead of a generated one for requests made with that context
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the idempotency key stored with WithKey, if any
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// Handle implements the MiddlewareHandler interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if !slices.Contains(m.config.Methods, req.Method) || req.Header.Get(m.config.HeaderName) != "" {
+			return next(ctx, req)
+		}
+
+		key, ok := KeyFromContext(ctx)
+		if !ok {
+			key = m.config.KeyGenerator()
+		}
+
+		// The header is set on the request in place so that clones made by the retry
+		// middleware for subsequent attempts carry the same key. The header map is copied
+		// first, since it may be shared with the caller, e.g. the Headers of an
+		// httpio.Request that is sent again as a new logical request.
+		req.Header = req.Header.Clone()
+		req.Header.Set(m.config.HeaderName, key)
+
+		return next(ctx, req)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 
@@ -78,6 +77,8 @@ type Logger interface {
 type StandardLogger struct {
 	Level  LogLevel
 	Format OutputFormat
+	// Schema names the timestamp, level, message and request ID fields of JSON output
+	Schema FieldSchema
 }
 
 // Log implements the Logger interface
@@ -88,9 +89,9 @@ func (l *StandardLogger) Log(ctx context.Context, level LogLevel, msg string, fi
 
 	if l.Format == FormatJSON {
 		data := map[string]interface{}{
-			"timestamp": time.Now().Format(time.RFC3339),
-			"level":     level.String(),
-			"message":   msg,
+			l.Schema.key("timestamp"): time.Now().Format(time.RFC3339),
+			l.Schema.key("level"):     level.String(),
+			l.Schema.key("message"):   msg,
 		}
 		// Add all fields to the JSON output
 		for k, v := range fields {
@@ -99,7 +100,7 @@ func (l *StandardLogger) Log(ctx context.Context, level LogLevel, msg string, fi
 
 		// Add request ID if available
 		if reqID, ok := ctx.Value(RequestIDKey).(string); ok {
-			data["request_id"] = reqID
+			data[l.Schema.key("request_id")] = reqID
 		}
 
 		jsonData, _ := json.Marshal(data)
@@ -134,20 +135,38 @@ type Config struct {
 	Format OutputFormat
 	// RequestIDGenerator creates unique request identifiers
 	RequestIDGenerator func() string
-	// RequestIDHeader is the header name for propagating request IDs
+	// RequestIDFromContext extracts an application-provided request ID (e.g. a trace ID)
+	// from the request context, preferred over generating a new one
+	RequestIDFromContext func(ctx context.Context) (string, bool)
+	// RequestIDHeader is the header an existing request ID is read from, and written to
+	// when propagating unless PropagationHeader is set
 	RequestIDHeader string
+	// PropagationHeader is the header the request ID is sent upstream in when
+	// PropagateRequestID is enabled (default: RequestIDHeader). An ID already present
+	// in this header is never overwritten.
+	PropagationHeader string
 	// SensitiveHeaders are headers that should be redacted
 	SensitiveHeaders []string
 	// SkipPaths are URL paths that should not be logged
 	SkipPaths []string
-	// SensitiveFields are JSON fields that should be redacted in bodies
+	// SensitiveFields are JSON fields that should be redacted in bodies.
+	// Leave nil for the defaults; an empty, non-nil slice disables body redaction.
+	// Field names are matched literally and case-insensitively, as substrings of the
+	// JSON key unless ExactFieldMatch is set.
 	SensitiveFields []string
+	// ExactFieldMatch requires JSON keys to equal a sensitive field name (ignoring case)
+	// instead of merely containing it
+	ExactFieldMatch bool
 	// EnableSampling enables log sampling to reduce volume
 	EnableSampling bool
 	// SampleRate defines the log sampling rate (1.0 = 100%)
 	SampleRate float64
-	// PropagateRequestID controls whether to propagate request ID
+	// PropagateRequestID controls whether the request ID is sent upstream. When disabled
+	// the ID is still generated and logged, but never added to the outgoing request.
 	PropagateRequestID bool
+	// FieldSchema selects the names of the logged fields (default: SchemaDefault). The
+	// default logger names its own fields accordingly.
+	FieldSchema FieldSchema
 }
 
 // DefaultConfig returns a default configuration
@@ -169,7 +188,8 @@ func DefaultConfig() *Config {
 
 // Middleware implements HTTP client logging
 type Middleware struct {
-	config *Config
+	config          *Config
+	sensitiveFields []string
 }
 
 // New creates a new logger middleware
@@ -188,25 +208,40 @@ func New(config *Config) *Middleware {
 		if config.RequestIDGenerator != nil {
 			cfg.RequestIDGenerator = config.RequestIDGenerator
 		}
+		if config.RequestIDFromContext != nil {
+			cfg.RequestIDFromContext = config.RequestIDFromContext
+		}
 		if config.RequestIDHeader != "" {
 			cfg.RequestIDHeader = config.RequestIDHeader
 		}
+		cfg.PropagationHeader = config.PropagationHeader
 		if len(config.SensitiveHeaders) > 0 {
 			cfg.SensitiveHeaders = config.SensitiveHeaders
 		}
 		if len(config.SkipPaths) > 0 {
 			cfg.SkipPaths = config.SkipPaths
 		}
-		if len(config.SensitiveFields) > 0 {
+		if config.SensitiveFields != nil {
 			cfg.SensitiveFields = config.SensitiveFields
 		}
 		if config.EnableSampling {
 			cfg.EnableSampling = config.EnableSampling
 			cfg.SampleRate = config.SampleRate
 		}
+		cfg.ExactFieldMatch = config.ExactFieldMatch
 		cfg.PropagateRequestID = config.PropagateRequestID
+		cfg.FieldSchema = config.FieldSchema
+		if standard, ok := cfg.Logger.(*StandardLogger); ok && config.Logger == nil {
+			standard.Schema = cfg.FieldSchema
+		}
 	}
-	return &Middleware{config: cfg}
+
+	sensitiveFields := make([]string, 0, len(cfg.SensitiveFields))
+	for _, field := range cfg.SensitiveFields {
+		sensitiveFields = append(sensitiveFields, strings.ToLower(field))
+	}
+
+	return &Middleware{config: cfg, sensitiveFields: sensitiveFields}
 }
 
 // WithLevel returns a middleware with the specified log level
@@ -250,7 +285,7 @@ func truncateBody(body []byte) []byte {
 
 // redactJSONFields redacts sensitive fields in JSON bodies
 func (m *Middleware) redactJSONFields(body []byte) []byte {
-	if len(body) == 0 {
+	if len(body) == 0 || len(m.sensitiveFields) == 0 {
 		return body
 	}
 
@@ -272,14 +307,7 @@ func (m *Middleware) redactJSONFields(body []byte) []byte {
 		case map[string]interface{}:
 			result := make(map[string]interface{})
 			for k, v := range val {
-				isSensitive := false
-				for _, field := range m.config.SensitiveFields {
-					if regexp.MustCompile(fmt.Sprintf("(?i)%s", field)).MatchString(k) {
-						isSensitive = true
-						break
-					}
-				}
-				if isSensitive {
+				if m.isSensitiveField(k) {
 					result[k] = "[REDACTED]"
 				} else {
 					result[k] = redact(v)
@@ -306,6 +334,21 @@ func (m *Middleware) redactJSONFields(body []byte) []byte {
 	return redactedJSON
 }
 
+// isSensitiveField reports whether a JSON key matches one of the sensitive field names
+func (m *Middleware) isSensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range m.sensitiveFields {
+		if m.config.ExactFieldMatch {
+			if key == field {
+				return true
+			}
+		} else if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
 // shouldSkipLogging determines if the request should be logged
 func (m *Middleware) shouldSkipLogging(path string) bool {
 	for _, p := range m.config.SkipPaths {
@@ -316,6 +359,17 @@ func (m *Middleware) shouldSkipLogging(path string) bool {
 	return false
 }
 
+// requestIDFor returns the application-provided request ID from the context if available,
+// otherwise a newly generated one
+func (m *Middleware) requestIDFor(ctx context.Context) string {
+	if m.config.RequestIDFromContext != nil {
+		if id, ok := m.config.RequestIDFromContext(ctx); ok && id != "" {
+			return id
+		}
+	}
+	return m.config.RequestIDGenerator()
+}
+
 // GetRequestID retrieves the request ID from context
 func GetRequestID(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(RequestIDKey).(string)
@@ -338,13 +392,17 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 		}
 
 		// Generate or retrieve request ID
-		requestID := ""
-		if existingID := req.Header.Get(m.config.RequestIDHeader); existingID != "" {
-			requestID = existingID
-		} else {
-			requestID = m.config.RequestIDGenerator()
-			if m.config.PropagateRequestID {
-				req.Header.Set(m.config.RequestIDHeader, requestID)
+		requestID := req.Header.Get(m.config.RequestIDHeader)
+		if requestID == "" {
+			requestID = m.requestIDFor(ctx)
+		}
+		if m.config.PropagateRequestID {
+			propagationHeader := m.config.PropagationHeader
+			if propagationHeader == "" {
+				propagationHeader = m.config.RequestIDHeader
+			}
+			if req.Header.Get(propagationHeader) == "" {
+				req.Header.Set(propagationHeader, requestID)
 			}
 		}
 
@@ -371,7 +429,7 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 				fields["body"] = string(truncateBody(bodyBytes))
 			}
 
-			m.config.Logger.Log(ctx, LevelInfo, "Outgoing request", fields)
+			m.config.Logger.Log(ctx, LevelInfo, "Outgoing request", m.config.FieldSchema.apply(fields))
 		}
 
 		start := time.Now()
@@ -392,7 +450,7 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 		fields := map[string]interface{}{
 			"method":   req.Method,
 			"url":      req.URL.String(),
-			"duration": duration.Milliseconds(),
+			"duration": duration,
 		}
 
 		if err != nil {
@@ -425,7 +483,7 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 			logMessage += fmt.Sprintf(" with status: %d", resp.StatusCode)
 		}
 
-		m.config.Logger.Log(ctx, level, logMessage, fields)
+		m.config.Logger.Log(ctx, level, logMessage, m.config.FieldSchema.apply(fields))
 
 		return resp, err
 	}
@@ -0,0 +1,78 @@
+package logger
+
+import "time"
+
+// FieldSchema selects the names of the fields emitted by the middleware, so that logs
+// can be ingested by observability platforms expecting a specific schema
+type FieldSchema int
+
+const (
+	// SchemaDefault uses the middleware's own short field names, such as "method"
+	SchemaDefault FieldSchema = iota
+	// SchemaECS uses Elastic Common Schema field names, such as "http.request.method"
+	SchemaECS
+	// SchemaOTel uses OpenTelemetry semantic convention attribute names
+	SchemaOTel
+)
+
+// schemaFields maps the default field names to those of each schema
+var schemaFields = map[FieldSchema]map[string]string{
+	SchemaECS: {
+		"method":           "http.request.method",
+		"url":              "url.full",
+		"status":           "http.response.status_code",
+		"duration":         "event.duration",
+		"error":            "error.message",
+		"headers":          "http.request.headers",
+		"response_headers": "http.response.headers",
+		"body":             "http.request.body.content",
+		"response_body":    "http.response.body.content",
+		"timestamp":        "@timestamp",
+		"level":            "log.level",
+		"message":          "message",
+		"request_id":       "http.request.id",
+	},
+	SchemaOTel: {
+		"method":           "http.request.method",
+		"url":              "url.full",
+		"status":           "http.response.status_code",
+		"duration":         "http.client.request.duration",
+		"error":            "exception.message",
+		"headers":          "http.request.header",
+		"response_headers": "http.response.header",
+		"body":             "http.request.body",
+		"response_body":    "http.response.body",
+		"timestamp":        "timestamp",
+		"level":            "severity_text",
+		"message":          "body",
+		"request_id":       "request_id",
+	},
+}
+
+// key returns the name of the default field name in the schema
+func (s FieldSchema) key(name string) string {
+	if renamed, ok := schemaFields[s][name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// apply renames fields to the schema and converts the request duration to its unit:
+// milliseconds by default, nanoseconds for ECS and seconds for OTel
+func (s FieldSchema) apply(fields map[string]interface{}) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if duration, ok := value.(time.Duration); ok && name == "duration" {
+			switch s {
+			case SchemaECS:
+				value = duration.Nanoseconds()
+			case SchemaOTel:
+				value = duration.Seconds()
+			default:
+				value = duration.Milliseconds()
+			}
+		}
+		renamed[s.key(name)] = value
+	}
+	return renamed
+}
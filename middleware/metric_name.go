@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// metricNameKey is the context key holding the low-cardinality name of a request
+type metricNameKey struct{}
+
+// WithMetricName returns a context carrying the name that metrics and tracing middleware
+// should use to label the request, such as a route template like /users/:id
+func WithMetricName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, metricNameKey{}, name)
+}
+
+// MetricNameFromContext returns the metric name stored in the context, if any
+func MetricNameFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(metricNameKey{}).(string)
+	return name, ok && name != ""
+}
+
+// MetricName returns the name to label the request with in metrics and spans. It falls
+// back to the raw URL path when no name was set, which may have high cardinality.
+func MetricName(req *http.Request) string {
+	if name, ok := MetricNameFromContext(req.Context()); ok {
+		return name
+	}
+	return req.URL.Path
+}
+
+// metricTagsKey is the context key holding the extra metric labels of a request
+type metricTagsKey struct{}
+
+// WithMetricTags returns a context carrying tags, such as tenant or feature, that metrics
+// and tracing middleware should add as labels or attributes. They are merged with tags
+// already in ctx, the new values taking precedence.
+//
+// Every distinct combination of label values creates a new time series, so tags must
+// only take a small, bounded set of values. Never use IDs, emails or raw paths as tags.
+func WithMetricTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range MetricTagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metricTagsKey{}, merged)
+}
+
+// MetricTagsFromContext returns the metric tags stored in the context, or nil if there are
+// none. The map must not be modified.
+func MetricTagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(metricTagsKey{}).(map[string]string)
+	return tags
+}
+
+// MetricTags returns the tags to add as labels to the metrics and spans of the request
+func MetricTags(req *http.Request) map[string]string {
+	return MetricTagsFromContext(req.Context())
+}
@@ -2,9 +2,14 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 )
 
+// ErrNilResponse is returned when a handler returns neither a response nor an error
+var ErrNilResponse = errors.New("handler returned a nil response and a nil error")
+
 // Handler defines the HTTP handler function signature
 type Handler func(ctx context.Context, req *http.Request) (*http.Response, error)
 
@@ -16,18 +21,44 @@ type Middleware interface {
 
 // Chain applies a series of middleware to a base handler function
 // The middlewares are applied in reverse order, so the first middleware
-// in the list is the outermost wrapper (executes first on the request, last on the response)
+// in the list is the outermost wrapper (executes first on the request, last on the response).
+// A middleware returning (nil, nil) is reported to the middlewares wrapping it as an
+// error matching ErrNilResponse.
 func Chain(base Handler, middlewares ...Middleware) Handler {
 	handler := base
 
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		middleware := middlewares[i]
-		handler = middleware.Handle(handler)
+		handler = guardNilResponse(middleware, middleware.Handle(handler))
 	}
 
 	return handler
 }
 
+// guardNilResponse converts a (nil, nil) outcome of the handler built by m into an error
+func guardNilResponse(m Middleware, handler Handler) Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		resp, err := handler(ctx, req)
+		if resp == nil && err == nil {
+			return nil, fmt.Errorf("%w: %T", ErrNilResponse, m)
+		}
+		return resp, err
+	}
+}
+
+// Compose combines several middlewares into a single reusable middleware.
+// The composed middleware behaves exactly as if the middlewares were added one
+// by one in the given order: the first one is the outermost wrapper.
+func Compose(middlewares ...Middleware) Middleware {
+	stack := make([]Middleware, len(middlewares))
+	copy(stack, middlewares)
+	return &functionMiddleware{
+		fn: func(next Handler) Handler {
+			return Chain(next, stack...)
+		},
+	}
+}
+
 // function-based middleware
 type functionMiddleware struct {
 	fn func(next Handler) Handler
@@ -44,3 +75,17 @@ type MiddlewareFunc func(next Handler) Handler
 func WrapMiddleware(mw MiddlewareFunc) Middleware {
 	return &functionMiddleware{fn: mw}
 }
+
+// ResponseFunc transforms the outcome of a request
+type ResponseFunc func(ctx context.Context, resp *http.Response, err error) (*http.Response, error)
+
+// ResponseOnly creates a middleware that passes requests through unchanged and applies fn
+// to every outcome, e.g. to add response headers or record metrics
+func ResponseOnly(fn ResponseFunc) Middleware {
+	return WrapMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			return fn(ctx, resp, err)
+		}
+	})
+}
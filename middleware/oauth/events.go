@@ -0,0 +1,121 @@
+package oauth
+
+import (
+	"time"
+)
+
+// EventType identifies a token lifecycle event
+type EventType int
+
+const (
+	// EventAcquired is emitted when a first token is obtained
+	EventAcquired EventType = iota
+	// EventRefreshed is emitted when a token replaces a previous one
+	EventRefreshed
+	// EventRefreshFailed is emitted when replacing an existing token fails
+	EventRefreshFailed
+	// EventAcquireFailed is emitted when obtaining a first token fails
+	EventAcquireFailed
+	// EventExpired is emitted once per token when it is found to have expired
+	EventExpired
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventAcquired:
+		return "acquired"
+	case EventRefreshed:
+		return "refreshed"
+	case EventRefreshFailed:
+		return "refresh_failed"
+	case EventAcquireFailed:
+		return "acquire_failed"
+	case EventExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// Event describes a change in the token lifecycle
+type Event struct {
+	// Type is the kind of event
+	Type EventType
+	// Time is when the event occurred
+	Time time.Time
+	// Token is the token concerned, if any
+	Token *TokenResponse
+	// Err is the error of failure events
+	Err error
+}
+
+// subscription is a registered event listener
+type subscription struct {
+	fn func(Event)
+}
+
+// Subscribe registers fn to receive token lifecycle events and returns a function that
+// removes it. Listeners are called synchronously while a token is being acquired, so they
+// must not block or issue requests through the middleware. OnNewToken and OnTokenError
+// keep working alongside subscribers.
+func (m *Middleware) Subscribe(fn func(Event)) (unsubscribe func()) {
+	sub := &subscription{fn: fn}
+
+	m.subMutex.Lock()
+	m.subscribers = append(m.subscribers, sub)
+	m.subMutex.Unlock()
+
+	return func() {
+		m.subMutex.Lock()
+		defer m.subMutex.Unlock()
+		for i, s := range m.subscribers {
+			if s == sub {
+				m.subscribers = append(m.subscribers[:i:i], m.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit delivers an event to the subscribers
+func (m *Middleware) emit(eventType EventType, token *TokenResponse, err error) {
+	m.subMutex.Lock()
+	subscribers := m.subscribers
+	m.subMutex.Unlock()
+
+	event := Event{Type: eventType, Time: time.Now(), Token: token, Err: err}
+	for _, sub := range subscribers {
+		sub.fn(event)
+	}
+}
+
+// tokenObtained records a new token, reporting it to OnNewToken and the subscribers
+func (m *Middleware) tokenObtained(token *TokenResponse) {
+	eventType := EventAcquired
+	if m.currentToken != nil || m.hadToken {
+		eventType = EventRefreshed
+	}
+
+	m.currentToken = token
+	m.tokenExpiresAt = m.expiresAt(token)
+	m.hadToken = true
+	m.expiryReported = false
+
+	if m.config.OnNewToken != nil {
+		m.config.OnNewToken(token)
+	}
+	m.emit(eventType, token, nil)
+}
+
+// tokenFailed reports a failure to obtain a token to OnTokenError and the subscribers
+func (m *Middleware) tokenFailed(err error) {
+	eventType := EventAcquireFailed
+	if m.currentToken != nil || m.hadToken {
+		eventType = EventRefreshFailed
+	}
+
+	if m.config.OnTokenError != nil {
+		m.config.OnTokenError(err)
+	}
+	m.emit(eventType, m.currentToken, err)
+}
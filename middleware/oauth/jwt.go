@@ -0,0 +1,73 @@
+package oauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// jwtClaims holds the registered claims the middleware reads from access tokens
+type jwtClaims struct {
+	Exp   *float64        `json:"exp"`
+	Scope string          `json:"scope"`
+	Scp   json.RawMessage `json:"scp"`
+}
+
+// decodeJWTClaims parses the claims of a JWT without verifying its signature, which is
+// the resource server's job
+func decodeJWTClaims(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oauth middleware: access token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// scopes returns the scopes from the scope claim or, failing that, from the scp claim,
+// which some providers send as a list
+func (c *jwtClaims) scopes() string {
+	if c.Scope != "" || len(c.Scp) == 0 {
+		return c.Scope
+	}
+
+	var list []string
+	if err := json.Unmarshal(c.Scp, &list); err == nil {
+		return strings.Join(list, " ")
+	}
+	var scope string
+	json.Unmarshal(c.Scp, &scope)
+	return scope
+}
+
+// expiresAt computes when token expires. When the token response carries no expires_in
+// and Config.DecodeJWT is set, the exp claim of the access token is used, and the scope
+// claims fill in a missing scope.
+func (m *Middleware) expiresAt(token *TokenResponse) time.Time {
+	if token.ExpiresIn > 0 || !m.config.DecodeJWT {
+		return time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	claims, err := decodeJWTClaims(token.AccessToken)
+	if err != nil {
+		return time.Now()
+	}
+	if token.Scope == "" {
+		token.Scope = claims.scopes()
+	}
+	if claims.Exp == nil {
+		return time.Now()
+	}
+	return time.Unix(int64(*claims.Exp), 0)
+}
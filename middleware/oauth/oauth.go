@@ -5,10 +5,12 @@
 package oauth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -18,6 +20,9 @@ import (
 	"github.com/anggasct/httpio/middleware"
 )
 
+// ErrTokenTimeout is returned when acquiring a token exceeds Config.TokenRequestTimeout
+var ErrTokenTimeout = errors.New("oauth middleware: token request timed out")
+
 // TokenResponse represents an OAuth token response
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -49,8 +54,23 @@ type Config struct {
 	// HeaderFormat is the format for the token header value (default: "Bearer %s")
 	HeaderFormat string
 	// RefreshThreshold is the time before expiration when the token should be refreshed
-	// This prevents using a token that's about to expire
+	// This prevents using a token that's about to expire. If the refresh fails, the current
+	// token keeps being used until it actually expires.
 	RefreshThreshold time.Duration
+	// TokenRequestTimeout bounds the time spent acquiring a token, so that a slow token
+	// server cannot consume the whole deadline of the request being authorized (0 = no limit)
+	TokenRequestTimeout time.Duration
+	// TokenSource, if set, supplies tokens instead of the token endpoint, e.g. an instance
+	// metadata service or a secrets agent. Caching and RefreshThreshold still apply.
+	TokenSource TokenSource
+	// DecodeJWT, if true, derives the expiry of tokens returned without expires_in from the
+	// exp claim of the access token, and their scope from its scope or scp claim. The
+	// signature is not verified.
+	DecodeJWT bool
+	// RetryOn401, if true, makes the middleware acquire a new token and retry the request
+	// once when the server responds with 401 Unauthorized. Request bodies are buffered so
+	// they can be replayed.
+	RetryOn401 bool
 	// OnNewToken is called when a new token is obtained
 	OnNewToken func(token *TokenResponse)
 	// OnTokenError is called when a token acquisition fails
@@ -72,7 +92,12 @@ type Middleware struct {
 	config         *Config
 	currentToken   *TokenResponse
 	tokenExpiresAt time.Time
+	hadToken       bool
+	expiryReported bool
 	mutex          sync.RWMutex
+
+	subscribers []*subscription
+	subMutex    sync.Mutex
 }
 
 // NewMiddleware creates a new OAuth middleware with the provided configuration
@@ -103,6 +128,12 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 			return nil, fmt.Errorf("oauth middleware: failed to get token: %w", err)
 		}
 
+		if m.config.RetryOn401 {
+			if err := bufferBody(req); err != nil {
+				return nil, fmt.Errorf("oauth middleware: failed to buffer request body: %w", err)
+			}
+		}
+
 		req.Header.Set(m.config.HeaderName, fmt.Sprintf(m.config.HeaderFormat, token.AccessToken))
 
 		res, _ := next(ctx, req)
@@ -110,16 +141,55 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 			return nil, errors.New("oauth middleware: next handler returned nil response")
 		}
 
-		if res != nil && res.StatusCode == http.StatusUnauthorized {
-			m.mutex.Lock()
+		if res.StatusCode != http.StatusUnauthorized {
+			return res, nil
+		}
+
+		m.mutex.Lock()
+		if m.currentToken == token {
 			m.currentToken = nil
-			m.mutex.Unlock()
+		}
+		m.mutex.Unlock()
+
+		if !m.config.RetryOn401 {
+			return res, nil
 		}
 
-		return res, nil
+		token, err = m.getValidToken(ctx)
+		if err != nil {
+			return res, nil
+		}
+
+		retryReq := req.Clone(ctx)
+		if req.GetBody != nil {
+			if retryReq.Body, err = req.GetBody(); err != nil {
+				return res, nil
+			}
+		}
+		res.Body.Close()
+
+		retryReq.Header.Set(m.config.HeaderName, fmt.Sprintf(m.config.HeaderFormat, token.AccessToken))
+		return next(ctx, retryReq)
 	}
 }
 
+// bufferBody reads a request body that cannot be replayed into memory and sets GetBody
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
 // getValidToken returns a valid token, obtaining a new one if necessary
 func (m *Middleware) getValidToken(ctx context.Context) (*TokenResponse, error) {
 	m.mutex.RLock()
@@ -136,40 +206,48 @@ func (m *Middleware) getValidToken(ctx context.Context) (*TokenResponse, error)
 	if m.currentToken != nil && time.Now().Add(m.config.RefreshThreshold).Before(m.tokenExpiresAt) {
 		return m.currentToken, nil
 	}
+	if m.currentToken != nil && !m.expiryReported && !time.Now().Before(m.tokenExpiresAt) {
+		m.expiryReported = true
+		m.emit(EventExpired, m.currentToken, nil)
+	}
 
-	if m.currentToken != nil && m.currentToken.RefreshToken != "" {
-		token, err := m.refreshExistingToken(ctx)
-		if err == nil {
-			m.currentToken = token
-			m.tokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
-
-			if m.config.OnNewToken != nil {
-				m.config.OnNewToken(token)
-			}
+	tokenCtx := ctx
+	if m.config.TokenRequestTimeout > 0 {
+		var cancel context.CancelFunc
+		tokenCtx, cancel = context.WithTimeout(ctx, m.config.TokenRequestTimeout)
+		defer cancel()
+	}
 
+	if m.config.TokenSource == nil && m.currentToken != nil && m.currentToken.RefreshToken != "" {
+		token, err := m.refreshExistingToken(tokenCtx)
+		if err == nil {
+			m.tokenObtained(token)
 			return token, nil
 		}
 
-		if m.config.OnTokenError != nil {
-			m.config.OnTokenError(fmt.Errorf("oauth middleware: refresh token failed, falling back to new token: %w", err))
-		}
+		m.tokenFailed(fmt.Errorf("oauth middleware: refresh token failed, falling back to new token: %w", err))
 	}
 
-	token, err := m.fetchNewToken(ctx)
+	var token *TokenResponse
+	var err error
+	if m.config.TokenSource != nil {
+		token, err = m.config.TokenSource.Token(tokenCtx)
+	} else {
+		token, err = m.fetchNewToken(tokenCtx)
+	}
 	if err != nil {
-		if m.config.OnTokenError != nil {
-			m.config.OnTokenError(err)
+		if ctx.Err() == nil && errors.Is(tokenCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %v", ErrTokenTimeout, err)
+		}
+		m.tokenFailed(err)
+		// A failed proactive refresh is not fatal while the current token is still valid
+		if m.currentToken != nil && time.Now().Before(m.tokenExpiresAt) {
+			return m.currentToken, nil
 		}
 		return nil, err
 	}
 
-	m.currentToken = token
-	m.tokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
-
-	if m.config.OnNewToken != nil {
-		m.config.OnNewToken(token)
-	}
-
+	m.tokenObtained(token)
 	return token, nil
 }
 
@@ -0,0 +1,41 @@
+package oauth
+
+import (
+	"context"
+	"math"
+)
+
+// TokenSource supplies access tokens to the middleware in place of the token endpoint
+type TokenSource interface {
+	Token(ctx context.Context) (*TokenResponse, error)
+}
+
+// TokenSourceFunc adapts a function to the TokenSource interface
+type TokenSourceFunc func(ctx context.Context) (*TokenResponse, error)
+
+// Token implements the TokenSource interface
+func (f TokenSourceFunc) Token(ctx context.Context) (*TokenResponse, error) {
+	return f(ctx)
+}
+
+// staticTokenLifetime is the expires_in of static tokens. They do not expire, so it is
+// long enough for the middleware to cache them for good.
+const staticTokenLifetime = math.MaxInt32
+
+// staticTokenSource always returns the same token
+type staticTokenSource struct {
+	token *TokenResponse
+}
+
+// StaticTokenSource returns a TokenSource that always supplies the given access token. The
+// token never expires, so the middleware asks for it only once.
+func StaticTokenSource(accessToken string) TokenSource {
+	return &staticTokenSource{
+		token: &TokenResponse{AccessToken: accessToken, TokenType: "Bearer", ExpiresIn: staticTokenLifetime},
+	}
+}
+
+// Token implements the TokenSource interface
+func (s *staticTokenSource) Token(ctx context.Context) (*TokenResponse, error) {
+	return s.token, nil
+}
@@ -0,0 +1,275 @@
+// Package ratelimit provides an adaptive client-side throttling middleware for httpio.
+//
+// Many APIs report their remaining request quota through response headers such as
+// X-RateLimit-Remaining and X-RateLimit-Reset. This middleware reads those headers
+// from every response and, once the remaining quota drops to a configured threshold,
+// spaces out subsequent requests so that the remaining quota lasts until the reset
+// time. When the quota is exhausted, requests are held until the window resets. Held
+// requests are released one at a time at the paced interval, not all at once. A 429 or
+// 503 response with a Retry-After header holds every request for the requested delay.
+//
+// The retry middleware shares this throttle state when the limiter is set as its
+// Config.Throttle, so a Retry-After seen while retrying paces all other requests too.
+//
+// Important: The throttle state is shared by every request that passes through the
+// same middleware instance, so a single instance should be used per upstream API.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// ResetFormat describes how the value of the reset header should be interpreted
+type ResetFormat int
+
+const (
+	// ResetAuto treats large values as Unix timestamps and small values as delta seconds
+	ResetAuto ResetFormat = iota
+	// ResetUnix treats the reset header as a Unix timestamp in seconds
+	ResetUnix
+	// ResetDelta treats the reset header as the number of seconds until the reset
+	ResetDelta
+)
+
+// unixThreshold separates delta seconds from Unix timestamps in ResetAuto mode
+const unixThreshold = 1_000_000_000
+
+// Config defines the configuration for the rate limit middleware.
+type Config struct {
+	// RemainingHeader is the response header carrying the remaining request quota.
+	RemainingHeader string
+	// ResetHeader is the response header carrying the time at which the quota resets.
+	ResetHeader string
+	// ResetFormat defines how the ResetHeader value is interpreted.
+	ResetFormat ResetFormat
+	// Threshold is the remaining quota at or below which requests start being paced.
+	Threshold int
+	// MaxDelay caps the delay applied before a single request (0 = no cap).
+	MaxDelay time.Duration
+	// OnThrottle is called whenever a request is delayed, with the delay applied.
+	OnThrottle func(req *http.Request, delay time.Duration)
+}
+
+// DefaultConfig returns a configuration for the common X-RateLimit-* headers.
+func DefaultConfig() *Config {
+	return &Config{
+		RemainingHeader: "X-RateLimit-Remaining",
+		ResetHeader:     "X-RateLimit-Reset",
+		ResetFormat:     ResetAuto,
+		Threshold:       10,
+		MaxDelay:        time.Minute,
+	}
+}
+
+// GitHubConfig returns a configuration matching the GitHub API rate limit headers.
+func GitHubConfig() *Config {
+	config := DefaultConfig()
+	config.ResetFormat = ResetUnix
+	return config
+}
+
+// StandardConfig returns a configuration for the IETF RateLimit-* headers,
+// where the reset value is expressed in seconds.
+func StandardConfig() *Config {
+	config := DefaultConfig()
+	config.RemainingHeader = "RateLimit-Remaining"
+	config.ResetHeader = "RateLimit-Reset"
+	config.ResetFormat = ResetDelta
+	return config
+}
+
+// Middleware implements adaptive throttling based on rate limit response headers
+type Middleware struct {
+	config *Config
+	mu     sync.Mutex
+	// nextAllowed is the earliest time the next request may be sent according to the
+	// rate limit headers. Each admitted request moves it on by interval, so held requests
+	// are released one at a time rather than together.
+	nextAllowed time.Time
+	interval    time.Duration
+	// pausedUntil holds every request after a Retry-After, independently of the rate
+	// limit headers, which therefore cannot shorten the pause
+	pausedUntil time.Time
+}
+
+// New creates a new rate limit middleware with the provided configuration.
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.RemainingHeader == "" {
+		config.RemainingHeader = "X-RateLimit-Remaining"
+	}
+	if config.ResetHeader == "" {
+		config.ResetHeader = "X-RateLimit-Reset"
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// Handle implements the MiddlewareHandler interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if err := m.wait(ctx, req); err != nil {
+			return nil, err
+		}
+
+		resp, err := next(ctx, req)
+		if resp != nil {
+			m.observe(resp)
+			m.observeRetryAfter(resp)
+		}
+		return resp, err
+	}
+}
+
+// PauseUntil holds every request until t, unless the throttle already holds them longer.
+// It lets other middleware, such as retry, feed server back-off signals into the limiter.
+func (m *Middleware) PauseUntil(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pausedUntil = later(m.pausedUntil, t)
+}
+
+// NextAllowed returns the earliest time at which the next request will be sent
+func (m *Middleware) NextAllowed() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return later(m.nextAllowed, m.pausedUntil)
+}
+
+// wait reserves the next slot for the request and blocks until it comes or the context
+// is done, in which case the slot is given back if no later one was handed out since
+func (m *Middleware) wait(ctx context.Context, req *http.Request) error {
+	m.mu.Lock()
+	now := time.Now()
+	slot := later(now, later(m.nextAllowed, m.pausedUntil))
+	var reserved time.Time
+	if m.interval > 0 {
+		m.nextAllowed = slot.Add(m.interval)
+		reserved = m.nextAllowed
+	}
+	m.mu.Unlock()
+
+	delay := slot.Sub(now)
+
+	if delay <= 0 {
+		return nil
+	}
+	if m.config.MaxDelay > 0 && delay > m.config.MaxDelay {
+		delay = m.config.MaxDelay
+	}
+
+	if m.config.OnThrottle != nil {
+		m.config.OnThrottle(req, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		m.mu.Lock()
+		if !reserved.IsZero() && m.nextAllowed.Equal(reserved) {
+			m.nextAllowed = slot
+		}
+		m.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe updates the throttle state from the rate limit headers of a response
+func (m *Middleware) observe(resp *http.Response) {
+	remainingStr := strings.TrimSpace(resp.Header.Get(m.config.RemainingHeader))
+	if remainingStr == "" {
+		return
+	}
+	remaining, err := strconv.Atoi(remainingStr)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if remaining > m.config.Threshold {
+		m.nextAllowed, m.interval = time.Time{}, 0
+		return
+	}
+
+	resetAt, ok := m.parseReset(resp.Header.Get(m.config.ResetHeader))
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	untilReset := resetAt.Sub(now)
+	if untilReset <= 0 {
+		m.nextAllowed, m.interval = time.Time{}, 0
+		return
+	}
+
+	// Slots already handed out are kept. With the quota exhausted, the last interval
+	// spaces out the requests released at the reset.
+	if remaining <= 0 {
+		m.nextAllowed = later(m.nextAllowed, resetAt)
+		return
+	}
+
+	m.interval = untilReset / time.Duration(remaining+1)
+	m.nextAllowed = later(m.nextAllowed, now.Add(m.interval))
+}
+
+// observeRetryAfter pauses requests for the delay a throttled or unavailable upstream asked for
+func (m *Middleware) observeRetryAfter(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	if delay, ok := middleware.RetryAfter(resp); ok {
+		m.PauseUntil(time.Now().Add(delay))
+	}
+}
+
+// later returns the later of a and b
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// parseReset converts the reset header value into an absolute time
+func (m *Middleware) parseReset(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, false
+	}
+
+	format := m.config.ResetFormat
+	if format == ResetAuto {
+		format = ResetDelta
+		if seconds >= unixThreshold {
+			format = ResetUnix
+		}
+	}
+
+	if format == ResetUnix {
+		return time.Unix(0, int64(seconds*float64(time.Second))), true
+	}
+	return time.Now().Add(time.Duration(seconds * float64(time.Second))), true
+}
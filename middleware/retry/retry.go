@@ -15,6 +15,8 @@ package retry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"net/http"
@@ -28,6 +30,39 @@ import (
 // Use a global random source for jitter calculation.
 var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// ErrBodyNotReplayable is matched by the error returned when a failed request should be
+// retried but its body was a one-shot reader that has already been consumed
+var ErrBodyNotReplayable = errors.New("request body cannot be replayed for retry")
+
+// Attempt records the outcome of a single attempt
+type Attempt struct {
+	// StatusCode is the response status, or 0 if no response was received
+	StatusCode int
+	// Err is the error returned by the attempt, if any
+	Err error
+}
+
+// RetryError is returned when every attempt failed with an error. It unwraps to the error
+// of the last attempt, so errors.Is and errors.As see through it.
+type RetryError struct {
+	// Attempts is the number of attempts made, including the first one
+	Attempts int
+	// History holds the outcome of each attempt, in order
+	History []Attempt
+	// Err is the error of the last attempt
+	Err error
+}
+
+// Error implements the error interface
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("request failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
 // Config defines the configuration for the retry middleware.
 type Config struct {
 	// MaxRetries is the maximum number of retries before giving up.
@@ -42,6 +77,19 @@ type Config struct {
 	ErrorPredicate func(err error) bool
 	// JitterFactor is the randomization factor for backoff delay (0 = no jitter, 0.2 = 20% jitter, etc).
 	JitterFactor float64
+	// OnRetry, if set, is called before each backoff sleep with the retry number (starting
+	// at 1), the request and outcome of the failed attempt and the delay before the next one.
+	// The response body, if any, is already closed.
+	OnRetry func(attempt int, req *http.Request, resp *http.Response, err error, nextDelay time.Duration)
+	// Throttle, if set, is told when the server asks to back off with Retry-After, so that
+	// other requests are held as well. A *ratelimit.Middleware can be used here.
+	Throttle Throttle
+}
+
+// Throttle holds requests until a given time. It is implemented by the rate limit
+// middleware, which lets a retry signal pace every request sharing the limiter.
+type Throttle interface {
+	PauseUntil(t time.Time)
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
@@ -79,24 +127,44 @@ func New(config *Config) *Middleware {
 	}
 }
 
-// Handle implements the MiddlewareHandler interface
+// Handle implements the MiddlewareHandler interface.
+// When all attempts fail with an error, the error returned is a *RetryError carrying the
+// attempt history. A final response with a retryable status is returned as is. A
+// Retry-After header on a failed response lengthens the delay before the next attempt.
 func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
 		resp, err := next(ctx, req)
 
-		if err == nil && resp != nil && !shouldRetry(m.config, resp, err) {
-			return resp, nil
+		if !shouldRetry(m.config, resp, err) {
+			return resp, err
 		}
 
 		var lastResp *http.Response = resp
 		var lastErr error = err
+		lastReq := req
+		history := []Attempt{newAttempt(resp, err)}
 
 		for attempt := 0; attempt < m.config.MaxRetries; attempt++ {
+			if !replayable(req) {
+				return nil, notReplayableError(lastResp, lastErr)
+			}
 			if lastResp != nil && lastResp.Body != nil {
 				lastResp.Body.Close()
 			}
 
 			backoffDuration := calcBackoff(m.config, attempt)
+			if retryAfter, ok := middleware.RetryAfter(lastResp); ok {
+				backoffDuration = max(backoffDuration, retryAfter)
+				if m.config.MaxDelay > 0 {
+					backoffDuration = min(backoffDuration, m.config.MaxDelay)
+				}
+				if m.config.Throttle != nil {
+					m.config.Throttle.PauseUntil(time.Now().Add(retryAfter))
+				}
+			}
+			if m.config.OnRetry != nil {
+				m.config.OnRetry(attempt+1, lastReq, lastResp, lastErr, backoffDuration)
+			}
 			select {
 			case <-ctx.Done():
 				return lastResp, ctx.Err()
@@ -110,13 +178,13 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 				if bodyErr != nil {
 					return lastResp, bodyErr
 				}
-			} else if retryReq.Body != nil {
-				return lastResp, lastErr
 			}
 
 			retryResp, retryErr := next(ctx, retryReq)
+			lastReq = retryReq
 			lastResp = retryResp
 			lastErr = retryErr
+			history = append(history, newAttempt(retryResp, retryErr))
 
 			if retryResp != nil && retryResp.StatusCode < 500 && retryErr == nil {
 				return retryResp, retryErr
@@ -127,10 +195,42 @@ func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
 			}
 		}
 
+		if lastErr != nil {
+			return lastResp, &RetryError{Attempts: len(history), History: history, Err: lastErr}
+		}
 		return lastResp, lastErr
 	}
 }
 
+// replayable reports whether the body of req can be sent again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// notReplayableError describes a failed attempt that cannot be retried because of its
+// body, closing the response of the attempt
+func notReplayableError(resp *http.Response, err error) error {
+	cause := err
+	if cause == nil {
+		cause = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	return fmt.Errorf("%w: the body is a one-shot io.Reader, use a []byte, string, "+
+		"bytes.Reader or strings.Reader body, or set GetBody, to allow retries "+
+		"(attempt failed: %w)", ErrBodyNotReplayable, cause)
+}
+
+// newAttempt records the outcome of an attempt
+func newAttempt(resp *http.Response, err error) Attempt {
+	attempt := Attempt{Err: err}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+	}
+	return attempt
+}
+
 // shouldRetry checks if a response or error should trigger a retry.
 func shouldRetry(config *Config, resp *http.Response, err error) bool {
 	if err != nil && config.ErrorPredicate != nil {
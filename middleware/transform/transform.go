@@ -0,0 +1,165 @@
+// Package transform provides a middleware that rewrites request and response bodies.
+//
+// It is intended for transparent body processing such as field-level encryption: outgoing
+// bodies are buffered and passed through a RequestBodyTransform before being sent, and
+// incoming bodies are buffered and passed through a ResponseBodyTransform before being
+// returned. Content-Length is updated to match the transformed bodies.
+//
+// Streaming responses (Server-Sent Events and NDJSON) are not buffered unless a
+// StreamTransform is configured, in which case it wraps the body reader instead.
+package transform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// RequestBodyTransform rewrites an outgoing request body
+type RequestBodyTransform func([]byte) ([]byte, error)
+
+// ResponseBodyTransform rewrites an incoming response body
+type ResponseBodyTransform func([]byte) ([]byte, error)
+
+// StreamTransform wraps the body of a streaming response
+type StreamTransform func(io.Reader) io.Reader
+
+// Config represents the configuration for the transform middleware
+type Config struct {
+	// Request rewrites request bodies (nil = unchanged)
+	Request RequestBodyTransform
+	// Response rewrites buffered response bodies (nil = unchanged)
+	Response ResponseBodyTransform
+	// Stream wraps the bodies of streaming responses (nil = unchanged)
+	Stream StreamTransform
+	// StreamingContentTypes are the media types of responses that are not buffered
+	// (default: Server-Sent Events and NDJSON)
+	StreamingContentTypes []string
+}
+
+// defaultStreamingContentTypes are the streaming media types used when none are configured
+var defaultStreamingContentTypes = []string{"text/event-stream", "application/x-ndjson"}
+
+// DefaultConfig returns a configuration that leaves bodies unchanged and treats
+// Server-Sent Events and NDJSON responses as streaming
+func DefaultConfig() *Config {
+	return &Config{
+		StreamingContentTypes: defaultStreamingContentTypes,
+	}
+}
+
+// Middleware is the transform middleware implementation
+type Middleware struct {
+	config *Config
+}
+
+// New creates a new transform middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.StreamingContentTypes == nil {
+		config.StreamingContentTypes = defaultStreamingContentTypes
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if m.config.Request != nil && req.Body != nil && req.Body != http.NoBody {
+			transformed, err := m.transformRequest(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			req = transformed
+		}
+
+		resp, err := next(ctx, req)
+		if err != nil || resp == nil || resp.Body == nil {
+			return resp, err
+		}
+
+		if m.isStreaming(resp) {
+			if m.config.Stream != nil {
+				resp.Body = &readCloser{Reader: m.config.Stream(resp.Body), Closer: resp.Body}
+				resp.ContentLength = -1
+				resp.Header.Del("Content-Length")
+			}
+			return resp, nil
+		}
+
+		if m.config.Response != nil {
+			if err := m.transformResponse(resp); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+}
+
+// transformRequest returns a copy of req with the body buffered and rewritten, keeping it
+// replayable. req itself keeps its GetBody, so middleware replaying it from further out,
+// such as retries, passes the original body through the transform again rather than
+// transforming the transformed one.
+func (m *Middleware) transformRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("transform middleware: failed to read request body: %w", err)
+	}
+
+	data, err = m.config.Request(data)
+	if err != nil {
+		return nil, fmt.Errorf("transform middleware: request transform failed: %w", err)
+	}
+
+	transformed := req.Clone(ctx)
+	transformed.ContentLength = int64(len(data))
+	transformed.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	transformed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	transformed.Body, _ = transformed.GetBody()
+	return transformed, nil
+}
+
+// transformResponse buffers and rewrites the response body
+func (m *Middleware) transformResponse(resp *http.Response) error {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("transform middleware: failed to read response body: %w", err)
+	}
+
+	data, err = m.config.Response(data)
+	if err != nil {
+		return fmt.Errorf("transform middleware: response transform failed: %w", err)
+	}
+
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return nil
+}
+
+// isStreaming reports whether the response has one of the configured streaming content types
+func (m *Middleware) isStreaming(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return slices.Contains(m.config.StreamingContentTypes, mediaType)
+}
+
+// readCloser combines a transformed reader with the closer of the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,150 @@
+// Package urlnorm provides a middleware that normalizes outgoing request URLs.
+//
+// Accidental spaces, unescaped characters or duplicate slashes in request paths cause
+// obscure failures on the server side. The middleware cleans them up before the request
+// is sent: duplicate slashes are collapsed, invalid characters are percent-encoded and
+// the host is lowercased. In strict mode, malformed URLs are rejected with an *Error
+// instead of being fixed.
+package urlnorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// ErrMalformedURL is matched by errors returned when a URL is rejected in strict mode
+var ErrMalformedURL = errors.New("malformed request URL")
+
+// Error is returned in strict mode for URLs that would need normalization
+type Error struct {
+	// URL is the rejected URL
+	URL string
+	// Normalized is the URL the middleware would have sent
+	Normalized string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("malformed request URL %q (normalized: %q)", e.URL, e.Normalized)
+}
+
+// Unwrap allows errors.Is(err, ErrMalformedURL)
+func (e *Error) Unwrap() error {
+	return ErrMalformedURL
+}
+
+// Config represents the configuration for the URL normalization middleware
+type Config struct {
+	// Strict rejects URLs that need normalization instead of fixing them
+	Strict bool
+	// CollapseSlashes replaces runs of slashes in the path with a single slash
+	CollapseSlashes bool
+	// LowercaseHost lowercases the host name
+	LowercaseHost bool
+}
+
+// DefaultConfig returns a configuration that fixes URLs
+func DefaultConfig() *Config {
+	return &Config{
+		CollapseSlashes: true,
+		LowercaseHost:   true,
+	}
+}
+
+// Middleware is the URL normalization middleware implementation
+type Middleware struct {
+	config *Config
+}
+
+// New creates a new URL normalization middleware with the provided configuration
+func New(config *Config) *Middleware {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Middleware{
+		config: config,
+	}
+}
+
+// Handle implements the middleware.Middleware interface
+func (m *Middleware) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		normalized, malformed := m.normalize(req.URL)
+		if malformed {
+			if m.config.Strict {
+				return nil, &Error{URL: req.URL.String(), Normalized: normalized.String()}
+			}
+			// A Host taken from the URL follows the normalized one; an explicit
+			// override, e.g. for virtual hosting, is kept
+			if req.Host == req.URL.Host {
+				req.Host = ""
+			}
+			req.URL = normalized
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// duplicateSlashes matches runs of two or more slashes
+var duplicateSlashes = regexp.MustCompile(`/{2,}`)
+
+// invalidQueryChars are characters that must be percent-encoded in a query string
+const invalidQueryChars = " \"<>\\^`{|}"
+
+// normalize returns a normalized copy of u and whether it differs from u. Paths
+// containing whitespace or control characters are considered malformed even though the
+// escaped form sent on the wire is valid, since they are almost always accidental.
+func (m *Middleware) normalize(u *url.URL) (*url.URL, bool) {
+	normalized := *u
+	malformed := strings.IndexFunc(u.Path, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0
+
+	if m.config.LowercaseHost {
+		if host := strings.ToLower(u.Host); host != u.Host {
+			normalized.Host = host
+			malformed = true
+		}
+	}
+
+	// Working on the escaped path keeps escaped slashes distinct from path separators
+	escaped := u.EscapedPath()
+	if m.config.CollapseSlashes && duplicateSlashes.MatchString(escaped) {
+		escaped = duplicateSlashes.ReplaceAllString(escaped, "/")
+		malformed = true
+	}
+	if path, err := url.PathUnescape(escaped); err == nil {
+		normalized.Path = path
+		normalized.RawPath = escaped
+	}
+
+	if strings.ContainsAny(u.RawQuery, invalidQueryChars) {
+		normalized.RawQuery = escapeQuery(u.RawQuery)
+		malformed = true
+	}
+
+	return &normalized, malformed
+}
+
+// escapeQuery percent-encodes the invalid characters of a raw query, keeping the order of
+// the parameters and any existing escapes, which servers may depend on, e.g. for signing
+func escapeQuery(rawQuery string) string {
+	var b strings.Builder
+	for i := 0; i < len(rawQuery); i++ {
+		if c := rawQuery[i]; strings.IndexByte(invalidQueryChars, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// TestingT is the subset of testing.TB used to report unmet expectations
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Expectation describes a request the mock server expects to receive and the
+// response it answers with
+type Expectation struct {
+	server   *MockServer
+	method   string
+	path     string
+	headers  map[string]string
+	jsonBody interface{}
+	hasBody  bool
+	status   int
+	response interface{}
+	calls    int
+}
+
+// Expect registers an expectation for a request with the given method and path.
+// Matching requests are answered by the expectation instead of the regular routes.
+func (ms *MockServer) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		server:  ms,
+		method:  method,
+		path:    path,
+		headers: make(map[string]string),
+		status:  http.StatusOK,
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.expectations = append(ms.expectations, e)
+	return e
+}
+
+// WithHeader requires the request to carry the header with the given value
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.server.mutex.Lock()
+	defer e.server.mutex.Unlock()
+	e.headers[key] = value
+	return e
+}
+
+// WithJSONBody requires the request body to be JSON equivalent to body
+func (e *Expectation) WithJSONBody(body interface{}) *Expectation {
+	e.server.mutex.Lock()
+	defer e.server.mutex.Unlock()
+	e.jsonBody = normalizeJSON(body)
+	e.hasBody = true
+	return e
+}
+
+// RespondJSON sets the status code and JSON payload sent for matching requests
+func (e *Expectation) RespondJSON(statusCode int, data interface{}) *Expectation {
+	e.server.mutex.Lock()
+	defer e.server.mutex.Unlock()
+	e.status = statusCode
+	e.response = data
+	return e
+}
+
+// Calls returns how many requests matched the expectation
+func (e *Expectation) Calls() int {
+	e.server.mutex.RLock()
+	defer e.server.mutex.RUnlock()
+	return e.calls
+}
+
+// matches reports whether the request satisfies the expectation; the caller must hold
+// the server mutex
+func (e *Expectation) matches(r *http.Request, body []byte) bool {
+	if e.method != r.Method || e.path != r.URL.Path {
+		return false
+	}
+
+	for key, value := range e.headers {
+		if r.Header.Get(key) != value {
+			return false
+		}
+	}
+
+	if e.hasBody {
+		var actual interface{}
+		if err := json.Unmarshal(body, &actual); err != nil {
+			return false
+		}
+		if !reflect.DeepEqual(e.jsonBody, actual) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AssertExpectations reports every expectation that was never matched by a request
+func (ms *MockServer) AssertExpectations(t TestingT) bool {
+	t.Helper()
+
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	ok := true
+	for _, e := range ms.expectations {
+		if e.calls == 0 {
+			t.Errorf("mockserver: expected %s %s was not received", e.method, e.path)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// matchExpectation returns the first expectation matching the request and records the call
+func (ms *MockServer) matchExpectation(r *http.Request, body []byte) *Expectation {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, e := range ms.expectations {
+		if e.matches(r, body) {
+			e.calls++
+			return e
+		}
+	}
+	return nil
+}
+
+// normalizeJSON converts a value into its generic JSON representation for comparison
+func normalizeJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
@@ -0,0 +1,110 @@
+package mockserver
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FaultConfig describes the failures and delays injected by a faulty route
+type FaultConfig struct {
+	// StatusCodes are returned, in order, for the first len(StatusCodes) requests
+	StatusCodes []int
+
+	// FailureRate is the probability (0.0-1.0) that a request beyond StatusCodes fails
+	FailureRate float64
+
+	// FailureStatus is the status code used for random failures (default 503)
+	FailureStatus int
+
+	// Latency is added before every response, including failures
+	Latency time.Duration
+
+	// DropRate is the probability (0.0-1.0) that the connection is closed without a response
+	DropRate float64
+
+	// Response is sent when a request is not failed or dropped
+	Response ResponseConfig
+}
+
+// faultyRoute tracks the request count of a faulty route
+type faultyRoute struct {
+	config   FaultConfig
+	mu       sync.Mutex
+	requests int
+	rand     *rand.Rand
+}
+
+// AddFaultyRoute adds a JSON route that injects failures, latency and dropped
+// connections according to config
+func (ms *MockServer) AddFaultyRoute(path string, config FaultConfig) {
+	if config.FailureStatus == 0 {
+		config.FailureStatus = http.StatusServiceUnavailable
+	}
+
+	route := &faultyRoute{
+		config: config,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	ms.AddRoute(path, func(w http.ResponseWriter, r *http.Request) {
+		status, drop := route.next()
+
+		if config.Latency > 0 {
+			time.Sleep(config.Latency)
+		}
+
+		if drop {
+			dropConnection(w)
+			return
+		}
+
+		if status != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error": "Injected Fault"}`))
+			return
+		}
+
+		ms.handleJSONResponse(w, r, config.Response)
+	})
+}
+
+// next decides the fault for the next request, returning the status code to fail
+// with (0 for success) and whether the connection should be dropped
+func (fr *faultyRoute) next() (int, bool) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	index := fr.requests
+	fr.requests++
+
+	if index < len(fr.config.StatusCodes) {
+		return fr.config.StatusCodes[index], false
+	}
+
+	if fr.config.DropRate > 0 && fr.rand.Float64() < fr.config.DropRate {
+		return 0, true
+	}
+
+	if fr.config.FailureRate > 0 && fr.rand.Float64() < fr.config.FailureRate {
+		return fr.config.FailureStatus, false
+	}
+
+	return 0, false
+}
+
+// dropConnection closes the underlying connection without writing a response
+func dropConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
@@ -1,9 +0,0 @@
-module mockserver
-
-go 1.24.3
-
-replace github.com/anggasct/httpio => ../
-
-require github.com/anggasct/httpio v0.0.0-00010101000000-000000000000
-
-require github.com/google/uuid v1.6.0 // indirect
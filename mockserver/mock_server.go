@@ -2,10 +2,15 @@
 package mockserver
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +26,11 @@ type MockServer struct {
 	address      string
 	stopChan     chan struct{}
 	defaultDelay time.Duration
+
+	decompressRequests bool
+	lastRequestBody    []byte
+	expectations       []*Expectation
+	overrides          map[string]RouteConfig
 }
 
 // RouteConfig contains configuration for a route
@@ -49,6 +59,7 @@ func NewMockServer(address string) *MockServer {
 
 	return &MockServer{
 		routes:       make(map[string]RouteConfig),
+		overrides:    make(map[string]RouteConfig),
 		middleware:   make([]MiddlewareFunc, 0),
 		address:      address,
 		stopChan:     make(chan struct{}),
@@ -70,6 +81,75 @@ func (ms *MockServer) SetDefaultDelay(delay time.Duration) {
 	ms.defaultDelay = delay
 }
 
+// EnableRequestDecompression makes the server transparently decompress gzip and deflate
+// encoded request bodies before they reach the route handlers
+func (ms *MockServer) EnableRequestDecompression(enabled bool) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.decompressRequests = enabled
+}
+
+// LastRequestBody returns the body of the most recent request, decompressed when
+// request decompression is enabled
+func (ms *MockServer) LastRequestBody() []byte {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+	return ms.lastRequestBody
+}
+
+// captureRequestBody records and returns the request body, decompressing it if enabled,
+// and replaces it so handlers can still read it
+func (ms *MockServer) captureRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	ms.mutex.RLock()
+	decompress := ms.decompressRequests
+	ms.mutex.RUnlock()
+
+	var reader io.Reader = r.Body
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if decompress && encoding != "" && encoding != "identity" {
+		switch encoding {
+		case "gzip":
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			reader = gz
+		case "deflate":
+			zr, err := zlib.NewReader(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			reader = zr
+		default:
+			return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+		}
+	}
+
+	body, err := io.ReadAll(reader)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if reader != r.Body {
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = int64(len(body))
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	ms.mutex.Lock()
+	ms.lastRequestBody = body
+	ms.mutex.Unlock()
+
+	return body, nil
+}
+
 // AddRoute adds a route handler to the mock server
 func (ms *MockServer) AddRoute(path string, handler http.HandlerFunc) {
 	ms.AddRouteWithMethods(path, handler, []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"})
@@ -85,6 +165,35 @@ func (ms *MockServer) AddRouteWithMethods(path string, handler http.HandlerFunc,
 	}
 }
 
+// SetRoute replaces the handler of a route at runtime, keeping its allowed methods.
+// Requests already in flight finish with the previous handler. The original route is
+// restored by Reset.
+func (ms *MockServer) SetRoute(path string, handler http.HandlerFunc) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"}
+	if existing, ok := ms.routes[path]; ok {
+		methods = existing.methods
+	}
+	ms.overrides[path] = RouteConfig{
+		handler: handler,
+		methods: methods,
+	}
+}
+
+// Reset clears recorded requests, expectations and route overrides, and restores
+// the default delay and request decompression settings
+func (ms *MockServer) Reset() {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.overrides = make(map[string]RouteConfig)
+	ms.expectations = nil
+	ms.lastRequestBody = nil
+	ms.defaultDelay = 0
+	ms.decompressRequests = false
+}
+
 // AddJSONRoute adds a route that returns JSON response from a struct
 func (ms *MockServer) AddJSONRoute(path string, config ResponseConfig) {
 	ms.AddRoute(path, func(w http.ResponseWriter, r *http.Request) {
@@ -103,7 +212,9 @@ func (ms *MockServer) AddJSONRouteWithMethods(path string, config ResponseConfig
 func (ms *MockServer) handleJSONResponse(w http.ResponseWriter, r *http.Request, config ResponseConfig) {
 	delay := config.Delay
 	if delay == 0 {
+		ms.mutex.RLock()
 		delay = ms.defaultDelay
+		ms.mutex.RUnlock()
 	}
 
 	if delay > 0 {
@@ -132,8 +243,24 @@ func (ms *MockServer) handleJSONResponse(w http.ResponseWriter, r *http.Request,
 // handler processes all incoming HTTP requests
 func (ms *MockServer) handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ms.captureRequestBody(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Invalid Request Body"}`))
+			return
+		}
+
+		if expectation := ms.matchExpectation(r, body); expectation != nil {
+			ms.mutex.RLock()
+			config := ResponseConfig{StatusCode: expectation.status, Data: expectation.response}
+			ms.mutex.RUnlock()
+			ms.handleJSONResponse(w, r, config)
+			return
+		}
+
 		ms.mutex.RLock()
-		routeConfig, exists := ms.routes[r.URL.Path]
+		routeConfig, params, exists := ms.lookupRoute(r.URL.Path)
+		middlewares := ms.middleware
 		ms.mutex.RUnlock()
 
 		if !exists {
@@ -158,7 +285,7 @@ func (ms *MockServer) handler() http.Handler {
 
 		handler := routeConfig.handler
 
-		for _, middleware := range ms.middleware {
+		for _, middleware := range middlewares {
 			handler = middleware(handler).ServeHTTP
 		}
 
@@ -166,10 +293,16 @@ func (ms *MockServer) handler() http.Handler {
 			handler = middleware(http.HandlerFunc(handler)).ServeHTTP
 		}
 
-		handler(w, r)
+		handler(w, withPathParams(r, params))
 	})
 }
 
+// ServeHTTP serves the mock server's routes, so it can also be mounted on another
+// server such as an httptest.Server instead of being started on its own address
+func (ms *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ms.handler().ServeHTTP(w, r)
+}
+
 // Start starts the mock server
 func (ms *MockServer) Start() error {
 	ms.server = &http.Server{
@@ -323,7 +456,7 @@ func (ms *MockServer) AddRESTRoute(basePath string, resource interface{}, storag
 	}, []string{"GET", "POST"})
 
 	ms.AddRouteWithMethods(basePath+"/{id}", func(w http.ResponseWriter, r *http.Request) {
-		id := r.URL.Path[len(basePath)+1:]
+		id := PathParam(r, "id")
 
 		switch r.Method {
 		case "GET":
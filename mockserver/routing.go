@@ -0,0 +1,92 @@
+package mockserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// pathParamsKey is the context key holding the parameters captured from a route pattern
+type pathParamsKey struct{}
+
+// PathParam returns the value captured for the named {param} segment of the matched route
+func PathParam(r *http.Request, name string) string {
+	return PathParams(r)[name]
+}
+
+// PathParams returns all values captured from {param} segments of the matched route
+func PathParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return params
+}
+
+// withPathParams returns a copy of r carrying the captured path parameters
+func withPathParams(r *http.Request, params map[string]string) *http.Request {
+	if len(params) == 0 {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+}
+
+// lookupRoute finds the route for path, preferring overrides and exact matches over
+// patterns. Among matching patterns the one with the fewest parameters wins.
+// The caller must hold the read lock.
+func (ms *MockServer) lookupRoute(path string) (RouteConfig, map[string]string, bool) {
+	for _, routes := range []map[string]RouteConfig{ms.overrides, ms.routes} {
+		if config, ok := routes[path]; ok {
+			return config, nil, true
+		}
+	}
+
+	for _, routes := range []map[string]RouteConfig{ms.overrides, ms.routes} {
+		var (
+			best       RouteConfig
+			bestParams map[string]string
+			found      bool
+		)
+		for pattern, config := range routes {
+			params, ok := matchPattern(pattern, path)
+			if !ok {
+				continue
+			}
+			if !found || len(params) < len(bestParams) {
+				best, bestParams, found = config, params, true
+			}
+		}
+		if found {
+			return best, bestParams, true
+		}
+	}
+
+	return RouteConfig{}, nil, false
+}
+
+// matchPattern matches path against a pattern such as /api/users/{id}, returning the
+// captured parameters
+func matchPattern(pattern, path string) (map[string]string, bool) {
+	if !strings.Contains(pattern, "{") {
+		return nil, false
+	}
+
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[part[1:len(part)-1]] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
@@ -0,0 +1,47 @@
+package httpio
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/anggasct/httpio/internal/client"
+)
+
+// PaginateLinks sends firstReq and follows the "next" link of each response, as found by
+// Response.Links, until a page has none. The handler is called with each page and the
+// page is closed once it returns; an error from the handler stops pagination and is
+// returned. Follow-up requests keep the method, headers and per-request settings of
+// firstReq, with the URL of the link replacing its URL and query.
+func (c *Client) PaginateLinks(ctx context.Context, firstReq *client.Request, handler func(page *Response) error) error {
+	req := firstReq
+	seen := make(map[string]bool)
+	for {
+		resp, err := req.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		var current string
+		if resp.Request != nil {
+			current = resp.Request.URL.String()
+		}
+		seen[current] = true
+		next, hasNext := resp.Links()["next"]
+
+		err = handler(resp)
+		resp.Close()
+		if err != nil {
+			return err
+		}
+		// A next link pointing back to a page already visited would loop forever
+		if !hasNext || seen[next] {
+			return nil
+		}
+
+		following := *req
+		following.URL = next
+		following.Query = make(url.Values)
+		following.Body = nil
+		req = &following
+	}
+}
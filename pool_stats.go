@@ -0,0 +1,90 @@
+package httpio
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats summarizes how requests obtained their connections, to help size the
+// connection pool. Counters accumulate over the lifetime of the client.
+type PoolStats struct {
+	// Requests is the number of connections handed to requests
+	Requests int64
+	// Reused is the number of requests served by a previously used connection
+	Reused int64
+	// New is the number of requests that had to establish a new connection
+	New int64
+	// Idle is the number of reused connections that were taken from the idle pool
+	Idle int64
+	// TotalWait is the total time requests spent obtaining a connection
+	TotalWait time.Duration
+	// MaxWait is the longest time a request spent obtaining a connection
+	MaxWait time.Duration
+}
+
+// AverageWait returns the mean time requests spent obtaining a connection
+func (s PoolStats) AverageWait() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Requests)
+}
+
+// poolCounters accumulates PoolStats from connection traces
+type poolCounters struct {
+	requests  atomic.Int64
+	reused    atomic.Int64
+	created   atomic.Int64
+	idle      atomic.Int64
+	totalWait atomic.Int64
+	maxWait   atomic.Int64
+}
+
+// PoolStats returns the connection statistics gathered from the requests sent so far
+func (c *Client) PoolStats() PoolStats {
+	return PoolStats{
+		Requests:  c.pool.requests.Load(),
+		Reused:    c.pool.reused.Load(),
+		New:       c.pool.created.Load(),
+		Idle:      c.pool.idle.Load(),
+		TotalWait: time.Duration(c.pool.totalWait.Load()),
+		MaxWait:   time.Duration(c.pool.maxWait.Load()),
+	}
+}
+
+// withPoolTrace returns req with a connection trace feeding the client's pool statistics
+func (c *Client) withPoolTrace(req *http.Request) *http.Request {
+	var start time.Time
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			start = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.pool.record(info, time.Since(start))
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// record accounts for a connection obtained after waiting for wait
+func (p *poolCounters) record(info httptrace.GotConnInfo, wait time.Duration) {
+	p.requests.Add(1)
+	if info.Reused {
+		p.reused.Add(1)
+	} else {
+		p.created.Add(1)
+	}
+	if info.WasIdle {
+		p.idle.Add(1)
+	}
+
+	p.totalWait.Add(int64(wait))
+	for {
+		current := p.maxWait.Load()
+		if int64(wait) <= current || p.maxWait.CompareAndSwap(current, int64(wait)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package httpio
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"syscall"
+)
+
+// WithSafeRetries enables transparent retries, up to n times, of idempotent requests
+// that fail with a connection reset or an EOF before any byte of the response was received.
+// Such failures are almost always safe to retry because the server never answered.
+func (c *Client) WithSafeRetries(n int) *Client {
+	c.safeRetries = n
+	return c
+}
+
+// doWithSafeRetries sends the request, retrying it on connection-level failures that
+// happened before the first response byte arrived
+func (c *Client) doWithSafeRetries(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var received atomic.Bool
+		trace := &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				received.Store(true)
+			},
+		}
+		attemptReq := req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, errors.New("httpio: cannot retry request with a non-replayable body")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := httpClient.Do(attemptReq)
+		if err == nil || attempt >= c.safeRetries || received.Load() ||
+			req.Context().Err() != nil || !isSafeRetryError(err) {
+			return resp, err
+		}
+	}
+}
+
+// isIdempotentMethod reports whether the HTTP method is idempotent as defined by RFC 9110
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSafeRetryError reports whether err is a connection reset or a premature EOF
+func isSafeRetryError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
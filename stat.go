@@ -0,0 +1,50 @@
+package httpio
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ResourceInfo describes a remote resource from the headers of a HEAD response
+type ResourceInfo struct {
+	// Exists is false when the server responded with 404 Not Found
+	Exists bool
+	// ContentLength is the size of the resource in bytes, or -1 if unknown
+	ContentLength int64
+	// ContentType is the Content-Type of the resource
+	ContentType string
+	// LastModified is the Last-Modified time of the resource, zero if absent
+	LastModified time.Time
+	// ETag is the entity tag of the resource
+	ETag string
+}
+
+// Stat issues a HEAD request for path and returns the metadata of the resource. A 404
+// response is not an error and reports Exists as false; other unsuccessful responses
+// return a *StatusError.
+func (c *Client) Stat(ctx context.Context, path string) (ResourceInfo, error) {
+	resp, err := c.HEAD(ctx, path)
+	if err != nil {
+		return ResourceInfo{}, err
+	}
+	defer resp.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ResourceInfo{ContentLength: -1}, nil
+	}
+	if err := resp.Err(); err != nil {
+		return ResourceInfo{}, err
+	}
+
+	info := ResourceInfo{
+		Exists:        true,
+		ContentLength: resp.ContentLength,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ETag:          resp.Header.Get("ETag"),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = lastModified
+	}
+	return info, nil
+}
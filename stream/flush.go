@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultIdleFlush is how long buffered records may wait for more data when flushing
+// every N records without an explicit FlushInterval
+const defaultIdleFlush = 100 * time.Millisecond
+
+// PipeOption configures Pipe
+type PipeOption func(*pipeOptions)
+
+type pipeOptions struct {
+	flushEvery    int
+	flushInterval time.Duration
+}
+
+// FlushEvery flushes after every n records instead of after each one, reducing syscalls
+// for high-rate streams of small records. Buffered records are still flushed once the
+// stream has been idle for the FlushInterval, 100ms by default.
+func FlushEvery(n int) PipeOption {
+	return func(o *pipeOptions) {
+		o.flushEvery = n
+	}
+}
+
+// FlushInterval flushes buffered records at most the given duration after the first of
+// them was written
+func FlushInterval(d time.Duration) PipeOption {
+	return func(o *pipeOptions) {
+		o.flushInterval = d
+	}
+}
+
+// flushWriter writes records to a ResponseWriter, flushing according to the options
+type flushWriter struct {
+	dst     http.ResponseWriter
+	flusher http.Flusher
+	every   int
+	wait    time.Duration
+
+	mu      sync.Mutex
+	pending int
+	timer   *time.Timer
+	closed  bool
+}
+
+// newFlushWriter creates a flushWriter for dst. Without options it flushes every record.
+func newFlushWriter(dst http.ResponseWriter, opts ...PipeOption) *flushWriter {
+	options := &pipeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	w := &flushWriter{dst: dst, every: options.flushEvery, wait: options.flushInterval}
+	w.flusher, _ = dst.(http.Flusher)
+	if w.every <= 0 && w.wait <= 0 {
+		w.every = 1
+	}
+	if w.every > 1 && w.wait <= 0 {
+		w.wait = defaultIdleFlush
+	}
+	return w
+}
+
+// write writes a record and flushes if the cadence requires it
+func (w *flushWriter) write(record []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.dst.Write(record); err != nil {
+		return err
+	}
+	w.pending++
+
+	if w.every > 0 && w.pending >= w.every {
+		w.flushLocked()
+		return nil
+	}
+	if w.wait > 0 && w.timer == nil {
+		w.timer = time.AfterFunc(w.wait, w.flushPending)
+	}
+	return nil
+}
+
+// flushPending flushes buffered records from the idle timer
+func (w *flushWriter) flushPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer = nil
+	if !w.closed {
+		w.flushLocked()
+	}
+}
+
+// flushLocked flushes buffered records; w.mu must be held
+func (w *flushWriter) flushLocked() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.pending > 0 && w.flusher != nil {
+		w.flusher.Flush()
+	}
+	w.pending = 0
+}
+
+// close flushes buffered records and stops the idle timer, after which dst is no longer
+// written to
+func (w *flushWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+	w.closed = true
+}
@@ -0,0 +1,104 @@
+// Package stream provides helpers for forwarding httpio streaming responses, e.g. from a
+// proxy handler to its own client.
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/anggasct/httpio"
+)
+
+// hopHeaders are connection-specific headers that must not be forwarded by proxies
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Pipe copies the streaming response src to dst, flushing after each write so that records
+// reach the client as soon as they arrive. FlushEvery and FlushInterval trade some latency
+// for fewer flushes on high-rate streams.
+//
+// The status code and end-to-end headers of src are written first; Content-Length is dropped
+// when a transform is given, since it may change the size of the body. Each chunk read from
+// src is passed through transform, if not nil, before being written; a nil result skips the
+// chunk. Trailers received from src are forwarded after the body.
+//
+// Pipe stops and closes src as soon as ctx is done, typically because the client of dst
+// disconnected, returning the context error. The request context of the handler serving
+// dst is the natural choice for ctx.
+func Pipe(ctx context.Context, src *httpio.Response, dst http.ResponseWriter, transform func([]byte) ([]byte, error), opts ...PipeOption) error {
+	if src == nil || src.Response == nil || src.Body == nil {
+		return errors.New("stream: source response has no body")
+	}
+	defer src.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		src.Body.Close()
+	})
+	defer stop()
+
+	header := dst.Header()
+	for key, values := range src.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	for _, key := range hopHeaders {
+		header.Del(key)
+	}
+	if transform != nil {
+		header.Del("Content-Length")
+	}
+	dst.WriteHeader(src.StatusCode)
+
+	if flusher, ok := dst.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	writer := newFlushWriter(dst, opts...)
+	defer writer.close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Body.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if transform != nil {
+				var transformErr error
+				if chunk, transformErr = transform(chunk); transformErr != nil {
+					return transformErr
+				}
+			}
+			if len(chunk) > 0 {
+				if writeErr := writer.write(chunk); writeErr != nil {
+					return writeErr
+				}
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+	}
+
+	writer.close()
+	for key, values := range src.Trailer {
+		for _, value := range values {
+			header.Add(http.TrailerPrefix+key, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package httpio
+
+import (
+	"strings"
+
+	"github.com/anggasct/httpio/internal/client"
+)
+
+// RequestTemplate declares a request, typically loaded from configuration, that
+// Client.FromTemplate turns into a prepared Request
+type RequestTemplate struct {
+	// Name identifies the template and is used as the request's metric name
+	Name string `json:"name"`
+	// Method is the HTTP method (default: GET)
+	Method string `json:"method"`
+	// Path is the request path, relative to the client's base URL. It may contain {name}
+	// placeholders that are replaced with the escaped value of the matching entry in Params.
+	Path string `json:"path"`
+	// Headers are added to the request
+	Headers map[string]string `json:"headers,omitempty"`
+	// Query holds query parameters added to the request
+	Query map[string]string `json:"query,omitempty"`
+	// Params holds the values of the path placeholders
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// WithParams returns a copy of the template using params as the values of its path
+// placeholders, leaving the original untouched so it can be shared
+func (t RequestTemplate) WithParams(params map[string]string) RequestTemplate {
+	t.Params = params
+	return t
+}
+
+// FromTemplate builds a prepared request from a declarative template. Placeholders without
+// a value in tmpl.Params make Do fail with ErrUnresolvedPathParam.
+func (c *Client) FromTemplate(tmpl RequestTemplate) *client.Request {
+	method := tmpl.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	req := c.NewRequest(strings.ToUpper(method), tmpl.Path).
+		WithPathParams(tmpl.Params).
+		WithHeaders(tmpl.Headers).
+		WithQueryMap(tmpl.Query)
+	if tmpl.Name != "" {
+		req.WithMetricName(tmpl.Name)
+	}
+	return req
+}
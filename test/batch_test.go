@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anggasct/httpio"
+)
+
+func TestJSONRPCBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var calls []struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params"`
+			ID      int             `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&calls); err != nil {
+			t.Errorf("Expected a JSON array of calls, got %v", err)
+			return
+		}
+		if len(calls) != 2 || calls[0].JSONRPC != "2.0" || calls[0].Method != "sum" || calls[1].Method != "subtract" {
+			t.Errorf("Unexpected calls: %+v", calls)
+			return
+		}
+
+		// Replies are sent in reverse order to check matching by ID
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[
+			{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params"}, "id": %d},
+			{"jsonrpc": "2.0", "result": 7, "id": %d}
+		]`, calls[1].ID, calls[0].ID)
+	}))
+	defer server.Close()
+
+	results, err := httpio.New().
+		WithBaseURL(server.URL).
+		NewBatch(httpio.BatchJSONRPC, "/rpc").
+		Call("sum", []int{3, 4}).
+		Call("subtract", map[string]int{"minuend": 1}).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	var sum int
+	if err := results[0].Decode(&sum); err != nil || sum != 7 {
+		t.Errorf("Expected sum 7, got %d (%v)", sum, err)
+	}
+
+	var rpcErr *httpio.JSONRPCError
+	if !errors.As(results[1].Err, &rpcErr) || rpcErr.Code != -32602 {
+		t.Errorf("Expected JSON-RPC error -32602, got %v", results[1].Err)
+	}
+}
+
+func TestODataBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		reader := multipart.NewReader(r.Body, params["boundary"])
+
+		out := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+out.Boundary())
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			sub, err := http.ReadRequest(bufio.NewReader(part))
+			if err != nil {
+				t.Errorf("Expected an application/http part, got %v", err)
+				return
+			}
+			body, _ := io.ReadAll(sub.Body)
+
+			response, _ := out.CreatePart(map[string][]string{"Content-Type": {"application/http"}})
+			if sub.Method == http.MethodGet {
+				fmt.Fprintf(response, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"path\":%q}", sub.URL.Path)
+			} else {
+				fmt.Fprintf(response, "HTTP/1.1 201 Created\r\nContent-Type: application/json\r\n\r\n%s", body)
+			}
+		}
+		out.Close()
+	}))
+	defer server.Close()
+
+	results, err := httpio.New().
+		WithBaseURL(server.URL).
+		NewBatch(httpio.BatchOData, "/$batch").
+		Add(http.MethodGet, "/People('alice')", nil).
+		Add(http.MethodPost, "/People", map[string]string{"name": "bob"}).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].StatusCode != http.StatusOK || string(results[0].Body) != `{"path":"/People('alice')"}` {
+		t.Errorf("Unexpected first result: %d %s", results[0].StatusCode, results[0].Body)
+	}
+	if results[1].StatusCode != http.StatusCreated || string(results[1].Body) != `{"name":"bob"}` {
+		t.Errorf("Unexpected second result: %d %s", results[1].StatusCode, results[1].Body)
+	}
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/anggasct/httpio/internal/brotli"
+)
+
+// brotliVector is a stream in testdata/brotli with the size and SHA-256 of its content
+type brotliVector struct {
+	name   string
+	size   int
+	sha256 string
+	data   []byte
+}
+
+// loadBrotliVectors reads the streams listed in testdata/brotli/vectors.txt. They were
+// produced by the reference encoder (libbrotli 1.0.9) from empty, tiny, repetitive,
+// random and UTF-8 inputs, at every quality, with window sizes from 10 to 24 bits and
+// in the generic, text and font modes.
+func loadBrotliVectors(t testing.TB) []brotliVector {
+	manifest, err := os.Open(filepath.Join("testdata", "brotli", "vectors.txt"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer manifest.Close()
+
+	var vectors []brotliVector
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			t.Fatalf("Expected name, size and hash, got %q", scanner.Text())
+		}
+		size, _ := strconv.Atoi(fields[1])
+		data, err := os.ReadFile(filepath.Join("testdata", "brotli", fields[0]+".br"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		vectors = append(vectors, brotliVector{name: fields[0], size: size, sha256: fields[2], data: data})
+	}
+	return vectors
+}
+
+// checkBrotliOutput compares decoded output with the size and hash of a vector
+func checkBrotliOutput(t *testing.T, vector brotliVector, output []byte, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sum := sha256.Sum256(output)
+	if len(output) != vector.size || hex.EncodeToString(sum[:]) != vector.sha256 {
+		t.Errorf("Expected %d bytes with SHA-256 %s, got %d bytes with %x", vector.size, vector.sha256, len(output), sum)
+	}
+}
+
+func TestBrotliReaderVectors(t *testing.T) {
+	vectors := loadBrotliVectors(t)
+	if len(vectors) == 0 {
+		t.Fatal("Expected test vectors")
+	}
+
+	for _, vector := range vectors {
+		t.Run(vector.name, func(t *testing.T) {
+			output, err := io.ReadAll(brotli.NewReader(bytes.NewReader(vector.data)))
+			checkBrotliOutput(t, vector, output, err)
+
+			// Input and output a byte at a time exercise every refill and resumption point
+			reader := brotli.NewReader(iotest.OneByteReader(bytes.NewReader(vector.data)))
+			output, err = io.ReadAll(iotest.OneByteReader(reader))
+			checkBrotliOutput(t, vector, output, err)
+		})
+	}
+}
+
+func TestBrotliReaderTruncated(t *testing.T) {
+	for _, vector := range loadBrotliVectors(t) {
+		for _, cut := range []int{0, 1, len(vector.data) / 2, len(vector.data) - 1} {
+			if cut >= len(vector.data) {
+				continue
+			}
+			_, err := io.ReadAll(brotli.NewReader(bytes.NewReader(vector.data[:cut])))
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("Expected io.ErrUnexpectedEOF for %s cut at %d bytes, got %v", vector.name, cut, err)
+			}
+		}
+	}
+}
+
+func TestBrotliReaderCorrupt(t *testing.T) {
+	for _, vector := range loadBrotliVectors(t) {
+		for i := 0; i < len(vector.data); i += max(1, len(vector.data)/64) {
+			corrupted := bytes.Clone(vector.data)
+			corrupted[i] ^= 0x5a
+			// Any outcome but a panic or a hang is acceptable, though most flips are detected
+			io.Copy(io.Discard, io.LimitReader(brotli.NewReader(bytes.NewReader(corrupted)), 16<<20))
+		}
+	}
+}
+
+func TestBrotliReaderRejectsInvalidStreams(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		// Large window streams are an extension outside RFC 7932
+		{"large window", []byte{0x11, 0x00}},
+		// Padding after an empty last meta-block must be zero
+		{"non-zero padding", []byte{0x06 | 0x80}},
+		// A simple prefix code listing the same symbol twice
+		{"duplicate symbol", []byte{0x62, 0x00, 0x00, 0x00, 0x54, 0x50, 0x10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := io.ReadAll(brotli.NewReader(bytes.NewReader(tt.data)))
+			if err == nil {
+				t.Error("Expected an error for an invalid stream")
+			}
+		})
+	}
+}
+
+func TestBrotliReaderClose(t *testing.T) {
+	vectors := loadBrotliVectors(t)
+	reader := brotli.NewReader(bytes.NewReader(vectors[len(vectors)-1].data))
+	if _, err := reader.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	reader.Close()
+	if _, err := reader.Read(make([]byte, 16)); !errors.Is(err, brotli.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// FuzzReader checks that arbitrary input never panics or hangs the decoder, and that
+// the output does not depend on how the input and output are split into reads
+func FuzzReader(f *testing.F) {
+	for _, vector := range loadBrotliVectors(f) {
+		if len(vector.data) <= 4<<10 {
+			f.Add(vector.data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		const limit = 4 << 20
+		whole, wholeErr := io.ReadAll(io.LimitReader(brotli.NewReader(bytes.NewReader(data)), limit))
+
+		reader := brotli.NewReader(iotest.OneByteReader(bytes.NewReader(data)))
+		pieces, piecesErr := io.ReadAll(io.LimitReader(iotest.OneByteReader(reader), limit))
+
+		if !bytes.Equal(whole, pieces) {
+			t.Errorf("Expected the same output for both read patterns, got %d and %d bytes", len(whole), len(pieces))
+		}
+		if (wholeErr == nil) != (piecesErr == nil) {
+			t.Errorf("Expected the same outcome for both read patterns, got %v and %v", wholeErr, piecesErr)
+		}
+	})
+}
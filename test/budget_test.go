@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/budget"
+)
+
+func TestBudgetShortCircuitsNearlyExpiredContext(t *testing.T) {
+	m := budget.New(&budget.Config{MinRemaining: 100 * time.Millisecond})
+
+	called := false
+	handler := m.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, err := handler(ctx, req)
+
+	if !errors.Is(err, budget.ErrInsufficientBudget) {
+		t.Fatalf("Expected ErrInsufficientBudget, got %v", err)
+	}
+
+	var budgetErr *budget.Error
+	if !errors.As(err, &budgetErr) || budgetErr.MinRemaining != 100*time.Millisecond {
+		t.Errorf("Expected *budget.Error with MinRemaining 100ms, got %v", err)
+	}
+
+	if called {
+		t.Error("Expected next handler not to be called")
+	}
+}
+
+func TestBudgetAllowsSufficientBudget(t *testing.T) {
+	m := budget.New(&budget.Config{
+		MinRemaining:   10 * time.Millisecond,
+		DeadlineHeader: "X-Request-Timeout-Ms",
+	})
+
+	var header string
+	handler := m.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		header = req.Header.Get("X-Request-Timeout-Ms")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := handler(ctx, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ms, err := strconv.Atoi(header)
+	if err != nil || ms <= 0 || ms > 1000 {
+		t.Errorf("Expected remaining budget header in (0, 1000], got %q", header)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Errorf("Expected context without deadline to pass through, got %v", err)
+	}
+}
+
+func TestBudgetDeadlineHeaderPerSendOfReusedRequest(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-Request-Timeout-Ms"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(budget.New(&budget.Config{
+		DeadlineHeader: "X-Request-Timeout-Ms",
+	}))
+	req := client.NewRequest("GET", "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := req.Do(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	// Sent again without a deadline, the request carries no stale budget
+	resp, err = req.Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if len(headers) != 2 || headers[0] == "" || headers[1] != "" {
+		t.Errorf("Expected a budget on the first send only, got %q", headers)
+	}
+	if got := req.Headers.Get("X-Request-Timeout-Ms"); got != "" {
+		t.Errorf("Expected the request headers to be left untouched, got %q", got)
+	}
+}
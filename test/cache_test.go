@@ -2,9 +2,13 @@ package test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -169,6 +173,44 @@ func TestCacheMiddlewareWithNonCacheableStatus(t *testing.T) {
 	}
 }
 
+func TestCacheMiddlewareCacheableStatusCodes(t *testing.T) {
+	config := cache.DefaultConfig()
+	config.CacheableStatusCodes = map[int]bool{http.StatusOK: true}
+
+	callCount := 0
+	handler := cache.NewMiddleware(cache.NewMemoryCache(100), config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		callCount++
+		status := http.StatusOK
+		if req.URL.Path == "/missing" {
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader("body")),
+		}, nil
+	})
+
+	for _, path := range []string{"/missing", "/missing", "/found", "/found"} {
+		req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+		resp, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+		// Let the cache store the response in the background
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Both 404s reach the handler, the second 200 is served from the cache
+	if callCount != 3 {
+		t.Errorf("Expected 3 handler calls, got %d", callCount)
+	}
+	if !cache.CacheableStatus[http.StatusNotFound] {
+		t.Error("Expected the package-wide CacheableStatus to be left untouched")
+	}
+}
+
 func TestCacheMiddlewareWithPOSTRequest(t *testing.T) {
 	mockCache := newMockCache()
 	config := cache.DefaultConfig()
@@ -359,3 +401,442 @@ func TestCacheKeyStrategies(t *testing.T) {
 		t.Error("Expected different keys for different strategies")
 	}
 }
+
+func TestCacheMiddlewareCoalescesConcurrentMisses(t *testing.T) {
+	var originHits int32
+	memCache := cache.NewMemoryCache(100)
+	cacheMiddleware := cache.NewMiddleware(memCache, cache.DefaultConfig())
+
+	handler := cacheMiddleware.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&originHits, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("origin body")),
+		}, nil
+	})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	bodies := make([]string, callers)
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			req, _ := http.NewRequest("GET", "http://example.com/cold", nil)
+			resp, err := handler(context.Background(), req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			bodies[i], errs[i] = string(body), err
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if hits := atomic.LoadInt32(&originHits); hits != 1 {
+		t.Errorf("Expected a single origin hit, got %d", hits)
+	}
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Errorf("Caller %d: expected no error, got %v", i, errs[i])
+		}
+		if bodies[i] != "origin body" {
+			t.Errorf("Caller %d: expected origin body, got %q", i, bodies[i])
+		}
+	}
+}
+
+// slowSetCache is a memory cache whose Set blocks until unblocked
+type slowSetCache struct {
+	*cache.MemoryCache
+	unblock chan struct{}
+}
+
+func (c *slowSetCache) Set(ctx context.Context, key string, response *cache.CachedResponse) error {
+	<-c.unblock
+	return c.MemoryCache.Set(ctx, key, response)
+}
+
+func TestCacheMiddlewareServesMissesWhileStoring(t *testing.T) {
+	var originHits int32
+	slowCache := &slowSetCache{MemoryCache: cache.NewMemoryCache(100), unblock: make(chan struct{})}
+	cacheMiddleware := cache.NewMiddleware(slowCache, cache.DefaultConfig())
+
+	handler := cacheMiddleware.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&originHits, 1)
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("origin body")),
+		}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com/slow-store", nil)
+		resp, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "origin body" {
+			t.Errorf("Expected origin body, got %q", body)
+		}
+	}
+	close(slowCache.unblock)
+
+	if hits := atomic.LoadInt32(&originHits); hits != 1 {
+		t.Errorf("Expected a single origin hit while the entry is stored, got %d", hits)
+	}
+}
+
+// recordingCache captures the entries stored by the cache middleware
+type recordingCache struct {
+	mockCache
+	stored chan *cache.CachedResponse
+}
+
+func newRecordingCache() *recordingCache {
+	return &recordingCache{
+		mockCache: mockCache{data: make(map[string]*cache.CachedResponse)},
+		stored:    make(chan *cache.CachedResponse, 10),
+	}
+}
+
+func (r *recordingCache) Set(ctx context.Context, key string, response *cache.CachedResponse) error {
+	r.stored <- response
+	return nil
+}
+
+// storedTTL sends a request with the given Cache-Control header through the middleware
+// and returns the time-to-live of the resulting cache entry
+func storedTTL(t *testing.T, config *cache.Config, cacheControl string) time.Duration {
+	t.Helper()
+	return storedTTLWithHeaders(t, config, http.Header{"Cache-Control": []string{cacheControl}})
+}
+
+// storedTTLWithHeaders sends a request whose response carries the given headers through
+// the middleware and returns the time-to-live of the resulting cache entry
+func storedTTLWithHeaders(t *testing.T, config *cache.Config, header http.Header) time.Duration {
+	t.Helper()
+	return storedTTLForStatus(t, config, 200, header)
+}
+
+// storedTTLForStatus sends a request whose response has the given status code and headers
+// through the middleware and returns the time-to-live of the resulting cache entry
+func storedTTLForStatus(t *testing.T, config *cache.Config, status int, header http.Header) time.Duration {
+	t.Helper()
+
+	recorder := newRecordingCache()
+	handler := cache.NewMiddleware(recorder, config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("body")),
+		}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/ttl", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case entry := <-recorder.stored:
+		return time.Until(entry.ExpiresAt)
+	case <-time.After(time.Second):
+		t.Fatal("Expected response to be cached")
+		return 0
+	}
+}
+
+func TestCacheMinTTLFloorsTinyMaxAge(t *testing.T) {
+	config := cache.DefaultConfig().WithMinTTL(time.Minute)
+
+	ttl := storedTTL(t, config, "max-age=1")
+	if ttl < 59*time.Second || ttl > time.Minute {
+		t.Errorf("Expected TTL floored to 1m, got %v", ttl)
+	}
+}
+
+func TestCacheMaxTTLCapsHugeMaxAge(t *testing.T) {
+	config := cache.DefaultConfig().WithMaxTTL(time.Hour)
+
+	ttl := storedTTL(t, config, "max-age=315360000")
+	if ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Expected TTL capped to 1h, got %v", ttl)
+	}
+}
+
+func TestCacheTTLWithinBoundsUnchanged(t *testing.T) {
+	config := cache.DefaultConfig().WithMinTTL(time.Second).WithMaxTTL(time.Hour)
+
+	ttl := storedTTL(t, config, "max-age=120")
+	if ttl < 119*time.Second || ttl > 120*time.Second {
+		t.Errorf("Expected server max-age of 2m to be kept, got %v", ttl)
+	}
+}
+
+func TestCacheHeuristicFreshness(t *testing.T) {
+	config := cache.DefaultConfig().WithHeuristicFreshness(true)
+
+	date := time.Now().UTC().Truncate(time.Second)
+	header := http.Header{
+		"Date":          []string{date.Format(http.TimeFormat)},
+		"Last-Modified": []string{date.Add(-10 * time.Hour).Format(http.TimeFormat)},
+	}
+
+	ttl := storedTTLWithHeaders(t, config, header)
+	if ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Expected heuristic TTL of 1h (10%% of 10h), got %v", ttl)
+	}
+
+	header.Set("Cache-Control", "max-age=120")
+	ttl = storedTTLWithHeaders(t, config, header)
+	if ttl < 119*time.Second || ttl > 120*time.Second {
+		t.Errorf("Expected explicit max-age of 2m to win over the heuristic, got %v", ttl)
+	}
+}
+
+func TestCacheHeuristicFreshnessDisabledUsesDefaultTTL(t *testing.T) {
+	config := cache.DefaultConfig().WithDefaultTTL(5 * time.Minute)
+
+	header := http.Header{
+		"Last-Modified": []string{time.Now().Add(-100 * time.Hour).UTC().Format(http.TimeFormat)},
+	}
+
+	ttl := storedTTLWithHeaders(t, config, header)
+	if ttl < 299*time.Second || ttl > 5*time.Minute {
+		t.Errorf("Expected default TTL of 5m, got %v", ttl)
+	}
+}
+
+func TestCacheStatusTTL(t *testing.T) {
+	config := cache.DefaultConfig().
+		WithDefaultTTL(10*time.Minute).
+		WithStatusTTL(http.StatusMovedPermanently, 24*time.Hour).
+		WithStatusTTL(http.StatusNotFound, 30*time.Second)
+
+	tests := []struct {
+		status int
+		want   time.Duration
+	}{
+		{http.StatusOK, 10 * time.Minute},
+		{http.StatusMovedPermanently, 24 * time.Hour},
+		{http.StatusNotFound, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		ttl := storedTTLForStatus(t, config, tt.status, http.Header{})
+		if ttl < tt.want-time.Second || ttl > tt.want {
+			t.Errorf("Expected TTL of %v for status %d, got %v", tt.want, tt.status, ttl)
+		}
+	}
+
+	ttl := storedTTLForStatus(t, config, http.StatusNotFound, http.Header{"Cache-Control": []string{"max-age=120"}})
+	if ttl < 119*time.Second || ttl > 120*time.Second {
+		t.Errorf("Expected explicit max-age of 2m to win over the status TTL, got %v", ttl)
+	}
+}
+
+func TestMemoryCacheBytesEvictsLeastRecentlyUsed(t *testing.T) {
+	memCache := cache.NewMemoryCacheBytes(300)
+	ctx := context.Background()
+
+	newEntry := func(size int) *cache.CachedResponse {
+		return &cache.CachedResponse{
+			Response:  &http.Response{StatusCode: 200, Header: make(http.Header)},
+			Body:      []byte(strings.Repeat("x", size)),
+			ExpiresAt: time.Now().Add(5 * time.Minute),
+		}
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := memCache.Set(ctx, key, newEntry(100)); err != nil {
+			t.Fatalf("Expected no error setting %s, got %v", key, err)
+		}
+	}
+
+	if used := memCache.UsedBytes(); used != 300 {
+		t.Fatalf("Expected 300 bytes used, got %d", used)
+	}
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	if _, ok := memCache.Get(ctx, "a"); !ok {
+		t.Fatal("Expected entry a to exist")
+	}
+
+	if err := memCache.Set(ctx, "d", newEntry(100)); err != nil {
+		t.Fatalf("Expected no error setting d, got %v", err)
+	}
+
+	if _, ok := memCache.Get(ctx, "b"); ok {
+		t.Error("Expected least recently used entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := memCache.Get(ctx, key); !ok {
+			t.Errorf("Expected entry %s to be kept", key)
+		}
+	}
+
+	if used := memCache.UsedBytes(); used != 300 {
+		t.Errorf("Expected 300 bytes used after eviction, got %d", used)
+	}
+
+	memCache.Delete(ctx, "a")
+	if used := memCache.UsedBytes(); used != 200 {
+		t.Errorf("Expected 200 bytes used after delete, got %d", used)
+	}
+
+	if err := memCache.Set(ctx, "huge", newEntry(1000)); err == nil {
+		t.Error("Expected error for entry larger than the byte limit")
+	}
+}
+
+func TestMemoryCacheConcurrentExpiredGet(t *testing.T) {
+	memCache := cache.NewMemoryCacheBytes(1000)
+	ctx := context.Background()
+
+	for i := 0; i < 200; i++ {
+		memCache.Set(ctx, "key", &cache.CachedResponse{
+			Response:  &http.Response{StatusCode: 200, Header: make(http.Header)},
+			Body:      []byte(strings.Repeat("x", 100)),
+			ExpiresAt: time.Now().Add(-time.Second),
+		})
+
+		// Every reader finds the expired entry, but only one may remove it
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				memCache.Get(ctx, "key")
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if used := memCache.UsedBytes(); used != 0 {
+			t.Fatalf("Expected 0 bytes used after the expired entry was removed, got %d", used)
+		}
+	}
+}
+
+func TestCacheWarm(t *testing.T) {
+	var originHits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&originHits, 1)
+		w.Write([]byte("warm " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	memCache := cache.NewMemoryCache(100)
+	cacheMiddleware := cache.NewMiddleware(memCache, cache.DefaultConfig().WithWarmConcurrency(2))
+
+	var reqs []*http.Request
+	for _, path := range []string{"/a", "/b"} {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		reqs = append(reqs, req)
+	}
+
+	if err := cacheMiddleware.Warm(context.Background(), reqs); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	atomic.StoreInt32(&originHits, 0)
+
+	handler := cacheMiddleware.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return http.DefaultClient.Do(req)
+	})
+
+	for _, path := range []string{"/a", "/b"} {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != "warm "+path {
+			t.Errorf("Expected cached body %q, got %q", "warm "+path, body)
+		}
+	}
+
+	if hits := atomic.LoadInt32(&originHits); hits != 0 {
+		t.Errorf("Expected warmed requests to be served from cache, got %d origin hits", hits)
+	}
+}
+
+func TestCacheHashedKeys(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/search?q="+strings.Repeat("x", 2048), nil)
+
+	strategy := &cache.MethodURLKeyStrategy{Hash: true}
+	key := strategy.GenerateKey(req)
+	if len(key) != 64 {
+		t.Errorf("Expected a 64 character SHA-256 key, got %d characters", len(key))
+	}
+	if raw := strategy.RawKey(req); raw != "GET:"+req.URL.String() {
+		t.Errorf("Expected raw key to keep the method and URL, got '%s'", raw)
+	}
+	if key != strategy.GenerateKey(req) {
+		t.Error("Expected hashed keys to be stable")
+	}
+
+	other, _ := http.NewRequest("GET", "http://example.com/search?q=y", nil)
+	if key == strategy.GenerateKey(other) {
+		t.Error("Expected different URLs to hash to different keys")
+	}
+}
+
+// benchmarkKeyMemory stores entries for long URLs in a MemoryCache using the given strategy
+// and reports the bytes held by the keys of the 1000 entries
+func benchmarkKeyMemory(b *testing.B, strategy cache.KeyStrategy) {
+	query := strings.Repeat("filter=value&", 200)
+	entry := &cache.CachedResponse{
+		Response:  &http.Response{StatusCode: 200, Header: http.Header{}},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	ctx := context.Background()
+
+	reqs := make([]*http.Request, 1000)
+	for j := range reqs {
+		reqs[j], _ = http.NewRequest("GET", fmt.Sprintf("http://example.com/items/%d?%s", j, query), nil)
+	}
+
+	var keyBytes int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		keyBytes = 0
+		memCache := cache.NewMemoryCache(len(reqs))
+		for _, req := range reqs {
+			key := strategy.GenerateKey(req)
+			keyBytes += len(key)
+			memCache.Set(ctx, key, entry)
+		}
+		memCache.Close()
+	}
+	b.ReportMetric(float64(keyBytes), "keybytes")
+}
+
+func BenchmarkCacheKeysRaw(b *testing.B) {
+	benchmarkKeyMemory(b, &cache.MethodURLKeyStrategy{})
+}
+
+func BenchmarkCacheKeysHashed(b *testing.B) {
+	benchmarkKeyMemory(b, &cache.MethodURLKeyStrategy{Hash: true})
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/charset"
+)
+
+func TestCharsetDecodesLatin1(t *testing.T) {
+	// "café naïve" encoded as ISO-8859-1
+	latin1 := []byte{'c', 'a', 'f', 0xe9, ' ', 'n', 'a', 0xef, 'v', 'e'}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+		w.Write(latin1)
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithBaseURL(server.URL).GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	text, err := charset.String(resp)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if text != "café naïve" {
+		t.Errorf("Expected %q, got %q", "café naïve", text)
+	}
+}
+
+func TestCharsetPassesThroughUTF8(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("héllo"))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithBaseURL(server.URL).GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	text, err := charset.String(resp)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if text != "héllo" {
+		t.Errorf("Expected %q, got %q", "héllo", text)
+	}
+}
+
+func TestCharsetUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=made-up")
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithBaseURL(server.URL).GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := charset.String(resp); err == nil {
+		t.Error("Expected error for unsupported charset")
+	}
+}
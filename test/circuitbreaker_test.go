@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/circuitbreaker"
+)
+
+func TestCircuitBreakerOpensAfterFailures(t *testing.T) {
+	cb := circuitbreaker.New(&circuitbreaker.Config{
+		FailureThreshold: 2,
+		RecoveryTimeout:  time.Minute,
+	})
+
+	handler := cb.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 500}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	for i := 0; i < 2; i++ {
+		if _, err := handler(context.Background(), req); err != nil {
+			t.Fatalf("Expected no error before circuit opens, got %v", err)
+		}
+	}
+
+	if state := cb.GetCircuitBreaker().GetState(); state != circuitbreaker.StateOpen {
+		t.Fatalf("Expected circuit to be open, got %s", state)
+	}
+
+	if _, err := handler(context.Background(), req); err == nil {
+		t.Error("Expected request to be rejected by open circuit")
+	}
+}
+
+func TestCircuitBreakerBypass(t *testing.T) {
+	cb := circuitbreaker.New(&circuitbreaker.Config{
+		FailureThreshold: 1,
+		RecoveryTimeout:  time.Minute,
+	})
+
+	healthy := false
+	calls := 0
+	handler := cb.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		calls++
+		if healthy {
+			return &http.Response{StatusCode: 200}, nil
+		}
+		return &http.Response{StatusCode: 500}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/health", nil)
+	handler(context.Background(), req)
+
+	if state := cb.GetCircuitBreaker().GetState(); state != circuitbreaker.StateOpen {
+		t.Fatalf("Expected circuit to be open, got %s", state)
+	}
+
+	healthy = true
+	resp, err := handler(circuitbreaker.Bypass(context.Background()), req)
+	if err != nil {
+		t.Fatalf("Expected bypassed request to succeed, got %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if calls != 2 {
+		t.Errorf("Expected bypassed request to reach the handler, got %d calls", calls)
+	}
+
+	if state := cb.GetCircuitBreaker().GetState(); state != circuitbreaker.StateOpen {
+		t.Errorf("Expected bypassed outcome not to be recorded, circuit is %s", state)
+	}
+
+	if _, err := handler(context.Background(), req); err == nil {
+		t.Error("Expected non-bypassed request to still be rejected")
+	}
+}
+
+func TestCircuitBreakerPerHostAllStats(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	config := circuitbreaker.DefaultConfig()
+	config.FailureThreshold = 2
+	config.PerHost = true
+	cb := circuitbreaker.New(config)
+	client := httpio.New().WithMiddleware(cb)
+
+	for i := 0; i < 3; i++ {
+		if resp, err := client.GET(context.Background(), failing.URL); err == nil {
+			resp.Close()
+		}
+		resp, err := client.GET(context.Background(), healthy.URL)
+		if err != nil {
+			t.Fatalf("Expected the healthy host not to be affected, got %v", err)
+		}
+		resp.Close()
+	}
+
+	stats := cb.AllStats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 hosts, got %d", len(stats))
+	}
+
+	failingStats := stats[strings.TrimPrefix(failing.URL, "http://")]
+	if failingStats.State != circuitbreaker.StateOpen {
+		t.Errorf("Expected failing host to be open, got %s", failingStats.State)
+	}
+	if failingStats.Requests != 2 || failingStats.Failures != 2 || failingStats.Rejected != 1 {
+		t.Errorf("Expected 2 requests, 2 failures and 1 rejection, got %+v", failingStats)
+	}
+
+	healthyStats := stats[strings.TrimPrefix(healthy.URL, "http://")]
+	if healthyStats.State != circuitbreaker.StateClosed {
+		t.Errorf("Expected healthy host to be closed, got %s", healthyStats.State)
+	}
+	if healthyStats.Requests != 3 || healthyStats.Failures != 0 || healthyStats.Rejected != 0 {
+		t.Errorf("Expected 3 successful requests, got %+v", healthyStats)
+	}
+}
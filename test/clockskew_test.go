@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/clockskew"
+)
+
+func TestClockSkewDetectsSkewedServerDate(t *testing.T) {
+	var requestDate string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestDate = r.Header.Get("Date")
+		w.Header().Set("Date", time.Now().Add(5*time.Minute).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var skews []time.Duration
+	config := clockskew.DefaultConfig()
+	config.OnSkew = func(req *http.Request, skew time.Duration) {
+		skews = append(skews, skew)
+	}
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(clockskew.New(config))
+
+	resp, err := client.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if _, err := http.ParseTime(requestDate); err != nil {
+		t.Errorf("Expected a valid request Date header, got %q", requestDate)
+	}
+
+	if len(skews) != 1 {
+		t.Fatalf("Expected the skew callback to fire once, got %d", len(skews))
+	}
+	if skews[0] < 4*time.Minute || skews[0] > 6*time.Minute {
+		t.Errorf("Expected a skew of about 5m, got %v", skews[0])
+	}
+}
+
+func TestClockSkewIgnoresAccurateServerDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	fired := false
+	config := clockskew.DefaultConfig()
+	config.OnSkew = func(req *http.Request, skew time.Duration) {
+		fired = true
+	}
+
+	resp, err := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(clockskew.New(config)).
+		GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if fired {
+		t.Error("Expected no skew to be reported")
+	}
+}
+
+func TestClockSkewDatePerSendOfReusedRequest(t *testing.T) {
+	var dates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dates = append(dates, r.Header.Get("Date"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	config := clockskew.DefaultConfig()
+	config.Now = func() time.Time { return now }
+
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(clockskew.New(config))
+	req := client.NewRequest("GET", "/")
+	for i := 0; i < 2; i++ {
+		resp, err := req.Do(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+		now = now.Add(time.Minute)
+	}
+
+	if len(dates) != 2 || dates[0] != "Mon, 01 Jan 2024 12:00:00 GMT" || dates[1] != "Mon, 01 Jan 2024 12:01:00 GMT" {
+		t.Errorf("Expected a fresh Date for each send, got %q", dates)
+	}
+	if got := req.Headers.Get("Date"); got != "" {
+		t.Errorf("Expected the request headers to be left untouched, got Date %q", got)
+	}
+}
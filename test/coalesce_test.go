@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/coalesce"
+)
+
+func TestCoalesceIdenticalGetsWithinWindow(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("result"))
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(coalesce.New(&coalesce.Config{Window: 500 * time.Millisecond}))
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	bodies := make([]string, 3)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.GET(ctx, "/search?q=go")
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			bodies[i], _ = resp.String()
+		}(i)
+	}
+	wg.Wait()
+
+	// The third request arrives after the first completed, but within the window
+	resp, err := client.GET(ctx, "/search?q=go")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	bodies[2], _ = resp.String()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("Expected 1 origin hit, got %d", got)
+	}
+	for i, body := range bodies {
+		if body != "result" {
+			t.Errorf("Expected body 'result' for call %d, got '%s'", i, body)
+		}
+	}
+
+	if _, err := client.GET(ctx, "/search?q=other"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("Expected a different URL to reach the origin, got %d hits", got)
+	}
+}
+
+func TestCoalesceWindowExpires(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(coalesce.New(&coalesce.Config{Window: 20 * time.Millisecond}))
+
+	ctx := context.Background()
+	client.GET(ctx, "/")
+	time.Sleep(50 * time.Millisecond)
+	client.GET(ctx, "/")
+
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("Expected 2 origin hits after the window expired, got %d", got)
+	}
+}
+
+func TestCoalesceWaitersRetryAfterLeaderCanceled(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("result"))
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(coalesce.New(&coalesce.Config{Window: 500 * time.Millisecond}))
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := client.GET(leaderCtx, "/search?q=go")
+		leaderDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	waiterDone := make(chan string, 1)
+	go func() {
+		resp, err := client.GET(context.Background(), "/search?q=go")
+		if err != nil {
+			t.Errorf("Expected no error for the waiter, got %v", err)
+			waiterDone <- ""
+			return
+		}
+		body, _ := resp.String()
+		waiterDone <- body
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the leader to be canceled, got %v", err)
+	}
+	if body := <-waiterDone; body != "result" {
+		t.Errorf("Expected the waiter to get its own result, got '%s'", body)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("Expected 2 origin hits, got %d", got)
+	}
+}
+
+func TestCoalescePassesThroughStreams(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(coalesce.New(&coalesce.Config{Window: 500 * time.Millisecond}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := client.GET(ctx, "/events")
+	if err != nil {
+		t.Fatalf("Expected the stream to be returned before it ends, got %v", err)
+	}
+	defer resp.Close()
+	defer close(release)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: hello\n" {
+		t.Errorf("Expected the first event, got %q (%v)", line, err)
+	}
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio/middleware/concurrency"
+)
+
+func TestConcurrencyStaticLimit(t *testing.T) {
+	config := concurrency.DefaultConfig()
+	config.Limit = 2
+	limiter := concurrency.New(config)
+
+	var inFlight, maxInFlight int32
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			if _, err := handler(context.Background(), req); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+	if limiter.InFlight() != 0 {
+		t.Errorf("Expected no requests left in flight, got %d", limiter.InFlight())
+	}
+}
+
+func TestConcurrencyAdaptiveLimitDecreasesOnLatency(t *testing.T) {
+	config := concurrency.DefaultConfig()
+	config.Limit = 10
+	config.Adaptive = true
+	config.LatencyTolerance = 5
+	limiter := concurrency.New(config)
+
+	var latency atomic.Int64
+	latency.Store(int64(2 * time.Millisecond))
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		time.Sleep(time.Duration(latency.Load()))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			handler(context.Background(), req)
+		}
+	}
+
+	send(30)
+	grown := limiter.Limit()
+	if grown <= 10 {
+		t.Fatalf("Expected the limit to grow with healthy responses, got %d", grown)
+	}
+
+	latency.Store(int64(50 * time.Millisecond))
+	send(3)
+	if limit := limiter.Limit(); limit >= grown/2 {
+		t.Errorf("Expected the limit to shrink after latency increases, got %d (was %d)", limit, grown)
+	}
+
+	// Failures shrink the limit as well
+	failing := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	before := limiter.Limit()
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	failing(context.Background(), req)
+	if limit := limiter.Limit(); limit >= before && before > 1 {
+		t.Errorf("Expected the limit to shrink after a failure, got %d (was %d)", limit, before)
+	}
+}
+
+func TestConcurrencyAdaptiveLimitIgnoresFastOutlier(t *testing.T) {
+	config := concurrency.DefaultConfig()
+	config.Limit = 10
+	config.Adaptive = true
+	limiter := concurrency.New(config)
+
+	var latency atomic.Int64
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		time.Sleep(time.Duration(latency.Load()))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	send := func(n int, d time.Duration) {
+		latency.Store(int64(d))
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			handler(context.Background(), req)
+		}
+	}
+
+	send(10, 10*time.Millisecond)
+	before := limiter.Limit()
+
+	// A single quick answer, e.g. a cached 404, must not make normal latencies look like spikes
+	send(1, 0)
+	send(10, 10*time.Millisecond)
+	if limit := limiter.Limit(); limit < before {
+		t.Errorf("Expected the limit to hold after a fast outlier, got %d (was %d)", limit, before)
+	}
+}
+
+func TestConcurrencyWaitHonorsContext(t *testing.T) {
+	config := concurrency.DefaultConfig()
+	config.Limit = 1
+	limiter := concurrency.New(config)
+
+	release := make(chan struct{})
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	go handler(context.Background(), req)
+	for limiter.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := handler(ctx, req); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded while waiting for a slot, got %v", err)
+	}
+	close(release)
+}
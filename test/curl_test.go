@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anggasct/httpio"
+)
+
+func TestFromCurl(t *testing.T) {
+	command := `curl -X PUT "https://api.example.com/v1/items/42" \
+  -H 'Content-Type: application/json' \
+  -H "Authorization: Bearer abc123" \
+  -d '{"name": "widget", "note": "it'\''s \"new\""}'`
+
+	req, err := httpio.New().FromCurl(command)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if req.Method != http.MethodPut {
+		t.Errorf("Expected method PUT, got %s", req.Method)
+	}
+	if req.URL != "https://api.example.com/v1/items/42" {
+		t.Errorf("Expected URL to be kept, got %s", req.URL)
+	}
+	if got := req.Headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", got)
+	}
+	if got := req.Headers.Get("Authorization"); got != "Bearer abc123" {
+		t.Errorf("Expected Authorization header, got %q", got)
+	}
+	expectedBody := `{"name": "widget", "note": "it's \"new\""}`
+	if req.Body != expectedBody {
+		t.Errorf("Expected body %s, got %v", expectedBody, req.Body)
+	}
+}
+
+func TestFromCurlDefaultsAndErrors(t *testing.T) {
+	req, err := httpio.New().FromCurl(`curl -s https://api.example.com/login -d user=alice -d pass=secret`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Method != http.MethodPost {
+		t.Errorf("Expected data to imply POST, got %s", req.Method)
+	}
+	if got := req.Headers.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected form Content-Type, got %q", got)
+	}
+	if req.Body != "user=alice&pass=secret" {
+		t.Errorf("Expected joined data, got %v", req.Body)
+	}
+
+	req, err = httpio.New().FromCurl(`curl https://api.example.com --data-raw @handle`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Body != "@handle" {
+		t.Errorf("Expected --data-raw to be sent literally, got %v", req.Body)
+	}
+
+	for _, command := range []string{
+		`curl --insecure https://api.example.com`,
+		`curl -H "Accept: */*"`,
+		`curl 'https://api.example.com`,
+		`curl https://api.example.com -d @payload.json`,
+		`curl https://api.example.com --data-binary @-`,
+	} {
+		if _, err := httpio.New().FromCurl(command); err == nil {
+			t.Errorf("Expected an error for %s", command)
+		}
+	}
+}
+
+func TestFromCurlRelativeURL(t *testing.T) {
+	var method, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method + " " + r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, err := httpio.New().WithBaseURL(server.URL).FromCurl(`curl -X PATCH /items/1 --data-raw '{"done":true}'`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp, err := req.Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if method != "PATCH /items/1" {
+		t.Errorf("Expected PATCH /items/1, got %s", method)
+	}
+	if body != `{"done":true}` {
+		t.Errorf("Expected body to be sent, got %q", body)
+	}
+}
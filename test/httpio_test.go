@@ -1,13 +1,23 @@
 package test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware"
 )
 
 func TestNew(t *testing.T) {
@@ -116,6 +126,30 @@ func TestWithTimeout(t *testing.T) {
 	}
 }
 
+func TestDefaultTimeout(t *testing.T) {
+	client := httpio.New()
+	if client.Timeout() != httpio.DefaultTimeout {
+		t.Errorf("Expected default timeout %v, got %v", httpio.DefaultTimeout, client.Timeout())
+	}
+
+	client.WithTimeout(0)
+	if client.Timeout() != 0 {
+		t.Errorf("Expected WithTimeout(0) to disable the timeout, got %v", client.Timeout())
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := client.WithBaseURL(server.URL).GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected unbounded client to succeed, got %v", err)
+	}
+	resp.Close()
+}
+
 func TestWithConnectionPool(t *testing.T) {
 	client := httpio.New()
 
@@ -126,6 +160,37 @@ func TestWithConnectionPool(t *testing.T) {
 	}
 }
 
+func TestTransportSettingsMerge(t *testing.T) {
+	custom := &http.Transport{WriteBufferSize: 8192}
+
+	client := httpio.New().
+		WithTransport(custom).
+		ConfigureTransport(func(transport *http.Transport) {
+			transport.ForceAttemptHTTP2 = true
+		}).
+		WithConnectionPool(10, 5, 5, 30*time.Second).
+		WithResponseHeaderTimeout(time.Second)
+
+	var configured *http.Transport
+	client.ConfigureTransport(func(transport *http.Transport) {
+		configured = transport
+	})
+
+	if configured != custom {
+		t.Fatal("Expected options to modify the custom transport in place")
+	}
+	if configured.WriteBufferSize != 8192 || !configured.ForceAttemptHTTP2 {
+		t.Errorf("Expected custom transport fields to survive, got WriteBufferSize=%d ForceAttemptHTTP2=%v",
+			configured.WriteBufferSize, configured.ForceAttemptHTTP2)
+	}
+	if configured.MaxIdleConns != 10 || configured.MaxConnsPerHost != 5 || configured.IdleConnTimeout != 30*time.Second {
+		t.Errorf("Expected connection pool settings to be applied, got %+v", configured)
+	}
+	if configured.ResponseHeaderTimeout != time.Second {
+		t.Errorf("Expected response header timeout to be applied, got %v", configured.ResponseHeaderTimeout)
+	}
+}
+
 func TestGET(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -308,3 +373,601 @@ func TestNewRequest(t *testing.T) {
 		t.Errorf("Expected URL https://api.example.com/test, got %s", req.URL)
 	}
 }
+
+func TestWithPathPrefix(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		prefix  string
+		path    string
+		want    string
+	}{
+		{"https://api.example.com", "/service-a", "/users", "https://api.example.com/service-a/users"},
+		{"https://api.example.com/", "service-a/", "users", "https://api.example.com/service-a/users"},
+		{"https://api.example.com//", "//service-a//", "//users?page=2", "https://api.example.com/service-a/users?page=2"},
+		{"", "/service-a", "/users", "/service-a/users"},
+	}
+
+	for _, tt := range tests {
+		client := httpio.New().WithBaseURL(tt.baseURL).WithPathPrefix(tt.prefix)
+		req := client.NewRequest("GET", tt.path)
+		if req.URL != tt.want {
+			t.Errorf("Expected URL %s for base %q, prefix %q and path %q, got %s", tt.want, tt.baseURL, tt.prefix, tt.path, req.URL)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service-a/users" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithBaseURL(server.URL).WithPathPrefix("/service-a").GET(context.Background(), "/users")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected prefixed path to reach the server, got status %d", resp.StatusCode)
+	}
+}
+
+func TestSendReusesConnections(t *testing.T) {
+	var newConns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(strings.Repeat("ignored body ", 100)))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL)
+
+	for i := 0; i < 50; i++ {
+		status, err := client.Send(context.Background(), "POST", "/webhook", map[string]int{"n": i})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if status != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d", status)
+		}
+	}
+
+	if n := atomic.LoadInt32(&newConns); n != 1 {
+		t.Errorf("Expected all Send calls to share 1 connection, got %d connections", n)
+	}
+}
+
+func TestStat(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected method HEAD, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/files/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Length", "2048")
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL)
+
+	info, err := client.Stat(context.Background(), "/files/report.pdf")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !info.Exists {
+		t.Error("Expected resource to exist")
+	}
+	if info.ContentLength != 2048 {
+		t.Errorf("Expected ContentLength 2048, got %d", info.ContentLength)
+	}
+	if info.ContentType != "application/pdf" {
+		t.Errorf("Expected ContentType application/pdf, got %s", info.ContentType)
+	}
+	if info.ETag != `"v1"` {
+		t.Errorf("Expected ETag \"v1\", got %s", info.ETag)
+	}
+	if !info.LastModified.Equal(lastModified) {
+		t.Errorf("Expected LastModified %v, got %v", lastModified, info.LastModified)
+	}
+
+	info, err = client.Stat(context.Background(), "/missing")
+	if err != nil {
+		t.Fatalf("Expected no error for a missing resource, got %v", err)
+	}
+	if info.Exists {
+		t.Error("Expected missing resource not to exist")
+	}
+
+	var statusErr *httpio.StatusError
+	if _, err := client.Stat(context.Background(), "/forbidden"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected *StatusError with status 403, got %v", err)
+	}
+}
+
+func TestPoolStatsCountsReusedConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL)
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.GET(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Consume()
+	}
+
+	stats := client.PoolStats()
+	if stats.Requests != 5 {
+		t.Errorf("Expected 5 requests, got %d", stats.Requests)
+	}
+	if stats.New != 1 {
+		t.Errorf("Expected 1 new connection, got %d", stats.New)
+	}
+	if stats.Reused != 4 {
+		t.Errorf("Expected 4 reused connections, got %d", stats.Reused)
+	}
+	if stats.Idle != 4 {
+		t.Errorf("Expected 4 connections taken from the idle pool, got %d", stats.Idle)
+	}
+	if stats.MaxWait <= 0 || stats.AverageWait() > stats.MaxWait {
+		t.Errorf("Expected consistent wait times, got average %v and max %v", stats.AverageWait(), stats.MaxWait)
+	}
+}
+
+func TestWithDefaultContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			return
+		}
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	defaultCtx, cancel := context.WithCancel(context.Background())
+	client := httpio.New().WithBaseURL(server.URL).WithDefaultContext(defaultCtx)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	// A nil context selects the default context
+	_, err := client.GET(nil, "/")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancelling the default context to abort the request, took %v", elapsed)
+	}
+
+	// An explicit context wins over the cancelled default context
+	resp, err := client.GET(context.Background(), "/fast")
+	if err != nil {
+		t.Fatalf("Expected an explicit context to be used, got %v", err)
+	}
+	resp.Close()
+}
+
+func TestWithSafeRetriesOnConnectionReset(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Failed to hijack connection: %v", err)
+				return
+			}
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.SetLinger(0)
+			}
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithSafeRetries(2)
+
+	resp, err := client.GET(context.Background(), "/reset")
+	if err != nil {
+		t.Fatalf("Expected transparent retry to succeed, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestWithoutSafeRetriesConnectionResetFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL)
+
+	if _, err := client.GET(context.Background(), "/reset"); err == nil {
+		t.Fatal("Expected error without safe retries")
+	}
+}
+
+func TestWithSafeRetriesSkipsNonIdempotent(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithSafeRetries(2)
+
+	if _, err := client.POST(context.Background(), "/reset", "payload"); err == nil {
+		t.Fatal("Expected error for POST request")
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected POST not to be retried, got %d attempts", got)
+	}
+}
+
+func TestWithSuccessPredicate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ok", r.URL.Query().Get("ok"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": ` + r.URL.Query().Get("ok") + `}`))
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithSuccessPredicate(func(resp *http.Response) bool {
+			return resp.StatusCode == http.StatusOK && resp.Header.Get("X-Ok") == "true"
+		})
+
+	resp, err := client.GET(context.Background(), "/?ok=false")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.IsSuccess() {
+		t.Error("Expected custom predicate to treat the 200 as a failure")
+	}
+
+	var statusErr *httpio.StatusError
+	if err := resp.Err(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusOK {
+		t.Errorf("Expected StatusError with status 200, got %v", err)
+	}
+
+	okResp, err := client.GET(context.Background(), "/?ok=true")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer okResp.Close()
+
+	if !okResp.IsSuccess() || okResp.Err() != nil {
+		t.Error("Expected custom predicate to accept the response")
+	}
+}
+
+func TestResponseErrDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithBaseURL(server.URL).GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.Err() == nil {
+		t.Error("Expected error for 404 response")
+	}
+}
+
+func TestWeightedEndpoints(t *testing.T) {
+	var hits [3]int32
+	servers := make([]*httptest.Server, len(hits))
+	for i := range servers {
+		i := i
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/items" {
+				t.Errorf("Expected path /api/items, got %s", r.URL.Path)
+			}
+			atomic.AddInt32(&hits[i], 1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer servers[i].Close()
+	}
+
+	healthy := true
+	client := httpio.New().WithWeightedEndpoints([]httpio.WeightedEndpoint{
+		{URL: servers[0].URL + "/api", Weight: 1},
+		{URL: servers[1].URL + "/api", Weight: 3},
+		{URL: servers[2].URL + "/api", Weight: 6, Healthy: func() bool { return healthy }},
+	})
+
+	get := func(ctx context.Context) {
+		resp, err := client.GET(ctx, "/items")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	for i := 0; i < 1000; i++ {
+		get(context.Background())
+	}
+	for i, expected := range []int32{100, 300, 600} {
+		if got := atomic.LoadInt32(&hits[i]); got < expected*9/10 || got > expected*11/10 {
+			t.Errorf("Endpoint %d: expected about %d requests, got %d", i, expected, got)
+		}
+	}
+
+	healthy = false
+	before := atomic.LoadInt32(&hits[2])
+	for i := 0; i < 100; i++ {
+		get(context.Background())
+	}
+	if got := atomic.LoadInt32(&hits[2]); got != before {
+		t.Errorf("Expected unhealthy endpoint to be skipped, got %d more requests", got-before)
+	}
+
+	healthy = true
+	for i := range hits {
+		atomic.StoreInt32(&hits[i], 0)
+	}
+	ctx := httpio.WithStickyEndpoint(context.Background())
+	for i := 0; i < 10; i++ {
+		get(ctx)
+	}
+	used := 0
+	for i := range hits {
+		if atomic.LoadInt32(&hits[i]) > 0 {
+			used++
+		}
+	}
+	if used != 1 {
+		t.Errorf("Expected sticky context to use a single endpoint, used %d", used)
+	}
+}
+
+func TestWithFinalizer(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithHeader("X-Tenant", "acme").
+		WithMiddleware(middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Middleware", "seen")
+				return next(ctx, req)
+			}
+		})).
+		WithFinalizer(func(req *http.Request) error {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			encoded, err := io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("X-Signature", strings.Join([]string{
+				req.Header.Get("Content-Type"),
+				req.Header.Get("X-Tenant"),
+				req.Header.Get("X-Middleware"),
+				string(encoded),
+			}, "|"))
+			return nil
+		})
+
+	resp, err := client.POST(context.Background(), "/", map[string]int{"id": 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	expected := `application/json|acme|seen|{"id":1}`
+	if signature != expected {
+		t.Errorf("Expected signature %q, got %q", expected, signature)
+	}
+
+	failing := httpio.New().WithBaseURL(server.URL).WithFinalizer(func(req *http.Request) error {
+		return errors.New("signing key unavailable")
+	})
+	if _, err := failing.GET(context.Background(), "/"); err == nil || !strings.Contains(err.Error(), "signing key unavailable") {
+		t.Errorf("Expected finalizer error, got %v", err)
+	}
+}
+
+func TestWithAutoDecompress(t *testing.T) {
+	lines := "first\nsecond\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		switch encoding := r.URL.Query().Get("encoding"); encoding {
+		case "gzip":
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(lines))
+			gz.Close()
+			w.Header().Set("Content-Encoding", encoding)
+		case "deflate":
+			zw := zlib.NewWriter(&buf)
+			zw.Write([]byte(lines))
+			zw.Close()
+			w.Header().Set("Content-Encoding", encoding)
+		default:
+			buf.WriteString(lines)
+			w.Header().Set("Content-Encoding", "identity")
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithAutoDecompress(true)
+
+	for _, encoding := range []string{"gzip", "deflate", "identity"} {
+		// Asking for an encoding explicitly stops net/http from decompressing it
+		resp, err := client.NewRequest("GET", "/").
+			WithHeader("Accept-Encoding", "gzip, deflate").
+			WithQuery("encoding", encoding).
+			Do(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", encoding, err)
+		}
+		if encoding != "identity" {
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Expected Content-Encoding to be removed for %s, got %q", encoding, got)
+			}
+			if resp.ContentLength != -1 {
+				t.Errorf("Expected unknown content length for %s, got %d", encoding, resp.ContentLength)
+			}
+		}
+		body, err := resp.String()
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", encoding, err)
+		}
+		if body != lines {
+			t.Errorf("Expected body %q for %s, got %q", lines, encoding, body)
+		}
+	}
+
+	var got []string
+	err := client.NewRequest("GET", "/").
+		WithHeader("Accept-Encoding", "gzip").
+		WithQuery("encoding", "gzip").
+		StreamLines(context.Background(), func(line []byte) error {
+			got = append(got, string(line))
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("Expected decompressed lines, got %q", got)
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.Path))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := httpio.New().WithUnixSocket(socket)
+	resp, err := client.GET(context.Background(), "http://unix/v1.41/containers/json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if body != "unix /v1.41/containers/json" {
+		t.Errorf("Expected the request to reach the socket, got %q", body)
+	}
+}
+
+func TestWithDefaultCookieJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+		case "/me":
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != "abc123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("jane"))
+		}
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithDefaultCookieJar()
+
+	resp, err := client.POST(context.Background(), "/login", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	resp, err = client.GET(context.Background(), "/me")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body != "jane" {
+		t.Errorf("Expected the session cookie to be sent, got status %d and body %q", resp.StatusCode, body)
+	}
+
+	// Without a jar the cookie is not kept
+	resp, err = httpio.New().WithBaseURL(server.URL).GET(context.Background(), "/me")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a cookie jar, got %d", resp.StatusCode)
+	}
+}
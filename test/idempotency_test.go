@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware"
+	"github.com/anggasct/httpio/middleware/idempotency"
+	"github.com/anggasct/httpio/middleware/retry"
+)
+
+func TestIdempotencyKeySharedAcrossRetries(t *testing.T) {
+	retryConfig := retry.DefaultConfig()
+	retryConfig.MaxRetries = 3
+	retryConfig.BaseDelay = 5 * time.Millisecond
+	retryConfig.RetryableStatusCodes = []int{http.StatusServiceUnavailable}
+
+	orders := map[string][]middleware.Middleware{
+		"idempotency-outer": {idempotency.New(nil), retry.New(retryConfig)},
+		"idempotency-inner": {retry.New(retryConfig), idempotency.New(nil)},
+	}
+
+	for name, middlewares := range orders {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var keys []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				keys = append(keys, r.Header.Get("Idempotency-Key"))
+				attempt := len(keys)
+				mu.Unlock()
+
+				if attempt < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			client := httpio.New().WithBaseURL(server.URL)
+			for _, m := range middlewares {
+				client.WithMiddleware(m)
+			}
+
+			resp, err := client.POST(context.Background(), "/payments", map[string]int{"amount": 10})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer resp.Close()
+
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("Expected status 201, got %d", resp.StatusCode)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(keys) != 3 {
+				t.Fatalf("Expected 3 attempts, got %d", len(keys))
+			}
+			for i, key := range keys {
+				if key == "" || key != keys[0] {
+					t.Errorf("Expected attempt %d to reuse key %q, got %q", i, keys[0], key)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyPerLogicalRequest(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(idempotency.New(nil))
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.POST(context.Background(), "/orders", "{}")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	ctx := idempotency.WithKey(context.Background(), "app-key")
+	resp, err := client.POST(ctx, "/orders", "{}")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	resp, err = client.GET(context.Background(), "/orders")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if keys[0] == "" || keys[0] == keys[1] {
+		t.Errorf("Expected distinct keys for distinct requests, got %q and %q", keys[0], keys[1])
+	}
+	if keys[2] != "app-key" {
+		t.Errorf("Expected application-provided key, got %q", keys[2])
+	}
+	if keys[3] != "" {
+		t.Errorf("Expected no key on GET, got %q", keys[3])
+	}
+}
+
+func TestIdempotencyKeyPerSendOfReusedRequest(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(idempotency.New(nil))
+	req := client.NewRequest("POST", "/orders").WithBody("{}")
+	for i := 0; i < 2; i++ {
+		resp, err := req.Do(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] == "" || keys[1] == "" || keys[0] == keys[1] {
+		t.Errorf("Expected a new key for each send, got %q", keys)
+	}
+	if got := req.Headers.Get("Idempotency-Key"); got != "" {
+		t.Errorf("Expected the request headers to be left untouched, got key %q", got)
+	}
+}
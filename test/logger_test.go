@@ -3,8 +3,12 @@ package test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/anggasct/httpio"
 	"github.com/anggasct/httpio/middleware/logger"
@@ -253,3 +257,373 @@ func TestLoggerIntegration(t *testing.T) {
 		t.Error("Expected client to be created")
 	}
 }
+
+// idRecordingLogger records the request ID attached to each log call
+type idRecordingLogger struct {
+	ids []string
+}
+
+func (l *idRecordingLogger) Log(ctx context.Context, level logger.LogLevel, msg string, fields map[string]interface{}) {
+	if id, ok := logger.GetRequestID(ctx); ok {
+		l.ids = append(l.ids, id)
+	}
+}
+
+type traceIDKey struct{}
+
+func TestLoggerRequestIDFromContext(t *testing.T) {
+	recorder := &idRecordingLogger{}
+
+	loggerMiddleware := logger.New(&logger.Config{
+		Logger: recorder,
+		Level:  logger.LevelInfo,
+		RequestIDFromContext: func(ctx context.Context) (string, bool) {
+			id, ok := ctx.Value(traceIDKey{}).(string)
+			return id, ok
+		},
+		RequestIDGenerator: func() string {
+			return "generated-id"
+		},
+		PropagateRequestID: true,
+	})
+
+	var sentHeader string
+	handler := loggerMiddleware.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		sentHeader = req.Header.Get("X-Request-ID")
+		return &http.Response{StatusCode: 200, Header: make(http.Header)}, nil
+	})
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace-abc")
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+
+	if _, err := handler(ctx, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if sentHeader != "trace-abc" {
+		t.Errorf("Expected propagated header trace-abc, got %q", sentHeader)
+	}
+
+	if len(recorder.ids) == 0 {
+		t.Fatal("Expected log entries")
+	}
+	for _, id := range recorder.ids {
+		if id != "trace-abc" {
+			t.Errorf("Expected logged request ID trace-abc, got %q", id)
+		}
+	}
+
+	// Without a context-provided ID the generator is used
+	req, _ = http.NewRequest("GET", "http://example.com/test", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sentHeader != "generated-id" {
+		t.Errorf("Expected generated request ID, got %q", sentHeader)
+	}
+}
+
+func TestLoggerRequestIDPropagation(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         logger.Config
+		existingHeader map[string]string
+		wantLogged     string
+		wantHeaders    map[string]string
+	}{
+		{
+			name:        "log only",
+			config:      logger.Config{PropagateRequestID: false},
+			wantLogged:  "generated-id",
+			wantHeaders: map[string]string{"X-Request-ID": ""},
+		},
+		{
+			name:        "propagate",
+			config:      logger.Config{PropagateRequestID: true},
+			wantLogged:  "generated-id",
+			wantHeaders: map[string]string{"X-Request-ID": "generated-id"},
+		},
+		{
+			name: "propagate to a different header",
+			config: logger.Config{
+				PropagateRequestID: true,
+				RequestIDHeader:    "X-Internal-ID",
+				PropagationHeader:  "X-Correlation-ID",
+			},
+			existingHeader: map[string]string{"X-Internal-ID": "inbound-id"},
+			wantLogged:     "inbound-id",
+			wantHeaders:    map[string]string{"X-Internal-ID": "inbound-id", "X-Correlation-ID": "inbound-id"},
+		},
+		{
+			name: "read existing but don't overwrite",
+			config: logger.Config{
+				PropagateRequestID: true,
+				PropagationHeader:  "X-Correlation-ID",
+			},
+			existingHeader: map[string]string{"X-Request-ID": "inbound-id", "X-Correlation-ID": "caller-id"},
+			wantLogged:     "inbound-id",
+			wantHeaders:    map[string]string{"X-Request-ID": "inbound-id", "X-Correlation-ID": "caller-id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &idRecordingLogger{}
+			config := tt.config
+			config.Logger = recorder
+			config.Level = logger.LevelInfo
+			config.RequestIDGenerator = func() string { return "generated-id" }
+
+			var sent http.Header
+			handler := logger.New(&config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				sent = req.Header.Clone()
+				return &http.Response{StatusCode: 200, Header: make(http.Header)}, nil
+			})
+
+			req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+			for k, v := range tt.existingHeader {
+				req.Header.Set(k, v)
+			}
+			if _, err := handler(context.Background(), req); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(recorder.ids) == 0 || recorder.ids[0] != tt.wantLogged {
+				t.Errorf("Expected logged request ID %q, got %v", tt.wantLogged, recorder.ids)
+			}
+			for header, want := range tt.wantHeaders {
+				if got := sent.Get(header); got != want {
+					t.Errorf("Expected %s header %q, got %q", header, want, got)
+				}
+			}
+		})
+	}
+}
+
+// bodyCapturingLogger records the logged request and response bodies
+type bodyCapturingLogger struct {
+	bodies []string
+}
+
+func (l *bodyCapturingLogger) Log(ctx context.Context, level logger.LogLevel, msg string, fields map[string]interface{}) {
+	if body, ok := fields["body"].(string); ok {
+		l.bodies = append(l.bodies, body)
+	}
+}
+
+func logRequestBody(t testing.TB, m *logger.Middleware, body string) {
+	handler := m.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: make(http.Header)}, nil
+	})
+	req, _ := http.NewRequest("POST", "http://example.com/test", strings.NewReader(body))
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestLoggerRedactsSensitiveFields(t *testing.T) {
+	capture := &bodyCapturingLogger{}
+	m := logger.New(&logger.Config{
+		Logger:          capture,
+		Level:           logger.LevelTrace,
+		SensitiveFields: []string{"password"},
+	})
+
+	logRequestBody(t, m, `{"user": "alice", "password": "hunter2"}`)
+
+	if len(capture.bodies) != 1 {
+		t.Fatalf("Expected one logged body, got %d", len(capture.bodies))
+	}
+	if strings.Contains(capture.bodies[0], "hunter2") || !strings.Contains(capture.bodies[0], "[REDACTED]") {
+		t.Errorf("Expected password to be redacted, got %s", capture.bodies[0])
+	}
+	if !strings.Contains(capture.bodies[0], "alice") {
+		t.Errorf("Expected non-sensitive fields to be kept, got %s", capture.bodies[0])
+	}
+}
+
+func TestLoggerEmptySensitiveFieldsLogsBodyAsIs(t *testing.T) {
+	capture := &bodyCapturingLogger{}
+	m := logger.New(&logger.Config{
+		Logger:          capture,
+		Level:           logger.LevelTrace,
+		SensitiveFields: []string{},
+	})
+
+	body := `{"password":   "hunter2"}`
+	logRequestBody(t, m, body)
+
+	if len(capture.bodies) != 1 || capture.bodies[0] != body {
+		t.Errorf("Expected body to be logged verbatim, got %v", capture.bodies)
+	}
+}
+
+// largeJSONBody builds a JSON array of objects with a mix of sensitive and plain fields
+func largeJSONBody(n int) string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id": %d, "name": "user%d", "token": "t%d", "profile": {"email": "u%d@example.com", "secret": "s"}}`, i, i, i, i)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// discardLogger drops every log entry
+type discardLogger struct{}
+
+func (discardLogger) Log(ctx context.Context, level logger.LogLevel, msg string, fields map[string]interface{}) {
+}
+
+func BenchmarkLoggerRedaction(b *testing.B) {
+	body := largeJSONBody(200)
+	m := logger.New(&logger.Config{Logger: discardLogger{}, Level: logger.LevelTrace})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logRequestBody(b, m, body)
+	}
+}
+
+func BenchmarkLoggerNoSensitiveFields(b *testing.B) {
+	body := largeJSONBody(200)
+	m := logger.New(&logger.Config{
+		Logger:          discardLogger{},
+		Level:           logger.LevelTrace,
+		SensitiveFields: []string{},
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logRequestBody(b, m, body)
+	}
+}
+
+func TestLoggerSensitiveFieldsMatchLiterally(t *testing.T) {
+	capture := &bodyCapturingLogger{}
+	m := logger.New(&logger.Config{
+		Logger:          capture,
+		Level:           logger.LevelTrace,
+		SensitiveFields: []string{"a.b"},
+	})
+
+	logRequestBody(t, m, `{"axb": "visible", "x_a.b_y": "hidden"}`)
+
+	if len(capture.bodies) != 1 {
+		t.Fatalf("Expected one logged body, got %d", len(capture.bodies))
+	}
+	if !strings.Contains(capture.bodies[0], "visible") {
+		t.Errorf("Expected field name not to be treated as a regex, got %s", capture.bodies[0])
+	}
+	if strings.Contains(capture.bodies[0], "hidden") {
+		t.Errorf("Expected key containing the literal field name to be redacted, got %s", capture.bodies[0])
+	}
+}
+
+func TestLoggerExactFieldMatch(t *testing.T) {
+	capture := &bodyCapturingLogger{}
+	m := logger.New(&logger.Config{
+		Logger:          capture,
+		Level:           logger.LevelTrace,
+		SensitiveFields: []string{"token"},
+		ExactFieldMatch: true,
+	})
+
+	logRequestBody(t, m, `{"Token": "secret-value", "token_type": "bearer"}`)
+
+	if len(capture.bodies) != 1 {
+		t.Fatalf("Expected one logged body, got %d", len(capture.bodies))
+	}
+	if strings.Contains(capture.bodies[0], "secret-value") {
+		t.Errorf("Expected exact (case-insensitive) match to be redacted, got %s", capture.bodies[0])
+	}
+	if !strings.Contains(capture.bodies[0], "bearer") {
+		t.Errorf("Expected partial match to be kept with ExactFieldMatch, got %s", capture.bodies[0])
+	}
+}
+
+// nestedJSONBody builds a deeply nested JSON object
+func nestedJSONBody(depth, width int) string {
+	if depth == 0 {
+		return `{"password": "p", "value": 1}`
+	}
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < width; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `"child%d": %s, "api_key%d": "k"`, i, nestedJSONBody(depth-1, width), i)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func BenchmarkLoggerRedactionNested(b *testing.B) {
+	body := nestedJSONBody(4, 4)
+	m := logger.New(&logger.Config{Logger: discardLogger{}, Level: logger.LevelTrace})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logRequestBody(b, m, body)
+	}
+}
+
+type fieldsCapturingLogger struct {
+	entries []map[string]interface{}
+}
+
+func (l *fieldsCapturingLogger) Log(ctx context.Context, level logger.LogLevel, msg string, fields map[string]interface{}) {
+	l.entries = append(l.entries, fields)
+}
+
+func TestLoggerECSFieldSchema(t *testing.T) {
+	capture := &fieldsCapturingLogger{}
+	m := logger.New(&logger.Config{
+		Logger:      capture,
+		Level:       logger.LevelInfo,
+		Format:      logger.FormatJSON,
+		FieldSchema: logger.SchemaECS,
+	})
+
+	handler := m.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		time.Sleep(time.Millisecond)
+		return &http.Response{StatusCode: http.StatusCreated, Header: make(http.Header)}, nil
+	})
+
+	req, _ := http.NewRequest("POST", "http://example.com/items", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(capture.entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(capture.entries))
+	}
+	output, err := json.Marshal(capture.entries[1])
+	if err != nil {
+		t.Fatalf("Expected fields to marshal, got %v", err)
+	}
+
+	var decoded map[string]interface{}
+	json.Unmarshal(output, &decoded)
+	if decoded["http.request.method"] != "POST" {
+		t.Errorf("Expected http.request.method POST, got %v", decoded["http.request.method"])
+	}
+	if decoded["url.full"] != "http://example.com/items" {
+		t.Errorf("Expected url.full, got %v", decoded["url.full"])
+	}
+	if decoded["http.response.status_code"] != float64(http.StatusCreated) {
+		t.Errorf("Expected http.response.status_code 201, got %v", decoded["http.response.status_code"])
+	}
+	if duration, ok := decoded["event.duration"].(float64); !ok || duration < float64(time.Millisecond) {
+		t.Errorf("Expected event.duration in nanoseconds, got %v", decoded["event.duration"])
+	}
+	for _, key := range []string{"method", "url", "status", "duration"} {
+		if _, ok := decoded[key]; ok {
+			t.Errorf("Expected default field %q to be renamed", key)
+		}
+	}
+}
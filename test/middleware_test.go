@@ -2,10 +2,16 @@ package test
 
 import (
 	"context"
+	"errors"
+	"maps"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/anggasct/httpio"
 	"github.com/anggasct/httpio/middleware"
+	"github.com/anggasct/httpio/middleware/retry"
 )
 
 type mockHandler struct {
@@ -153,3 +159,252 @@ func TestMiddlewareOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestComposeMatchesSequentialMiddlewares(t *testing.T) {
+	newRecorder := func(order *[]string, name string) middleware.Middleware {
+		return middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				*order = append(*order, "before-"+name)
+				resp, err := next(ctx, req)
+				*order = append(*order, "after-"+name)
+				return resp, err
+			}
+		})
+	}
+
+	run := func(build func(order *[]string) []middleware.Middleware) []string {
+		var order []string
+		baseHandler := func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			order = append(order, "handler")
+			return &http.Response{StatusCode: 200}, nil
+		}
+		handler := middleware.Chain(baseHandler, build(&order)...)
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if _, err := handler(context.Background(), req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return order
+	}
+
+	sequential := run(func(order *[]string) []middleware.Middleware {
+		return []middleware.Middleware{newRecorder(order, "1"), newRecorder(order, "2"), newRecorder(order, "3")}
+	})
+
+	composed := run(func(order *[]string) []middleware.Middleware {
+		stack := middleware.Compose(newRecorder(order, "1"), newRecorder(order, "2"))
+		return []middleware.Middleware{stack, newRecorder(order, "3")}
+	})
+
+	if len(sequential) != len(composed) {
+		t.Fatalf("Expected %v, got %v", sequential, composed)
+	}
+	for i := range sequential {
+		if sequential[i] != composed[i] {
+			t.Errorf("Expected order[%d] = %s, got %s", i, sequential[i], composed[i])
+		}
+	}
+}
+
+func TestClientWithMiddlewareStack(t *testing.T) {
+	first := &testMiddleware{name: "First"}
+	second := &testMiddleware{name: "Second"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Middleware-First") != "called" || r.Header.Get("X-Middleware-Second") != "called" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	stack := middleware.Compose(first, second)
+	client := httpio.New().WithBaseURL(server.URL).WithMiddlewareStack(stack)
+
+	resp, err := client.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected stacked middlewares to run, got status %d", resp.StatusCode)
+	}
+}
+
+// metricRecorder records the metric name and tags of every request it sees
+type metricRecorder struct {
+	names []string
+	tags  []map[string]string
+}
+
+func (m *metricRecorder) Handle(next middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		m.names = append(m.names, middleware.MetricName(req))
+		m.tags = append(m.tags, middleware.MetricTags(req))
+		return next(ctx, req)
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := &metricRecorder{}
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(recorder).
+		WithRouteTemplates(func(path string) string {
+			if strings.HasPrefix(path, "/users/") {
+				return "/users/:id"
+			}
+			return ""
+		})
+
+	ctx := context.Background()
+	for _, req := range []*httpio.Request{
+		client.NewRequest("GET", "/users/42"),
+		client.NewRequest("GET", "/orders/7").WithMetricName("/orders/:id"),
+		client.NewRequest("GET", "/users/42").WithMetricName("get_user"),
+		client.NewRequest("GET", "/health"),
+	} {
+		resp, err := req.Do(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	expected := []string{"/users/:id", "/orders/:id", "get_user", "/health"}
+	if len(recorder.names) != len(expected) {
+		t.Fatalf("Expected %d metric names, got %v", len(expected), recorder.names)
+	}
+	for i, want := range expected {
+		if recorder.names[i] != want {
+			t.Errorf("Expected metric name %q for request %d, got %q", want, i, recorder.names[i])
+		}
+	}
+}
+
+func TestMetricTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := &metricRecorder{}
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(recorder)
+
+	ctx := middleware.WithMetricTags(context.Background(), map[string]string{"tenant": "acme", "feature": "default"})
+	for _, req := range []*httpio.Request{
+		client.NewRequest("GET", "/reports").WithMetricTags(map[string]string{"feature": "export"}),
+		client.NewRequest("GET", "/reports"),
+	} {
+		resp, err := req.Do(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	expected := []map[string]string{
+		{"tenant": "acme", "feature": "export"},
+		{"tenant": "acme", "feature": "default"},
+	}
+	if len(recorder.tags) != len(expected) {
+		t.Fatalf("Expected %d tag sets, got %v", len(expected), recorder.tags)
+	}
+	for i, want := range expected {
+		if !maps.Equal(recorder.tags[i], want) {
+			t.Errorf("Expected tags %v for request %d, got %v", want, i, recorder.tags[i])
+		}
+	}
+}
+
+func TestValidateDetectsDuplicateMiddlewares(t *testing.T) {
+	client := httpio.New().
+		WithMiddleware(retry.New(nil)).
+		WithMiddleware(middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler { return next })).
+		WithMiddleware(middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler { return next }))
+
+	if err := client.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	client.WithMiddleware(retry.New(nil))
+
+	if err := client.Validate(); !errors.Is(err, httpio.ErrDuplicateMiddleware) {
+		t.Errorf("Expected ErrDuplicateMiddleware, got %v", err)
+	}
+
+	chain := client.Middlewares()
+	if len(chain) != 4 {
+		t.Fatalf("Expected 4 middlewares, got %d", len(chain))
+	}
+	chain[0] = nil
+	if client.Middlewares()[0] == nil {
+		t.Error("Expected Middlewares to return a copy of the chain")
+	}
+}
+
+func TestResponseOnlyMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(middleware.ResponseOnly(func(ctx context.Context, resp *http.Response, err error) (*http.Response, error) {
+			if resp != nil {
+				resp.Header.Set("X-Seen", "true")
+			}
+			return resp, err
+		}))
+
+	for _, path := range []string{"/a", "/b"} {
+		resp, err := client.GET(context.Background(), path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+		if got := resp.Header.Get("X-Seen"); got != "true" {
+			t.Errorf("Expected X-Seen header on %s, got '%s'", path, got)
+		}
+	}
+}
+
+func TestNilResponseFromMiddlewareIsAnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	buggy := middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return nil, nil
+		}
+	})
+	// An outer middleware that trusts a nil error to come with a response
+	trusting := middleware.ResponseOnly(func(ctx context.Context, resp *http.Response, err error) (*http.Response, error) {
+		if err == nil {
+			resp.Header.Set("X-Status", resp.Status)
+		}
+		return resp, err
+	})
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(trusting).
+		WithMiddleware(buggy)
+
+	_, err := client.GET(context.Background(), "/")
+	if !errors.Is(err, httpio.ErrNilResponse) {
+		t.Fatalf("Expected ErrNilResponse, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "nil response") {
+		t.Errorf("Expected a descriptive error, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio/mockserver"
+)
+
+// newMockTestServer serves the mock server's routes on a random local port
+func newMockTestServer(t *testing.T, ms *mockserver.MockServer) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(ms)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMockServerDecompressesGzipRequestBody(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.EnableRequestDecompression(true)
+
+	var handlerBody []byte
+	ms.AddRoute("/upload", func(w http.ResponseWriter, r *http.Request) {
+		handlerBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := newMockTestServer(t, ms)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"message": "hello"}`))
+	gz.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/upload", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+
+	if got := string(ms.LastRequestBody()); got != `{"message": "hello"}` {
+		t.Errorf("Expected decompressed last request body, got %q", got)
+	}
+
+	if string(handlerBody) != `{"message": "hello"}` {
+		t.Errorf("Expected handler to read decompressed body, got %q", handlerBody)
+	}
+}
+
+func TestMockServerKeepsCompressedBodyWhenDisabled(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddRoute("/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := newMockTestServer(t, ms)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("payload"))
+	gz.Close()
+	raw := compressed.Bytes()
+
+	req, _ := http.NewRequest("POST", server.URL+"/upload", bytes.NewReader(raw))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if !bytes.Equal(ms.LastRequestBody(), raw) {
+		t.Error("Expected raw compressed body to be captured when decompression is disabled")
+	}
+}
+
+// recordingT captures errors reported through TestingT
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockServerExpectationMatched(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	expectation := ms.Expect("POST", "/users").
+		WithHeader("X-Api-Key", "k").
+		WithJSONBody(map[string]interface{}{"name": "alice"}).
+		RespondJSON(http.StatusCreated, map[string]interface{}{"id": 1})
+
+	server := newMockTestServer(t, ms)
+
+	req, _ := http.NewRequest("POST", server.URL+"/users", strings.NewReader(`{"name":"alice"}`))
+	req.Header.Set("X-Api-Key", "k")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	var data map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&data)
+	if data["id"] != float64(1) {
+		t.Errorf("Expected id 1 in response, got %v", data)
+	}
+
+	if expectation.Calls() != 1 {
+		t.Errorf("Expected 1 matching call, got %d", expectation.Calls())
+	}
+
+	ms.AssertExpectations(t)
+}
+
+func TestMockServerExpectationUnmatched(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.Expect("POST", "/users").
+		WithHeader("X-Api-Key", "k").
+		RespondJSON(http.StatusCreated, nil)
+
+	server := newMockTestServer(t, ms)
+
+	req, _ := http.NewRequest("POST", server.URL+"/users", strings.NewReader(`{}`))
+	req.Header.Set("X-Api-Key", "wrong")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected unmatched request to fall through to 404, got %d", resp.StatusCode)
+	}
+
+	recorder := &recordingT{}
+	if ms.AssertExpectations(recorder) {
+		t.Error("Expected AssertExpectations to report failure")
+	}
+	if len(recorder.errors) != 1 || !strings.Contains(recorder.errors[0], "POST /users") {
+		t.Errorf("Expected unmet expectation to be reported, got %v", recorder.errors)
+	}
+}
+
+func TestMockServerFaultyRouteFailsThenSucceeds(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddFaultyRoute("/flaky", mockserver.FaultConfig{
+		StatusCodes: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusInternalServerError},
+		Response:    mockserver.ResponseConfig{Data: map[string]string{"status": "ok"}},
+	})
+
+	server := newMockTestServer(t, ms)
+
+	expected := []int{
+		http.StatusServiceUnavailable,
+		http.StatusBadGateway,
+		http.StatusInternalServerError,
+		http.StatusOK,
+		http.StatusOK,
+	}
+	for i, want := range expected {
+		resp, err := http.Get(server.URL + "/flaky")
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != want {
+			t.Errorf("Request %d: expected status %d, got %d", i, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestMockServerFaultyRouteFailureRate(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddFaultyRoute("/down", mockserver.FaultConfig{
+		FailureRate:   1.0,
+		FailureStatus: http.StatusTooManyRequests,
+	})
+
+	server := newMockTestServer(t, ms)
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + "/down")
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Request %d: expected status 429, got %d", i, resp.StatusCode)
+		}
+	}
+}
+
+func TestMockServerFaultyRouteLatencyAndDrops(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddFaultyRoute("/slow", mockserver.FaultConfig{Latency: 50 * time.Millisecond})
+	ms.AddFaultyRoute("/drop", mockserver.FaultConfig{DropRate: 1.0})
+
+	server := newMockTestServer(t, ms)
+
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms latency, got %v", elapsed)
+	}
+
+	if _, err := http.Get(server.URL + "/drop"); err == nil {
+		t.Error("Expected dropped connection to produce an error")
+	}
+}
+
+func TestMockServerSetRouteAndReset(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddJSONRoute("/status", mockserver.ResponseConfig{Data: map[string]string{"state": "original"}})
+
+	server := newMockTestServer(t, ms)
+
+	getState := func() string {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/status")
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var data map[string]string
+		json.NewDecoder(resp.Body).Decode(&data)
+		return data["state"]
+	}
+
+	if state := getState(); state != "original" {
+		t.Fatalf("Expected original state, got %q", state)
+	}
+
+	ms.SetRoute("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "replaced"}`))
+	})
+
+	if state := getState(); state != "replaced" {
+		t.Errorf("Expected replaced state, got %q", state)
+	}
+
+	ms.Reset()
+
+	if state := getState(); state != "original" {
+		t.Errorf("Expected original state after Reset, got %q", state)
+	}
+}
+
+func TestMockServerPathParameters(t *testing.T) {
+	ms := mockserver.NewMockServer("")
+	ms.AddRoute("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": mockserver.PathParam(r, "id")})
+	})
+	ms.AddRoute("/api/users/{userId}/posts/{postId}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(mockserver.PathParams(r))
+	})
+	ms.AddRoute("/api/users/me", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": "me-route"})
+	})
+
+	server := newMockTestServer(t, ms)
+
+	getJSON := func(path string) map[string]string {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", path, resp.StatusCode)
+		}
+
+		var data map[string]string
+		json.NewDecoder(resp.Body).Decode(&data)
+		return data
+	}
+
+	if data := getJSON("/api/users/42"); data["id"] != "42" {
+		t.Errorf("Expected captured id 42, got %v", data)
+	}
+
+	data := getJSON("/api/users/7/posts/9")
+	if data["userId"] != "7" || data["postId"] != "9" {
+		t.Errorf("Expected userId 7 and postId 9, got %v", data)
+	}
+
+	if data := getJSON("/api/users/me"); data["id"] != "me-route" {
+		t.Errorf("Expected exact route to win over pattern, got %v", data)
+	}
+
+	resp, err := http.Get(server.URL + "/api/users/42/comments")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unmatched path, got %d", resp.StatusCode)
+	}
+}
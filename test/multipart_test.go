@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+)
+
+// generatedReader produces size bytes on demand and counts how many were read
+type generatedReader struct {
+	size int64
+	read atomic.Int64
+}
+
+func (g *generatedReader) Read(p []byte) (int, error) {
+	remaining := g.size - g.read.Load()
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	g.read.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func TestMultipartStreamsParts(t *testing.T) {
+	const partSize = 64 << 20
+	part := &generatedReader{size: partSize}
+
+	var readAhead int64
+	var fieldValue string
+	var received int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "multipart/form-data" {
+			t.Errorf("Expected multipart/form-data, got %q", mediaType)
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+
+		field, _ := reader.NextPart()
+		value, _ := io.ReadAll(field)
+		fieldValue = string(value)
+
+		file, _ := reader.NextPart()
+		n, _ := io.CopyN(io.Discard, file, 1<<20)
+		// Give the client time to run ahead if it were buffering the part
+		time.Sleep(100 * time.Millisecond)
+		readAhead = part.read.Load() - n
+		rest, _ := io.Copy(io.Discard, file)
+		received = n + rest
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	body := httpio.NewMultipart().
+		Field("name", "archive").
+		File("file", "archive.bin", part)
+
+	resp, err := httpio.New().WithBaseURL(server.URL).POST(context.Background(), "/upload", body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if fieldValue != "archive" {
+		t.Errorf("Expected field value archive, got %q", fieldValue)
+	}
+	if received != partSize {
+		t.Errorf("Expected %d bytes to be received, got %d", partSize, received)
+	}
+	if readAhead > 16<<20 {
+		t.Errorf("Expected the part to be streamed, but %d bytes were read ahead", readAhead)
+	}
+}
+
+func TestMultipartHonorsCancellation(t *testing.T) {
+	part := &generatedReader{size: 1 << 30}
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.CopyN(io.Discard, r.Body, 1<<20)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	body := httpio.NewMultipart().File("file", "large.bin", part)
+	_, err := httpio.New().WithBaseURL(server.URL).POST(ctx, "/upload", body)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context deadline error, got %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	stopped := part.read.Load()
+	time.Sleep(50 * time.Millisecond)
+	if part.read.Load() != stopped {
+		t.Error("Expected the part to stop being read after cancellation")
+	}
+	if stopped > 64<<20 {
+		t.Errorf("Expected backpressure to bound reading, read %d bytes", stopped)
+	}
+}
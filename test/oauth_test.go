@@ -0,0 +1,458 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/oauth"
+)
+
+func TestOAuthTokenRequestTimeout(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "slow-token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	apiCalled := false
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiCalled = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	config := oauth.DefaultConfig()
+	config.TokenURL = tokenServer.URL
+	config.ClientID = "client"
+	config.ClientSecret = "secret"
+	config.TokenRequestTimeout = 50 * time.Millisecond
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GET(ctx, "/resource")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, oauth.ErrTokenTimeout) {
+		t.Fatalf("Expected ErrTokenTimeout, got %v", err)
+	}
+
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("Expected token timeout to fire quickly, took %v", elapsed)
+	}
+
+	if ctx.Err() != nil {
+		t.Error("Expected the request context budget to remain available")
+	}
+
+	if apiCalled {
+		t.Error("Expected API not to be called without a token")
+	}
+}
+
+func TestOAuthTokenRequestWithinTimeout(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "fast-token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fast-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	config := oauth.DefaultConfig()
+	config.TokenURL = tokenServer.URL
+	config.TokenRequestTimeout = time.Second
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	resp, err := client.GET(context.Background(), "/resource")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestOAuthCustomTokenSource(t *testing.T) {
+	var authHeaders []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	calls := 0
+	config := oauth.DefaultConfig()
+	config.TokenSource = oauth.TokenSourceFunc(func(ctx context.Context) (*oauth.TokenResponse, error) {
+		calls++
+		return &oauth.TokenResponse{AccessToken: "agent-token", TokenType: "Bearer", ExpiresIn: 3600}, nil
+	})
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.GET(context.Background(), "/resource")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected token source to be called once and cached, got %d calls", calls)
+	}
+
+	for i, header := range authHeaders {
+		if header != "Bearer agent-token" {
+			t.Errorf("Request %d: expected Bearer agent-token, got %q", i, header)
+		}
+	}
+}
+
+func TestOAuthStaticTokenSource(t *testing.T) {
+	var authHeader string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	config := oauth.DefaultConfig()
+	config.TokenSource = oauth.StaticTokenSource("static-token")
+
+	resp, err := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config)).
+		GET(context.Background(), "/resource")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if authHeader != "Bearer static-token" {
+		t.Errorf("Expected Bearer static-token, got %q", authHeader)
+	}
+}
+
+func TestOAuthStaticTokenSourceIsCached(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	source := oauth.StaticTokenSource("static-token")
+	calls := 0
+	config := oauth.DefaultConfig()
+	config.TokenSource = oauth.TokenSourceFunc(func(ctx context.Context) (*oauth.TokenResponse, error) {
+		calls++
+		return source.Token(ctx)
+	})
+
+	client := httpio.New().WithBaseURL(apiServer.URL).WithMiddleware(oauth.New(config))
+	for i := 0; i < 3; i++ {
+		resp, err := client.GET(context.Background(), "/resource")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the static token to be fetched once, got %d calls", calls)
+	}
+}
+
+func TestOAuthKeepsValidTokenWhenProactiveRefreshFails(t *testing.T) {
+	var tokenCalls int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&tokenCalls, 1) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "first-token", "token_type": "Bearer", "expires_in": 60, "refresh_token": "refresh"}`))
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer first-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	var tokenErrors int32
+	config := oauth.DefaultConfig()
+	config.TokenURL = tokenServer.URL
+	config.ClientID = "client"
+	config.ClientSecret = "secret"
+	// The token is within the refresh window as soon as it is acquired
+	config.RefreshThreshold = 2 * time.Minute
+	config.OnTokenError = func(err error) {
+		atomic.AddInt32(&tokenErrors, 1)
+	}
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.GET(context.Background(), "/resource")
+		if err != nil {
+			t.Fatalf("Expected no error on request %d, got %v", i, err)
+		}
+		resp.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 on request %d, got %d", i, resp.StatusCode)
+		}
+	}
+
+	if atomic.LoadInt32(&tokenCalls) < 2 {
+		t.Error("Expected a proactive refresh to be attempted")
+	}
+	if atomic.LoadInt32(&tokenErrors) == 0 {
+		t.Error("Expected the failed refresh to be reported to OnTokenError")
+	}
+}
+
+func TestOAuthRetryOn401WithRefreshedToken(t *testing.T) {
+	var tokenCalls int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	var apiCalls int32
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&apiCalls, 1)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected replayed body 'payload', got '%s'", body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	config := oauth.DefaultConfig()
+	config.TokenURL = tokenServer.URL
+	config.ClientID = "client"
+	config.ClientSecret = "secret"
+	config.RetryOn401 = true
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	resp, err := client.NewRequest("POST", "/resource").
+		WithBody(strings.NewReader("payload")).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 after retry, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&apiCalls); got != 2 {
+		t.Errorf("Expected 2 API calls, got %d", got)
+	}
+	if got := atomic.LoadInt32(&tokenCalls); got != 2 {
+		t.Errorf("Expected 2 token requests, got %d", got)
+	}
+}
+
+func TestOAuthDecodeJWTExpiry(t *testing.T) {
+	newJWT := func(exp time.Time) string {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d,"scp":["read","write"]}`, exp.Unix())))
+		return header + "." + payload + ".signature"
+	}
+
+	var tokenCalls int32
+	var exp time.Time
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer"}`, newJWT(exp))
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	var scope string
+	config := oauth.DefaultConfig()
+	config.TokenURL = tokenServer.URL
+	config.ClientID = "client"
+	config.ClientSecret = "secret"
+	config.RefreshThreshold = time.Minute
+	config.DecodeJWT = true
+	config.OnNewToken = func(token *oauth.TokenResponse) {
+		scope = token.Scope
+	}
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+
+	get := func() {
+		resp, err := client.GET(context.Background(), "/resource")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	// A token expiring in an hour is reused
+	exp = time.Now().Add(time.Hour)
+	get()
+	get()
+	if got := atomic.LoadInt32(&tokenCalls); got != 1 {
+		t.Errorf("Expected the token to be reused until exp, got %d token requests", got)
+	}
+	if scope != "read write" {
+		t.Errorf("Expected scope 'read write' from the scp claim, got '%s'", scope)
+	}
+
+	// A token expiring within the refresh threshold is replaced on the next request
+	client2 := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(oauth.New(config))
+	exp = time.Now().Add(30 * time.Second)
+	atomic.StoreInt32(&tokenCalls, 0)
+	for i := 0; i < 2; i++ {
+		resp, err := client2.GET(context.Background(), "/resource")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+	if got := atomic.LoadInt32(&tokenCalls); got != 2 {
+		t.Errorf("Expected a token close to exp to be refreshed, got %d token requests", got)
+	}
+}
+
+func TestOAuthSubscribeLifecycleEvents(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	calls := 0
+	config := oauth.DefaultConfig()
+	config.RefreshThreshold = 0
+	config.TokenSource = oauth.TokenSourceFunc(func(ctx context.Context) (*oauth.TokenResponse, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("token endpoint unavailable")
+		}
+		return &oauth.TokenResponse{AccessToken: fmt.Sprintf("token-%d", calls), TokenType: "Bearer", ExpiresIn: 1}, nil
+	})
+
+	newTokens := 0
+	config.OnNewToken = func(token *oauth.TokenResponse) { newTokens++ }
+
+	middleware := oauth.New(config)
+	var events []oauth.Event
+	middleware.Subscribe(func(event oauth.Event) {
+		events = append(events, event)
+	})
+	unsubscribed := 0
+	unsubscribe := middleware.Subscribe(func(event oauth.Event) { unsubscribed++ })
+	unsubscribe()
+
+	client := httpio.New().
+		WithBaseURL(apiServer.URL).
+		WithMiddleware(middleware)
+
+	start := time.Now()
+	get := func() error {
+		resp, err := client.GET(context.Background(), "/resource")
+		if err == nil {
+			resp.Close()
+		}
+		return err
+	}
+
+	if err := get(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if err := get(); err == nil {
+		t.Fatal("Expected an error while the token endpoint is unavailable")
+	}
+	if err := get(); err != nil {
+		t.Fatalf("Expected no error after recovery, got %v", err)
+	}
+
+	expected := []oauth.EventType{oauth.EventAcquired, oauth.EventExpired, oauth.EventRefreshFailed, oauth.EventRefreshed}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], event.Type)
+		}
+		if event.Time.Before(start) {
+			t.Errorf("Event %d: expected a timestamp, got %v", i, event.Time)
+		}
+	}
+	if events[2].Err == nil {
+		t.Error("Expected the refresh failure event to carry the error")
+	}
+	if events[3].Token == nil || events[3].Token.AccessToken != "token-3" {
+		t.Errorf("Expected the refreshed event to carry token-3, got %+v", events[3].Token)
+	}
+
+	if newTokens != 2 {
+		t.Errorf("Expected OnNewToken to be called twice, got %d", newTokens)
+	}
+	if unsubscribed != 0 {
+		t.Errorf("Expected no events after unsubscribing, got %d", unsubscribed)
+	}
+}
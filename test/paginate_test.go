@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anggasct/httpio"
+)
+
+func TestPaginateLinks(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("Expected headers to be kept across pages, got %v", r.Header)
+		}
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "1":
+			w.Header().Set("Link", `</items?page=2>; rel="next", <`+server.URL+`/items?page=3>; rel="last"`)
+		case "2":
+			w.Header().Set("Link", `<`+server.URL+`/items?page=3>; rel="next last", </items?page=1>; rel="prev first"`)
+		case "3":
+			w.Header().Set("Link", `</items?page=1>; rel="first"`)
+		}
+		fmt.Fprintf(w, `{"page": %s}`, page)
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL)
+	first := c.NewRequest(http.MethodGet, "/items").
+		WithQuery("page", "1").
+		WithHeader("X-Api-Key", "secret")
+
+	var pages []int
+	err := c.PaginateLinks(context.Background(), first, func(page *httpio.Response) error {
+		var body struct {
+			Page int `json:"page"`
+		}
+		if err := page.JSON(&body); err != nil {
+			return err
+		}
+		pages = append(pages, body.Page)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if fmt.Sprint(pages) != "[1 2 3]" {
+		t.Errorf("Expected pages [1 2 3], got %v", pages)
+	}
+}
+
+func TestResponseLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<https://example.com/a,b>; rel="next"; title="x, y"`)
+		w.Header().Add("Link", `</items?page=9>; rel=last`)
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL+"/items")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	links := resp.Links()
+	if links["next"] != "https://example.com/a,b" {
+		t.Errorf("Expected next link, got %q", links["next"])
+	}
+	if links["last"] != server.URL+"/items?page=9" {
+		t.Errorf("Expected resolved last link, got %q", links["last"])
+	}
+}
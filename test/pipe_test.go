@@ -0,0 +1,168 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/stream"
+)
+
+func TestPipeTransformsAndFlushes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Trailer", "X-Checksum")
+		flusher := w.(http.Flusher)
+		for _, line := range []string{"one\n", "two\n", "three\n"} {
+			w.Write([]byte(line))
+			flusher.Flush()
+		}
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	err = stream.Pipe(context.Background(), resp, recorder, func(chunk []byte) ([]byte, error) {
+		return bytes.ToUpper(chunk), nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Expected Content-Type to be forwarded, got '%s'", got)
+	}
+	if got := recorder.Body.String(); got != "ONE\nTWO\nTHREE\n" {
+		t.Errorf("Expected transformed body, got '%s'", got)
+	}
+	if !recorder.Flushed {
+		t.Error("Expected the recorder to be flushed")
+	}
+	if got := recorder.Result().Trailer.Get("X-Checksum"); got != "abc" {
+		t.Errorf("Expected trailer X-Checksum 'abc', got '%s'", got)
+	}
+}
+
+func TestPipeStopsWhenContextDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = stream.Pipe(ctx, resp, httptest.NewRecorder(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Pipe to return promptly, took %v", elapsed)
+	}
+}
+
+// countingFlushRecorder is a ResponseRecorder that counts flushes
+type countingFlushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes atomic.Int32
+}
+
+func (r *countingFlushRecorder) Flush() {
+	r.flushes.Add(1)
+	r.ResponseRecorder.Flush()
+}
+
+// recordSource returns a streaming response whose body yields the records written to the
+// returned pipe writer one read at a time
+func recordSource() (*httpio.Response, *io.PipeWriter) {
+	reader, writer := io.Pipe()
+	return &httpio.Response{Response: &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       reader,
+	}}, writer
+}
+
+func TestPipeFlushCadence(t *testing.T) {
+	run := func(opts ...stream.PipeOption) (*countingFlushRecorder, error) {
+		src, writer := recordSource()
+		go func() {
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(writer, "record %d\n", i)
+			}
+			writer.Close()
+		}()
+
+		recorder := &countingFlushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		return recorder, stream.Pipe(context.Background(), src, recorder, nil, opts...)
+	}
+
+	perRecord, err := run()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	batched, err := run(stream.FlushEvery(10))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := perRecord.flushes.Load(); got != 101 {
+		t.Errorf("Expected a flush per record plus the headers, got %d", got)
+	}
+	if got := batched.flushes.Load(); got > 20 {
+		t.Errorf("Expected at most 20 flushes with FlushEvery(10), got %d", got)
+	}
+	if perRecord.Body.String() != batched.Body.String() || strings.Count(batched.Body.String(), "\n") != 100 {
+		t.Error("Expected all records to be delivered")
+	}
+}
+
+func TestPipeFlushesOnIdle(t *testing.T) {
+	src, writer := recordSource()
+	recorder := &countingFlushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- stream.Pipe(context.Background(), src, recorder, nil, stream.FlushEvery(10), stream.FlushInterval(20*time.Millisecond))
+	}()
+
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(writer, "record %d\n", i)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Headers plus the idle flush of the three buffered records
+	if got := recorder.flushes.Load(); got != 2 {
+		t.Errorf("Expected buffered records to be flushed while idle, got %d flushes", got)
+	}
+
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/ratelimit"
+	"github.com/anggasct/httpio/middleware/retry"
+)
+
+func TestRateLimitMiddlewarePacesRequests(t *testing.T) {
+	var mu sync.Mutex
+	remaining := 3
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", "0.3")
+		if remaining > 0 {
+			remaining--
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	config := ratelimit.DefaultConfig()
+	config.Threshold = 2
+	config.OnThrottle = func(req *http.Request, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(ratelimit.New(config))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		resp, err := client.GET(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+	elapsed := time.Since(start)
+
+	// remaining=3 (no pacing), 2, 1, 0 (paced) -> three throttled requests
+	if len(delays) != 3 {
+		t.Fatalf("Expected 3 throttled requests, got %d", len(delays))
+	}
+
+	for i := 1; i < len(delays); i++ {
+		if delays[i] <= delays[i-1] {
+			t.Errorf("Expected increasing delays as quota drains, got %v", delays)
+		}
+	}
+
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("Expected requests to be paced, completed in %v", elapsed)
+	}
+}
+
+func TestRateLimitMiddlewareNoThrottleAboveThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "100")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	throttled := 0
+	config := ratelimit.GitHubConfig()
+	config.OnThrottle = func(req *http.Request, delay time.Duration) {
+		throttled++
+	}
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(ratelimit.New(config))
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.GET(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Close()
+	}
+
+	if throttled != 0 {
+		t.Errorf("Expected no throttling above threshold, got %d", throttled)
+	}
+}
+
+func TestRateLimitMiddlewareRespectsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", "10")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(ratelimit.New(ratelimit.StandardConfig()))
+
+	resp, err := client.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.GET(ctx, "/")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline exceeded, got %v", err)
+	}
+}
+
+func TestRateLimitMiddlewareHonorsRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	config := ratelimit.DefaultConfig()
+	config.OnThrottle = func(req *http.Request, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+	client := httpio.New().WithBaseURL(server.URL).WithMiddleware(ratelimit.New(config))
+
+	resp, err := client.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	// The next request is held for the delay the server asked for
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GET(ctx, "/"); err == nil {
+		t.Fatal("Expected the paced request to outlast its context")
+	}
+	if len(delays) != 1 || delays[0] < 1900*time.Millisecond {
+		t.Errorf("Expected the request to be held for about 2s, got %v", delays)
+	}
+}
+
+func TestRetrySharesRetryAfterWithRateLimiter(t *testing.T) {
+	limiter := ratelimit.New(nil)
+
+	config := retry.DefaultConfig()
+	config.MaxRetries = 1
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 20 * time.Millisecond
+	config.RetryableStatusCodes = []int{http.StatusTooManyRequests}
+	config.Throttle = limiter
+
+	attempts := 0
+	handler := retry.New(config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			header := http.Header{"Retry-After": {"3"}}
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	start := time.Now()
+	resp, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the retry delay to be capped by MaxDelay, took %v", elapsed)
+	}
+
+	// Other requests sharing the limiter are paced by the Retry-After signal
+	if wait := time.Until(limiter.NextAllowed()); wait < 2*time.Second {
+		t.Errorf("Expected the limiter to hold requests for about 3s, got %v", wait)
+	}
+}
+
+func TestRateLimitMiddlewareKeepsRetryAfterPause(t *testing.T) {
+	limiter := ratelimit.New(nil)
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		// A concurrent request was told to back off while this one was in flight
+		limiter.PauseUntil(time.Now().Add(2 * time.Second))
+		header := http.Header{"X-Ratelimit-Remaining": {"100"}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if wait := time.Until(limiter.NextAllowed()); wait < time.Second {
+		t.Errorf("Expected the Retry-After pause to be kept, got %v", wait)
+	}
+}
+
+func TestRateLimitMiddlewareSpacesWaitingRequests(t *testing.T) {
+	var mu sync.Mutex
+	var delays []time.Duration
+	config := ratelimit.DefaultConfig()
+	config.Threshold = 2
+	config.OnThrottle = func(req *http.Request, delay time.Duration) {
+		mu.Lock()
+		delays = append(delays, delay)
+		mu.Unlock()
+	}
+	limiter := ratelimit.New(config)
+
+	calls := 0
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		header := http.Header{}
+		if calls == 1 {
+			// One request left in the next 300ms: one every 150ms
+			header.Set("X-RateLimit-Remaining", "1")
+			header.Set("X-RateLimit-Reset", "0.3")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler(context.Background(), req.Clone(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	if len(delays) != 3 {
+		t.Fatalf("Expected 3 throttled requests, got %v", delays)
+	}
+	slices.Sort(delays)
+	for i := 1; i < len(delays); i++ {
+		if gap := delays[i] - delays[i-1]; gap < 100*time.Millisecond {
+			t.Errorf("Expected waiting requests to be released one at a time, got delays %v", delays)
+			break
+		}
+	}
+}
+
+func TestRateLimitMiddlewareCanceledWaitersReleaseSlots(t *testing.T) {
+	config := ratelimit.DefaultConfig()
+	config.Threshold = 2
+	limiter := ratelimit.New(config)
+
+	calls := 0
+	handler := limiter.Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		calls++
+		// One request left in the next 2s: one every second
+		header := http.Header{"X-Ratelimit-Remaining": {"1"}, "X-Ratelimit-Reset": {"2"}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := handler(ctx, req.Clone(ctx))
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Fatalf("Expected context deadline exceeded, got %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected canceled requests not to be sent, got %d calls", calls)
+	}
+	if wait := time.Until(limiter.NextAllowed()); wait > 1500*time.Millisecond {
+		t.Errorf("Expected canceled waiters to give back their slots, next request waits %v", wait)
+	}
+}
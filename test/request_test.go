@@ -2,14 +2,24 @@ package test
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/anggasct/httpio"
 	"github.com/anggasct/httpio/internal/client"
 	"github.com/anggasct/httpio/middleware"
+	"github.com/anggasct/httpio/middleware/retry"
 )
 
 type mockHTTPClient struct {
@@ -309,3 +319,462 @@ func TestRequestStreamLines(t *testing.T) {
 		t.Error("Expected to receive some lines")
 	}
 }
+
+func TestMaxRequestBytesInMemoryBody(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL).WithMaxRequestBytes(16)
+
+	_, err := c.POST(context.Background(), "/upload", map[string]string{"payload": strings.Repeat("x", 64)})
+	if !errors.Is(err, httpio.ErrRequestBodyTooLarge) {
+		t.Fatalf("Expected ErrRequestBodyTooLarge, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("Expected oversize request not to be sent")
+	}
+
+	resp, err := c.POST(context.Background(), "/upload", "small")
+	if err != nil {
+		t.Fatalf("Expected body within limit to be sent, got %v", err)
+	}
+	resp.Close()
+}
+
+func TestMaxRequestBytesSizedReaderStaysReplayable(t *testing.T) {
+	var mu sync.Mutex
+	var lengths []int64
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lengths = append(lengths, r.ContentLength)
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	retryConfig := retry.DefaultConfig()
+	retryConfig.BaseDelay = time.Millisecond
+	c := httpio.New().WithBaseURL(server.URL).WithMaxRequestBytes(16).WithMiddleware(retry.New(retryConfig))
+
+	_, err := c.POST(context.Background(), "/upload", strings.NewReader(strings.Repeat("x", 64)))
+	if !errors.Is(err, httpio.ErrRequestBodyTooLarge) {
+		t.Fatalf("Expected ErrRequestBodyTooLarge, got %v", err)
+	}
+
+	resp, err := c.POST(context.Background(), "/upload", strings.NewReader("small"))
+	if err != nil {
+		t.Fatalf("Expected the body to be replayed, got %v", err)
+	}
+	resp.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 || bodies[1] != "small" {
+		t.Errorf("Expected the body to be sent twice, got %q", bodies)
+	}
+	for _, length := range lengths {
+		if length != 5 {
+			t.Errorf("Expected Content-Length 5, got %d", length)
+		}
+	}
+}
+
+func TestMaxRequestBytesStreamingBodyReturnsDataBeforeError(t *testing.T) {
+	var reads []int
+	var readErr error
+	inspect := middleware.WrapMiddleware(func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			buf := make([]byte, 64)
+			for readErr == nil {
+				var n int
+				n, readErr = req.Body.Read(buf)
+				reads = append(reads, n)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+	})
+
+	c := httpio.New().WithMaxRequestBytes(8).WithMiddleware(inspect)
+	body := io.MultiReader(strings.NewReader(strings.Repeat("x", 32)))
+	if _, err := c.POST(context.Background(), "http://example.com/upload", body); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(reads) != 2 || reads[0] != 8 || reads[1] != 0 {
+		t.Errorf("Expected 8 bytes and then the error, got reads of %v", reads)
+	}
+	if !errors.Is(readErr, httpio.ErrRequestBodyTooLarge) {
+		t.Errorf("Expected ErrRequestBodyTooLarge, got %v", readErr)
+	}
+}
+
+func TestMaxRequestBytesStreamingBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL).WithMaxRequestBytes(1024)
+
+	// Hide the concrete reader type so the body size is unknown up front
+	body := io.MultiReader(strings.NewReader(strings.Repeat("x", 4096)))
+	_, err := c.POST(context.Background(), "/upload", body)
+	if !errors.Is(err, httpio.ErrRequestBodyTooLarge) {
+		t.Fatalf("Expected ErrRequestBodyTooLarge, got %v", err)
+	}
+
+	resp, err := c.POST(context.Background(), "/upload", io.MultiReader(strings.NewReader("small")))
+	if err != nil {
+		t.Fatalf("Expected streaming body within limit to be sent, got %v", err)
+	}
+	resp.Close()
+}
+
+func TestRequestWithFileBody(t *testing.T) {
+	var contentType string
+	var contentLength int64
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		contentLength = r.ContentLength
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `{"name": "report"}`
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := httpio.New().WithBaseURL(server.URL)
+	resp, err := c.NewRequest(http.MethodPut, "/upload").WithFileBody(path).Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
+	if contentLength != int64(len(content)) {
+		t.Errorf("Expected Content-Length %d, got %d", len(content), contentLength)
+	}
+	if received != content {
+		t.Errorf("Expected body %q, got %q", content, received)
+	}
+
+	// Files without a known extension are sniffed
+	path = filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n0000"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = c.NewRequest(http.MethodPut, "/upload").WithFileBody(path).Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if contentType != "image/png" {
+		t.Errorf("Expected sniffed Content-Type image/png, got %q", contentType)
+	}
+
+	_, err = c.NewRequest(http.MethodPut, "/upload").WithFileBody(filepath.Join(t.TempDir(), "missing")).Do(context.Background())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestRequestWithTrailer(t *testing.T) {
+	var body, checksum string
+	var transferEncoding []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		// Trailers are only available once the body has been read
+		checksum = r.Trailer.Get("X-Checksum")
+		transferEncoding = r.TransferEncoding
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().
+		WithBaseURL(server.URL).
+		NewRequest(http.MethodPost, "/upload").
+		WithBody([]byte("chunked payload")).
+		WithTrailer("X-Checksum", "abc123").
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if body != "chunked payload" {
+		t.Errorf("Expected body to be received, got %q", body)
+	}
+	if checksum != "abc123" {
+		t.Errorf("Expected trailer X-Checksum abc123, got %q", checksum)
+	}
+	if len(transferEncoding) != 1 || transferEncoding[0] != "chunked" {
+		t.Errorf("Expected chunked transfer encoding, got %v", transferEncoding)
+	}
+}
+
+func TestRequestWithJSONCodec(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "widget", "internal_id": 42}`))
+	}))
+	defer server.Close()
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	c := httpio.New().WithBaseURL(server.URL)
+	strict := httpio.StandardJSONCodec{DisallowUnknownFields: true}
+
+	resp, err := c.NewRequest(http.MethodPost, "/items").
+		WithBody(item{Name: "widget"}).
+		WithJSONCodec(strict).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received != `{"name":"widget"}` {
+		t.Errorf("Expected encoded body, got %q", received)
+	}
+	var strictItem item
+	if err := resp.JSON(&strictItem); err == nil || !strings.Contains(err.Error(), "internal_id") {
+		t.Errorf("Expected strict decoding to reject the unknown field, got %v", err)
+	}
+
+	resp, err = c.NewRequest(http.MethodGet, "/items").Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var lenientItem item
+	if err := resp.JSON(&lenientItem); err != nil {
+		t.Fatalf("Expected default decoding to ignore unknown fields, got %v", err)
+	}
+	if lenientItem.Name != "widget" {
+		t.Errorf("Expected name widget, got %q", lenientItem.Name)
+	}
+}
+
+func TestRequestConditionalGet(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL)
+
+	resp, err := c.NewRequest(http.MethodGet, "/").WithIfNoneMatch(`"v1"`).Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+	if !resp.NotModified() {
+		t.Errorf("Expected NotModified for a matching ETag, got status %d", resp.StatusCode)
+	}
+
+	resp, err = c.NewRequest(http.MethodGet, "/").WithIfModifiedSince(modified.Add(time.Hour)).Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+	if !resp.NotModified() {
+		t.Errorf("Expected NotModified for an unchanged resource, got status %d", resp.StatusCode)
+	}
+
+	resp, err = c.NewRequest(http.MethodGet, "/").WithIfNoneMatch(`"v0"`).Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+	if resp.NotModified() {
+		t.Error("Expected a full response for a stale ETag")
+	}
+}
+
+func TestRequestWithFormBody(t *testing.T) {
+	var contentType, query string
+	var contentLength int64
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		contentLength = r.ContentLength
+		query = r.URL.RawQuery
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	values := url.Values{"grant_type": {"password"}, "username": {"alice smith"}}
+	c := httpio.New().WithBaseURL(server.URL)
+	resp, err := c.NewRequest(http.MethodPost, "/token").
+		WithQuery("tenant", "acme").
+		WithFormBody(values).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected form Content-Type, got %q", contentType)
+	}
+	if contentLength != int64(len(values.Encode())) {
+		t.Errorf("Expected Content-Length %d, got %d", len(values.Encode()), contentLength)
+	}
+	if query != "tenant=acme" {
+		t.Errorf("Expected query to stay separate, got %q", query)
+	}
+	if form.Get("username") != "alice smith" || form.Get("grant_type") != "password" {
+		t.Errorf("Expected form values, got %v", form)
+	}
+
+	_, err = c.NewRequest(http.MethodPost, "/token").
+		WithBody(map[string]string{"a": "b"}).
+		WithFormBody(values).
+		Do(context.Background())
+	if !errors.Is(err, httpio.ErrConflictingBody) {
+		t.Errorf("Expected ErrConflictingBody, got %v", err)
+	}
+}
+
+func TestRequestWithPathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithBaseURL(server.URL)
+
+	resp, err := client.NewRequest("GET", "/users/{id}/repos/{repo}?filter={raw}").
+		WithPathParam("id", "jane doe").
+		WithPathParams(map[string]string{"repo": "a/b", "raw": "ignored"}).
+		WithQuery("sort", "name").
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "/users/jane%20doe/repos/a%2Fb?filter=%7Braw%7D&sort=name"
+	if body != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+
+	_, err = client.NewRequest("GET", "/users/{id}/repos/{repo}").
+		WithPathParam("id", "42").
+		Do(context.Background())
+	if !errors.Is(err, httpio.ErrUnresolvedPathParam) {
+		t.Fatalf("Expected ErrUnresolvedPathParam, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "{repo}") {
+		t.Errorf("Expected the error to name the placeholder, got %q", err)
+	}
+}
+
+func TestRequestWithQueryStruct(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	type Paging struct {
+		Page    int `url:"page,omitempty"`
+		PerPage int `url:"per_page"`
+	}
+	type searchParams struct {
+		Paging
+		Query   string    `url:"q"`
+		Labels  []string  `url:"label"`
+		Since   time.Time `url:"since,omitempty"`
+		Until   time.Time `url:"until,omitempty"`
+		Draft   *bool     `url:"draft,omitempty"`
+		Sort    string    `url:"sort,omitempty"`
+		Score   float64   `url:"min_score"`
+		Secret  string    `url:"-"`
+		private string
+	}
+
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	params := searchParams{
+		Paging: Paging{PerPage: 50},
+		Query:  "httpio",
+		Labels: []string{"bug", "help wanted"},
+		Since:  since,
+		Score:  0.5,
+		Secret: "token",
+	}
+
+	resp, err := httpio.New().NewRequest("GET", server.URL+"/search").
+		WithQueryStruct(&params).
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, _ := url.ParseQuery(body)
+	expected := url.Values{
+		"per_page":  {"50"},
+		"q":         {"httpio"},
+		"label":     {"bug", "help wanted"},
+		"since":     {"2024-05-01T12:00:00Z"},
+		"min_score": {"0.5"},
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected query %v, got %v", expected, got)
+	}
+	for key, want := range expected {
+		if !slices.Equal(got[key], want) {
+			t.Errorf("Expected %s to be %v, got %v", key, want, got[key])
+		}
+	}
+
+	_, err = httpio.New().NewRequest("GET", server.URL).
+		WithQueryStruct(struct {
+			Filter map[string]string `url:"filter"`
+		}{Filter: map[string]string{"a": "b"}}).
+		Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Filter") {
+		t.Errorf("Expected an error naming the unsupported field, got %v", err)
+	}
+}
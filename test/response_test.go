@@ -1,12 +1,26 @@
 package test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/internal/brotli"
 	"github.com/anggasct/httpio/internal/client"
 )
 
@@ -163,3 +177,831 @@ func TestResponseClose(t *testing.T) {
 		t.Error("Expected error reading from closed body, got nil")
 	}
 }
+
+func TestResponseDecodeFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"total": 3, "cursor": "abc"}` + "\n"))
+		w.Write([]byte(`{"id": 1}` + "\n" + `{"id": 2}` + "\n" + `{"id": 3}` + "\n"))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	response := &client.Response{Response: resp}
+
+	var header struct {
+		Total  int    `json:"total"`
+		Cursor string `json:"cursor"`
+	}
+	rest, err := response.DecodeFirst(&header)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if header.Total != 3 || header.Cursor != "abc" {
+		t.Errorf("Expected header {3 abc}, got %+v", header)
+	}
+
+	var lines []string
+	err = rest.StreamLines(func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %v", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Expected record %d to be %s, got %s", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestResponseDecodeFirstKeepsResponseSettings(t *testing.T) {
+	const full = `{"total": 2}` + "\n" + `{"id": 1}` + "\n" + `{"id": 2}` + "\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/x-ndjson\r\nContent-Length: %d\r\n\r\n", len(full))
+		if r.URL.Path == "/short" {
+			buf.WriteString(full[:len(full)-5])
+		} else {
+			buf.WriteString(full)
+		}
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL).WithContentLengthCheck(true)
+	for _, path := range []string{"/full", "/short"} {
+		resp, err := c.GET(context.Background(), path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var header map[string]int
+		rest, err := resp.DecodeFirst(&header)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if rest.Duration() != resp.Duration() {
+			t.Errorf("Expected duration %v to be kept, got %v", resp.Duration(), rest.Duration())
+		}
+
+		_, err = rest.Bytes()
+		var shortErr *httpio.ShortReadError
+		if path == "/full" && err != nil {
+			t.Errorf("Expected the complete rest to pass the length check, got %v", err)
+		}
+		if path == "/short" && !errors.As(err, &shortErr) {
+			t.Errorf("Expected *ShortReadError for a truncated rest, got %v", err)
+		}
+	}
+}
+
+func TestResponseNoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"json", "string", "decode-first"} {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		response := &client.Response{Response: resp}
+
+		if response.HasBody() {
+			t.Errorf("%s: expected 204 response to have no body", name)
+		}
+
+		switch name {
+		case "json":
+			data := map[string]string{"untouched": "yes"}
+			if err := response.JSON(&data); err != nil {
+				t.Errorf("Expected no error decoding 204 response, got %v", err)
+			}
+			if data["untouched"] != "yes" {
+				t.Error("Expected target to be left untouched")
+			}
+		case "string":
+			str, err := response.String()
+			if err != nil || str != "" {
+				t.Errorf("Expected empty string and no error, got %q, %v", str, err)
+			}
+		case "decode-first":
+			var v interface{}
+			if _, err := response.DecodeFirst(&v); err != client.ErrNoBody {
+				t.Errorf("Expected ErrNoBody, got %v", err)
+			}
+		}
+	}
+}
+
+func TestResponseHead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "20")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := http.Head(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	response := &client.Response{Response: resp}
+
+	if response.HasBody() {
+		t.Error("Expected HEAD response to have no body")
+	}
+
+	var data map[string]interface{}
+	if err := response.JSON(&data); err != nil {
+		t.Errorf("Expected no error decoding HEAD response, got %v", err)
+	}
+
+	body, err := (&client.Response{Response: resp}).Bytes()
+	if err != nil || len(body) != 0 {
+		t.Errorf("Expected empty body and no error, got %q, %v", body, err)
+	}
+}
+
+func TestResponseContentType(t *testing.T) {
+	tests := []struct {
+		header    string
+		mediaType string
+		charset   string
+	}{
+		{"application/json; charset=utf-8", "application/json", "utf-8"},
+		{"text/plain", "text/plain", ""},
+		{"Text/HTML; Charset=ISO-8859-1", "text/html", "iso-8859-1"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		response := &client.Response{Response: &http.Response{Header: http.Header{}}}
+		if tt.header != "" {
+			response.Header.Set("Content-Type", tt.header)
+		}
+
+		mediaType, params := response.ContentType()
+		if mediaType != tt.mediaType {
+			t.Errorf("%q: expected media type %q, got %q", tt.header, tt.mediaType, mediaType)
+		}
+		if params == nil {
+			t.Errorf("%q: expected non-nil params", tt.header)
+		}
+		if charset := response.Charset(); charset != tt.charset {
+			t.Errorf("%q: expected charset %q, got %q", tt.header, tt.charset, charset)
+		}
+	}
+}
+
+func TestResponseCloseTwice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test response"))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	response := &client.Response{Response: resp}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := response.Close(); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := response.Close(); err != nil {
+		t.Errorf("Expected repeated Close to return no error, got %v", err)
+	}
+}
+
+func TestResponseCloseReusesConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 16<<10)))
+	}))
+	defer server.Close()
+
+	httpClient := httpio.New()
+
+	var reused []bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused = append(reused, info.Reused)
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.GET(ctx, server.URL)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		buf := make([]byte, 10)
+		if _, err := io.ReadFull(resp.Body, buf); err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		resp.Close()
+	}
+
+	if len(reused) != 2 || !reused[1] {
+		t.Errorf("Expected the second request to reuse the connection, got %v", reused)
+	}
+}
+
+func TestResponseCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", Path: "/", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	response := &client.Response{Response: resp}
+	defer response.Close()
+
+	if cookies := response.Cookies(); len(cookies) != 2 {
+		t.Fatalf("Expected 2 cookies, got %d", len(cookies))
+	}
+
+	session, ok := response.Cookie("session")
+	if !ok {
+		t.Fatal("Expected session cookie to be found")
+	}
+	if session.Value != "abc123" || !session.HttpOnly || session.Path != "/" {
+		t.Errorf("Expected session cookie abc123 with HttpOnly and Path /, got %+v", session)
+	}
+
+	if _, ok := response.Cookie("missing"); ok {
+		t.Error("Expected missing cookie not to be found")
+	}
+}
+
+// apiError is an error-shaped response body
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func TestResponseAsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"id": 1}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": "invalid_email", "message": "email is malformed"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL)
+
+	resp, err := c.GET(context.Background(), "/bad")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	err = resp.AsError(&apiError{})
+	var target *apiError
+	if !errors.As(err, &target) {
+		t.Fatalf("Expected *apiError, got %T: %v", err, err)
+	}
+	if target.Code != "invalid_email" || err.Error() != "invalid_email: email is malformed" {
+		t.Errorf("Expected decoded error body, got %v", err)
+	}
+
+	resp, err = c.GET(context.Background(), "/bad")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	var body map[string]string
+	err = resp.AsError(&body)
+	var statusErr *httpio.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected *StatusError for a non-error target, got %T: %v", err, err)
+	}
+	if statusErr.StatusCode != http.StatusBadRequest || body["code"] != "invalid_email" || statusErr.Detail == nil {
+		t.Errorf("Expected wrapped 400 with decoded detail, got %+v", statusErr)
+	}
+
+	resp, err = c.GET(context.Background(), "/ok")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Close()
+
+	if err := resp.AsError(&apiError{}); err != nil {
+		t.Errorf("Expected nil for a 2xx response, got %v", err)
+	}
+}
+
+func TestContentLengthCheckDetectsTruncatedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n")
+		buf.WriteString(`{"name":"partial"}`)
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	var result map[string]string
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := resp.JSON(&result); err != nil {
+		t.Fatalf("Expected the unchecked decode to succeed, got %v", err)
+	}
+
+	client := httpio.New().WithContentLengthCheck(true)
+
+	resp, err = client.GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = resp.JSON(&result)
+
+	var shortErr *httpio.ShortReadError
+	if !errors.As(err, &shortErr) {
+		t.Fatalf("Expected *ShortReadError, got %v", err)
+	}
+	if shortErr.Expected != 100 || shortErr.Read != int64(len(`{"name":"partial"}`)) {
+		t.Errorf("Expected 18 of 100 bytes read, got %d of %d", shortErr.Read, shortErr.Expected)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected error to match io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	resp, err = client.GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := resp.Bytes(); !errors.As(err, &shortErr) {
+		t.Errorf("Expected *ShortReadError from Bytes, got %v", err)
+	}
+}
+
+func TestContentLengthCheckIgnoresChunkedResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("part one,"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("part two"))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().WithContentLengthCheck(true).GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if body != "part one,part two" {
+		t.Errorf("Expected full body, got '%s'", body)
+	}
+}
+
+func TestResponseProblemDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{
+			"type": "https://example.com/probs/invalid-email",
+			"title": "Invalid email",
+			"status": 422,
+			"detail": "The address jane@ is not valid",
+			"instance": "/users/42",
+			"field": "email"
+		}`))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	problem, ok := resp.Problem()
+	if !ok {
+		t.Fatal("Expected problem details")
+	}
+	if problem.Type != "https://example.com/probs/invalid-email" || problem.Title != "Invalid email" ||
+		problem.Status != 422 || problem.Detail != "The address jane@ is not valid" || problem.Instance != "/users/42" {
+		t.Errorf("Unexpected problem details: %+v", problem)
+	}
+	if string(problem.Extensions["field"]) != `"email"` {
+		t.Errorf("Expected extension member field, got %v", problem.Extensions)
+	}
+
+	var fromErr *httpio.ProblemDetails
+	if err := resp.Err(); !errors.As(err, &fromErr) || fromErr != problem {
+		t.Errorf("Expected Err to carry the problem details, got %v", err)
+	}
+}
+
+func TestResponseProblemIgnoresOtherContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"title": "not a problem document"}`))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := resp.Problem(); ok {
+		t.Error("Expected no problem details for application/json")
+	}
+	body, _ := resp.String()
+	if !strings.Contains(body, "not a problem document") {
+		t.Errorf("Expected the body to be left unread, got '%s'", body)
+	}
+}
+
+func TestResponseBodyReaderDecompresses(t *testing.T) {
+	content := strings.Repeat("compressed payload\n", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var encoder io.WriteCloser
+		switch r.URL.Query().Get("encoding") {
+		case "gzip":
+			encoder = gzip.NewWriter(w)
+		case "deflate":
+			encoder = zlib.NewWriter(w)
+		}
+		w.Header().Set("Content-Encoding", r.URL.Query().Get("encoding"))
+		encoder.Write([]byte(content))
+		encoder.Close()
+	}))
+	defer server.Close()
+
+	// Requesting compression explicitly stops the transport from decompressing the body
+	c := httpio.New().WithBaseURL(server.URL).WithHeader("Accept-Encoding", "gzip, deflate")
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		resp, err := c.GET(context.Background(), "/?encoding="+encoding)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		reader, err := resp.BodyReader()
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", encoding, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("Expected no read error for %s, got %v", encoding, err)
+		}
+		if string(data) != content {
+			t.Errorf("Expected decompressed %s body, got %d bytes", encoding, len(data))
+		}
+	}
+}
+
+func TestResponseDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Trace", "abc")
+		w.Write([]byte(`{"id":1,"tags":["a"]}`))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var dump strings.Builder
+	if err := resp.Dump(&dump, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{"HTTP/1.1 200 OK", "X-Trace: abc", "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}"} {
+		if !strings.Contains(dump.String(), expected) {
+			t.Errorf("Expected dump to contain %q, got:\n%s", expected, dump.String())
+		}
+	}
+
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := resp.JSON(&body); err != nil {
+		t.Fatalf("Expected body to remain readable, got %v", err)
+	}
+	if body.ID != 1 {
+		t.Errorf("Expected id 1, got %d", body.ID)
+	}
+}
+
+func TestResponseXML(t *testing.T) {
+	type item struct {
+		XMLName xml.Name `xml:"item"`
+		ID      int      `xml:"id,attr"`
+		Name    string   `xml:"name"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			// "café" encoded as ISO-8859-1
+			w.Header().Set("Content-Type", "application/xml; charset=ISO-8859-1")
+			w.Write([]byte("<item id=\"2\"><name>caf\xe9</name></item>"))
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<item id="3"><name>broken</item>`))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><item id="1"><name>widget</name></item>`))
+		}
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL)
+
+	resp, err := c.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var utf8Item item
+	if err := resp.XML(&utf8Item); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if utf8Item.ID != 1 || utf8Item.Name != "widget" {
+		t.Errorf("Expected item 1 widget, got %+v", utf8Item)
+	}
+
+	resp, err = c.GET(context.Background(), "/latin1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var latin1Item item
+	if err := resp.XML(&latin1Item); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if latin1Item.Name != "café" {
+		t.Errorf("Expected name café, got %q", latin1Item.Name)
+	}
+
+	resp, err = c.GET(context.Background(), "/invalid")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var invalid item
+	if err := resp.XML(&invalid); err == nil {
+		t.Error("Expected a decode error for malformed XML")
+	}
+}
+
+func TestResponseDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// Time spent streaming the body is not part of the duration
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	duration := resp.Duration()
+	if duration < 50*time.Millisecond || duration >= 150*time.Millisecond {
+		t.Errorf("Expected a duration of about 50ms, got %v", duration)
+	}
+
+	if _, err := resp.String(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.Duration() != duration {
+		t.Error("Expected reading the body not to change the duration")
+	}
+}
+
+// closeTrackingReader records whether the decoder reading from it was closed
+type closeTrackingReader struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTrackingReader) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRegisterContentDecoder(t *testing.T) {
+	var decoder *closeTrackingReader
+	httpio.RegisterContentDecoder("X-Base64", func(r io.Reader) io.Reader {
+		decoder = &closeTrackingReader{Reader: base64.NewDecoder(base64.StdEncoding, r)}
+		return decoder
+	})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("line one\nline two\n"))
+	gz.Close()
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unknown" {
+			w.Header().Set("Content-Encoding", "x-unknown")
+			w.Write([]byte("raw"))
+			return
+		}
+		// gzip applied first, then base64
+		w.Header().Set("Content-Encoding", "gzip, x-base64")
+		w.Write([]byte(encoded))
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithAutoDecompress(true)
+	resp, err := client.NewRequest("GET", server.URL).WithHeader("Accept-Encoding", "gzip").Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if body != "line one\nline two\n" {
+		t.Errorf("Expected decoded body, got %q", body)
+	}
+	if decoder == nil || !decoder.closed {
+		t.Error("Expected the registered decoder to be closed with the body")
+	}
+
+	var lines []string
+	err = client.NewRequest("GET", server.URL).WithHeader("Accept-Encoding", "gzip").
+		StreamLines(context.Background(), func(line []byte) error {
+			lines = append(lines, string(line))
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(lines) != 2 || lines[1] != "line two" {
+		t.Errorf("Expected decoded lines, got %q", lines)
+	}
+
+	// Unregistered encodings are left for the caller to handle
+	resp, err = client.GET(context.Background(), server.URL+"/unknown")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+	if got := resp.Header.Get("Content-Encoding"); got != "x-unknown" {
+		t.Errorf("Expected Content-Encoding to be kept, got %q", got)
+	}
+}
+
+func TestBrotliContentEncoding(t *testing.T) {
+	var decoders []*brotli.Reader
+	httpio.RegisterContentDecoder("br", func(r io.Reader) io.Reader {
+		decoder := brotli.NewReader(r)
+		decoders = append(decoders, decoder)
+		return decoder
+	})
+	t.Cleanup(func() {
+		httpio.RegisterContentDecoder("br", func(r io.Reader) io.Reader {
+			return brotli.NewReader(r)
+		})
+	})
+
+	var expected strings.Builder
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(&expected, "line %d\n", i)
+	}
+	// The lines above, compressed with quality 11
+	compressed, _ := hex.DecodeString("1b8601f81d09364ef2b9ef15cbb234363718fbbe455905e246453b905b7c504c9a89a8" +
+		"2c20015dfe0bab944bddb45dff08482cda58d74f4062d1c6ba7e06128b36d6f50b9058b4b1eefc7151")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(compressed)
+	}))
+	defer server.Close()
+
+	client := httpio.New().WithAutoDecompress(true)
+	resp, err := client.NewRequest("GET", server.URL).WithHeader("Accept-Encoding", "br").Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if body != expected.String() {
+		t.Errorf("Expected decoded body, got %q", body)
+	}
+
+	var lines []string
+	err = client.NewRequest("GET", server.URL).WithHeader("Accept-Encoding", "br").
+		StreamLines(context.Background(), func(line []byte) error {
+			lines = append(lines, string(line))
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(lines) != 50 || lines[49] != "line 50" {
+		t.Errorf("Expected 50 decoded lines, got %q", lines)
+	}
+
+	if len(decoders) != 2 {
+		t.Fatalf("Expected 2 decoders, got %d", len(decoders))
+	}
+	for _, decoder := range decoders {
+		if _, err := decoder.Read(make([]byte, 1)); !errors.Is(err, brotli.ErrClosed) {
+			t.Errorf("Expected the decoder to be closed with the body, got %v", err)
+		}
+	}
+}
+
+func TestResponseJSONAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"name":"a"}{"id":2,"name":"b"}` + "\n" + `  {"id":3,"name":"c"}`))
+	}))
+	defer server.Close()
+
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var items []item
+	if err := resp.JSONAll(&items); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	for i, it := range items {
+		if it.ID != i+1 {
+			t.Errorf("Expected item %d to have id %d, got %d", i, i+1, it.ID)
+		}
+	}
+	if items[2].Name != "c" {
+		t.Errorf("Expected last name c, got %q", items[2].Name)
+	}
+
+	resp, err = httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var notSlice item
+	if err := resp.JSONAll(&notSlice); err == nil {
+		t.Error("Expected an error for a non-slice target")
+	}
+}
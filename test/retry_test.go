@@ -3,7 +3,9 @@ package test
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -240,3 +242,191 @@ func TestRetryWithContextCancellation(t *testing.T) {
 		t.Errorf("Expected context cancellation to prevent all retries, got %d attempts", attempts)
 	}
 }
+
+func TestRetryOnRetryCallback(t *testing.T) {
+	type retryEvent struct {
+		attempt int
+		status  int
+		delay   time.Duration
+	}
+
+	var events []retryEvent
+
+	config := retry.DefaultConfig()
+	config.MaxRetries = 3
+	config.BaseDelay = 5 * time.Millisecond
+	config.OnRetry = func(attempt int, req *http.Request, resp *http.Response, err error, nextDelay time.Duration) {
+		if req == nil {
+			t.Error("Expected request to be passed to OnRetry")
+		}
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		events = append(events, retryEvent{attempt: attempt, status: status, delay: nextDelay})
+	}
+
+	attempts := 0
+	baseHandler := func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 4 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	resp, err := retry.New(config).Handle(baseHandler)(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	expected := []retryEvent{
+		{attempt: 1, status: http.StatusServiceUnavailable, delay: 5 * time.Millisecond},
+		{attempt: 2, status: http.StatusServiceUnavailable, delay: 10 * time.Millisecond},
+		{attempt: 3, status: http.StatusServiceUnavailable, delay: 20 * time.Millisecond},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d retry events, got %d", len(expected), len(events))
+	}
+	for i, want := range expected {
+		if events[i] != want {
+			t.Errorf("Expected event %d to be %+v, got %+v", i, want, events[i])
+		}
+	}
+}
+
+func TestRetryErrorCarriesAttemptHistory(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 2
+	config.BaseDelay = time.Millisecond
+
+	errTimeout := errors.New("upstream timeout")
+	attempts := 0
+	handler := retry.New(config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 2 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+		}
+		return nil, errTimeout
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/test", nil)
+	_, err := handler(context.Background(), req)
+
+	var retryErr *retry.RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected *retry.RetryError, got %v", err)
+	}
+	if retryErr.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", retryErr.Attempts)
+	}
+	if len(retryErr.History) != 3 || retryErr.History[1].StatusCode != http.StatusServiceUnavailable || retryErr.History[0].Err != errTimeout {
+		t.Errorf("Expected history of timeout, 503 and timeout, got %+v", retryErr.History)
+	}
+	if !errors.Is(err, errTimeout) {
+		t.Errorf("Expected error to unwrap to the last error, got %v", err)
+	}
+}
+
+func TestRetryRejectsNonReplayableBody(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 2
+	config.BaseDelay = time.Millisecond
+
+	attempts := 0
+	handler := retry.New(config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		attempts++
+		io.Copy(io.Discard, req.Body)
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+
+	// Hide the concrete reader type so no GetBody is available
+	body := io.MultiReader(strings.NewReader("payload"))
+	req, _ := http.NewRequest("POST", "http://example.com/test", body)
+	resp, err := handler(context.Background(), req)
+
+	if !errors.Is(err, retry.ErrBodyNotReplayable) {
+		t.Fatalf("Expected ErrBodyNotReplayable, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "GetBody") || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("Expected a descriptive error, got %q", err)
+	}
+	if resp != nil {
+		t.Error("Expected no response")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+
+	// Replayable bodies are retried as usual
+	attempts = 0
+	req, _ = http.NewRequest("POST", "http://example.com/test", strings.NewReader("payload"))
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryNonReplayableBodyWithoutRetry(t *testing.T) {
+	errRejected := errors.New("rejected")
+	tests := []struct {
+		name   string
+		config func(*retry.Config)
+		resp   *http.Response
+		err    error
+	}{
+		{
+			name:   "error rejected by predicate",
+			config: func(c *retry.Config) { c.ErrorPredicate = func(error) bool { return false } },
+			err:    errRejected,
+		},
+		{
+			name:   "error without predicate",
+			config: func(c *retry.Config) { c.ErrorPredicate = nil },
+			err:    errRejected,
+		},
+		{
+			name:   "no retries",
+			config: func(c *retry.Config) { c.MaxRetries = 0 },
+			resp:   &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := retry.DefaultConfig()
+			config.BaseDelay = time.Millisecond
+			tt.config(config)
+
+			attempts := 0
+			handler := retry.New(config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				attempts++
+				io.Copy(io.Discard, req.Body)
+				return tt.resp, tt.err
+			})
+
+			body := io.MultiReader(strings.NewReader("payload"))
+			req, _ := http.NewRequest("POST", "http://example.com/test", body)
+			resp, err := handler(context.Background(), req)
+
+			if errors.Is(err, retry.ErrBodyNotReplayable) {
+				t.Fatalf("Expected the outcome of the attempt, got %v", err)
+			}
+			if err != tt.err {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+			if resp != tt.resp {
+				t.Errorf("Expected the response of the attempt, got %v", resp)
+			}
+			if attempts != 1 {
+				t.Errorf("Expected a single attempt, got %d", attempts)
+			}
+		})
+	}
+}
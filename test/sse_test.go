@@ -1,12 +1,18 @@
 package test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/anggasct/httpio"
 	"github.com/anggasct/httpio/internal/client"
 )
 
@@ -268,3 +274,165 @@ data: Hello from server
 		t.Errorf("Expected event data to be 'Hello from server', got %s", event.Data)
 	}
 }
+
+func TestStreamSSEMux(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: update\ndata: u1\n\nevent: delete\ndata: d1\n\nevent: update\ndata: u2\n\nevent: ping\ndata: p1\n\ndata: plain\n\n"))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	var updates, deletes, messages, defaults []string
+	mux := client.NewEventMux().
+		On("update", func(event client.Event) error {
+			updates = append(updates, event.Data)
+			return nil
+		}).
+		On("delete", func(event client.Event) error {
+			deletes = append(deletes, event.Data)
+			return nil
+		}).
+		On("message", func(event client.Event) error {
+			messages = append(messages, event.Data)
+			return nil
+		}).
+		Default(func(event client.Event) error {
+			defaults = append(defaults, event.Event+":"+event.Data)
+			return nil
+		})
+
+	response := &client.Response{Response: resp}
+	if err := response.StreamSSEMux(mux); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(updates) != 2 || updates[0] != "u1" || updates[1] != "u2" {
+		t.Errorf("Expected update events [u1 u2], got %v", updates)
+	}
+	if len(deletes) != 1 || deletes[0] != "d1" {
+		t.Errorf("Expected delete events [d1], got %v", deletes)
+	}
+	if len(messages) != 1 || messages[0] != "plain" {
+		t.Errorf("Expected unnamed event routed as message, got %v", messages)
+	}
+	if len(defaults) != 1 || defaults[0] != "ping:p1" {
+		t.Errorf("Expected default handler to receive ping event, got %v", defaults)
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	event := client.Event{Event: "update", Data: `{"id": 7, "status": "ok"}`}
+
+	var payload struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := event.JSON(&payload); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if payload.ID != 7 || payload.Status != "ok" {
+		t.Errorf("Expected {7 ok}, got %+v", payload)
+	}
+
+	invalid := client.Event{Data: "not json"}
+	if err := invalid.JSON(&payload); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("Expected invalid JSON error, got %v", err)
+	}
+}
+
+func TestStreamSSEWithErrorDetector(t *testing.T) {
+	sseData := `data: {"delta": "Hel"}
+
+data: {"delta": "lo"}
+
+event: error
+data: {"error": {"code": "overloaded"}}
+
+`
+
+	reader := io.NopCloser(strings.NewReader(sseData))
+	handler := &testSSEHandler{}
+
+	err := client.StreamSSE(reader, handler, client.WithErrorDetector(func(raw json.RawMessage) bool {
+		return strings.HasPrefix(string(raw), `{"error"`)
+	}))
+
+	var streamErr *client.StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("Expected StreamError, got %v", err)
+	}
+	if len(handler.events) != 2 {
+		t.Errorf("Expected 2 events before the error, got %d", len(handler.events))
+	}
+	if !handler.closed {
+		t.Error("Expected OnClose to be called")
+	}
+}
+
+func TestStreamSSEBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+		}
+		flusher.Flush()
+		time.Sleep(150 * time.Millisecond)
+		fmt.Fprint(w, "data: 6\n\n")
+		flusher.Flush()
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var batches [][]string
+	err = resp.StreamSSEBatch(func(events []httpio.SSEEvent) error {
+		var batch []string
+		for _, event := range events {
+			batch = append(batch, event.Data)
+		}
+		batches = append(batches, batch)
+		return nil
+	}, 3, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A full batch, a partial batch flushed after maxWait and the rest at the end of the stream
+	want := [][]string{{"1", "2", "3"}, {"4", "5"}, {"6"}}
+	if fmt.Sprint(batches) != fmt.Sprint(want) {
+		t.Errorf("Expected batches %v, got %v", want, batches)
+	}
+}
+
+func TestStreamSSEBatchHandlerErrorStopsStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: 1\n\ndata: 2\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stopErr := errors.New("stop")
+	err = resp.StreamSSEBatch(func(events []httpio.SSEEvent) error {
+		return stopErr
+	}, 2, time.Second)
+	if !errors.Is(err, stopErr) {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+}
@@ -1,11 +1,22 @@
 package test
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/anggasct/httpio"
 	"github.com/anggasct/httpio/internal/client"
 )
 
@@ -81,6 +92,47 @@ func TestStreamLinesWithCustomDelimiter(t *testing.T) {
 	}
 }
 
+func TestStreamLinesWithKeepDelimiter(t *testing.T) {
+	data := "line 1\r\nline 2\nline 3"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	response := &client.Response{Response: resp}
+
+	var lines []string
+	total := 0
+	err = client.StreamLines(response, func(line []byte) error {
+		lines = append(lines, string(line))
+		total += len(line)
+		return nil
+	}, client.WithKeepDelimiter())
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"line 1\r\n", "line 2\n", "line 3"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Expected line %d to be %q, got %q", i, expected[i], line)
+		}
+	}
+
+	if total != len(data) {
+		t.Errorf("Expected lines to add up to %d bytes, got %d", len(data), total)
+	}
+}
+
 func TestStreamLinesWithContentType(t *testing.T) {
 	data := "line 1\nline 2"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -338,3 +390,421 @@ func TestWithByteDelimiter(t *testing.T) {
 		t.Error("Expected byte delimiter option to be created")
 	}
 }
+
+func TestStreamJSONWithReconnectResumesFromCursor(t *testing.T) {
+	var mu sync.Mutex
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		mu.Lock()
+		cursors = append(cursors, cursor)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher := w.(http.Flusher)
+
+		if cursor == "" {
+			w.Write([]byte(`{"id": 1}` + "\n" + `{"id": 2}` + "\n"))
+			flusher.Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		w.Write([]byte(`{"id": 3}` + "\n" + `{"id": 4}` + "\n"))
+	}))
+	defer server.Close()
+
+	c := httpio.New().WithBaseURL(server.URL)
+
+	config := httpio.DefaultReconnectConfig()
+	config.BaseDelay = 10 * time.Millisecond
+	config.Resume = func(lastRecord json.RawMessage) *httpio.Request {
+		var record struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(lastRecord, &record); err != nil {
+			return nil
+		}
+		return c.NewRequest("GET", "/feed").WithQuery("cursor", strconv.Itoa(record.ID))
+	}
+
+	var ids []int
+	err := c.NewRequest("GET", "/feed").StreamJSONWithReconnect(context.Background(), func(raw json.RawMessage) error {
+		var record struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		ids = append(ids, record.ID)
+		return nil
+	}, config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []int{1, 2, 3, 4}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected records %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected record %d to be %d, got %d", i, expected[i], ids[i])
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cursors) != 2 || cursors[1] != "2" {
+		t.Errorf("Expected resume with cursor 2, got %v", cursors)
+	}
+}
+
+func TestStreamLinesWithReconnectMaxRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		panic(http.ErrAbortHandler)
+	}))
+	defer server.Close()
+
+	config := httpio.DefaultReconnectConfig()
+	config.MaxRetries = 2
+	config.BaseDelay = 5 * time.Millisecond
+
+	err := httpio.New().WithBaseURL(server.URL).NewRequest("GET", "/logs").
+		StreamLinesWithReconnect(context.Background(), func(line []byte) error {
+			return nil
+		}, config)
+	if err == nil {
+		t.Fatal("Expected error after exhausting reconnect attempts")
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts (initial + 2 retries), got %d", got)
+	}
+}
+
+func TestStreamLinesWithReconnectRespectsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	config := httpio.DefaultReconnectConfig()
+	config.MaxRetries = 100
+	config.BaseDelay = time.Second
+
+	err := httpio.New().WithBaseURL(server.URL).NewRequest("GET", "/logs").
+		StreamLinesWithReconnect(ctx, func(line []byte) error {
+			return nil
+		}, config)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline exceeded, got %v", err)
+	}
+}
+
+func TestStreamJSONWithReconnectStopsAtLimit(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"id": 1}` + "\n" + `{"id": 2}` + "\n" + `{"id": 3}` + "\n"))
+	}))
+	defer server.Close()
+
+	config := httpio.DefaultReconnectConfig()
+	config.BaseDelay = time.Millisecond
+
+	received := 0
+	err := httpio.New().WithBaseURL(server.URL).NewRequest("GET", "/feed").
+		StreamJSONWithReconnect(context.Background(), func(raw json.RawMessage) error {
+			received++
+			return nil
+		}, config, httpio.WithMaxRecords(2))
+
+	var limitErr *httpio.StreamLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected *StreamLimitError, got %v", err)
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 records, got %d", received)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected no reconnect after the limit, got %d attempts", got)
+	}
+}
+
+func TestStreamJSONWithErrorDetector(t *testing.T) {
+	data := `{"id": 1}
+{"id": 2}
+{"error": "upstream unavailable"}
+{"id": 3}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	isError := func(raw json.RawMessage) bool {
+		var record map[string]interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false
+		}
+		_, ok := record["error"]
+		return ok
+	}
+
+	var ids []float64
+	err := httpio.New().NewRequest("GET", server.URL).
+		StreamJSON(context.Background(), func(raw json.RawMessage) error {
+			var record map[string]float64
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return err
+			}
+			ids = append(ids, record["id"])
+			return nil
+		}, httpio.WithErrorDetector(isError))
+
+	var streamErr *httpio.StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("Expected StreamError, got %v", err)
+	}
+	if string(streamErr.Raw) != `{"error": "upstream unavailable"}` {
+		t.Errorf("Expected error record in StreamError, got %s", streamErr.Raw)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 records before the error, got %v", ids)
+	}
+}
+
+func TestStreamIntoWithErrorDetector(t *testing.T) {
+	type Item struct {
+		ID int `json:"id"`
+	}
+
+	data := `{"id": 1}{"type": "error", "message": "quota exceeded"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	var items []Item
+	err = client.StreamInto(&client.Response{Response: resp}, func(item Item) error {
+		items = append(items, item)
+		return nil
+	}, client.WithErrorDetector(func(raw json.RawMessage) bool {
+		return strings.Contains(string(raw), `"type": "error"`)
+	}))
+
+	var streamErr *client.StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("Expected StreamError, got %v", err)
+	}
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("Expected only the first item to reach the handler, got %v", items)
+	}
+}
+
+func TestStreamWithMaxRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(w, "{\"id\": %d}\n", i)
+		}
+	}))
+	defer server.Close()
+
+	var received int
+	err := httpio.New().NewRequest("GET", server.URL).
+		StreamJSON(context.Background(), func(raw json.RawMessage) error {
+			received++
+			return nil
+		}, httpio.WithMaxRecords(10))
+
+	var limitErr *httpio.StreamLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected StreamLimitError, got %v", err)
+	}
+	if limitErr.Limit != "records" || limitErr.Max != 10 {
+		t.Errorf("Expected records limit of 10, got %+v", limitErr)
+	}
+	if received != 10 {
+		t.Errorf("Expected 10 records before halting, got %d", received)
+	}
+}
+
+func TestStreamWithMaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 100; i++ {
+			w.Write([]byte("0123456789\n"))
+		}
+	}))
+	defer server.Close()
+
+	var received int
+	err := httpio.New().NewRequest("GET", server.URL).
+		StreamLines(context.Background(), func(line []byte) error {
+			received += len(line)
+			return nil
+		}, httpio.WithMaxBytes(55))
+
+	var limitErr *httpio.StreamLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected StreamLimitError, got %v", err)
+	}
+	if limitErr.Limit != "bytes" || limitErr.Max != 55 {
+		t.Errorf("Expected bytes limit of 55, got %+v", limitErr)
+	}
+	if received != 50 {
+		t.Errorf("Expected 50 bytes (5 lines) before halting, got %d", received)
+	}
+}
+
+func TestStreamLinesLogicalContentTypeOfGzippedStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("{\"id\":1}\n{\"id\":2}\n"))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client := httpio.New()
+
+	resp, err := client.GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = resp.StreamLines(func(line []byte) error { return nil }, httpio.WithContentType("application/x-ndjson"))
+	if err == nil {
+		t.Error("Expected the transport content type check to fail")
+	}
+
+	resp, err = client.GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var lines []string
+	err = resp.StreamLines(func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}, httpio.WithLogicalContentType("application/x-ndjson"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(lines) != 2 || lines[0] != `{"id":1}` || lines[1] != `{"id":2}` {
+		t.Errorf("Expected two decompressed lines, got %q", lines)
+	}
+}
+
+func TestStreamLogicalContentTypeMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = resp.StreamJSON(func(raw json.RawMessage) error { return nil }, httpio.WithLogicalContentType("application/x-ndjson"))
+	if err == nil || !strings.Contains(err.Error(), "unexpected content type") {
+		t.Errorf("Expected unexpected content type error, got %v", err)
+	}
+}
+
+func TestResponseTransformLines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for _, line := range []string{"first", "skip", "second"} {
+			fmt.Fprintln(w, line)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	resp, err := httpio.New().GET(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	err = resp.TransformLines(recorder, func(line []byte) ([]byte, error) {
+		if string(line) == "skip" {
+			return nil, nil
+		}
+		return []byte(strings.ToUpper(string(line))), nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := recorder.Body.String(); got != "FIRST\nSECOND\n" {
+		t.Errorf("Expected transformed lines, got %q", got)
+	}
+	if !recorder.Flushed {
+		t.Error("Expected the writer to be flushed")
+	}
+}
+
+// trackingBody is a body that blocks until data is written to it and records closing
+type trackingBody struct {
+	*io.PipeReader
+	closed atomic.Bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed.Store(true)
+	return b.PipeReader.Close()
+}
+
+func TestStreamReturnsPromptlyOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	body := &trackingBody{PipeReader: pr}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/stream", nil)
+	resp := &client.Response{Response: &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: body, Request: req}}
+
+	go func() {
+		pw.Write([]byte("first chunk"))
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	var chunks int32
+	done := make(chan error, 1)
+	go func() {
+		done <- resp.Stream(func(chunk []byte) error {
+			atomic.AddInt32(&chunks, 1)
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Stream to return promptly after cancellation")
+	}
+
+	if atomic.LoadInt32(&chunks) != 1 {
+		t.Errorf("Expected 1 chunk before cancellation, got %d", chunks)
+	}
+	if !body.closed.Load() {
+		t.Error("Expected the body to be closed")
+	}
+}
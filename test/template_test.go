@@ -0,0 +1,59 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anggasct/httpio"
+)
+
+func TestFromTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected method POST, got %s", r.Method)
+		}
+		if r.URL.EscapedPath() != "/users/jane%20doe/repos/httpio" {
+			t.Errorf("Expected path /users/jane%%20doe/repos/httpio, got %s", r.URL.EscapedPath())
+		}
+		if got := r.URL.Query().Get("per_page"); got != "50" {
+			t.Errorf("Expected per_page=50, got '%s'", got)
+		}
+		if got := r.Header.Get("X-Api-Version"); got != "2" {
+			t.Errorf("Expected X-Api-Version 2, got '%s'", got)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	config := []byte(`{
+		"name": "star_repo",
+		"method": "post",
+		"path": "/users/{user}/repos/{repo}",
+		"headers": {"X-Api-Version": "2"},
+		"query": {"per_page": "50"}
+	}`)
+
+	var tmpl httpio.RequestTemplate
+	if err := json.Unmarshal(config, &tmpl); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	client := httpio.New().WithBaseURL(server.URL)
+	req := client.FromTemplate(tmpl.WithParams(map[string]string{"user": "jane doe", "repo": "httpio"}))
+
+	resp, err := req.Do(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if tmpl.Params != nil {
+		t.Error("Expected WithParams to leave the template untouched")
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+)
+
+// unreachableAddr returns the address of a listener whose accept backlog is full, so
+// further connection attempts hang until they time out
+func unreachableAddr(t *testing.T) string {
+	t.Helper()
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("Failed to bind socket: %v", err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("Failed to read socket address: %v", err)
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", sa.(*syscall.SockaddrInet4).Port)
+
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 50*time.Millisecond)
+		if err != nil {
+			return addr
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	addr := unreachableAddr(t)
+
+	client := httpio.New().
+		WithDialTimeout(50 * time.Millisecond).
+		WithTotalTimeout(time.Second)
+
+	start := time.Now()
+	_, err := client.GET(context.Background(), "http://"+addr+"/")
+	if !errors.Is(err, httpio.ErrDialTimeout) {
+		t.Fatalf("Expected ErrDialTimeout, got %v", err)
+	}
+	if errors.Is(err, httpio.ErrHeaderTimeout) {
+		t.Error("Expected dial timeout not to match ErrHeaderTimeout")
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected dial timeout before the total timeout, took %v", elapsed)
+	}
+}
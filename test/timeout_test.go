@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+)
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithResponseHeaderTimeout(50 * time.Millisecond).
+		WithTotalTimeout(time.Second)
+
+	_, err := client.GET(context.Background(), "/")
+	if !errors.Is(err, httpio.ErrHeaderTimeout) {
+		t.Fatalf("Expected ErrHeaderTimeout, got %v", err)
+	}
+	if errors.Is(err, httpio.ErrDialTimeout) {
+		t.Error("Expected header timeout not to match ErrDialTimeout")
+	}
+}
+
+func TestTotalTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithResponseHeaderTimeout(time.Second).
+		WithTotalTimeout(50 * time.Millisecond)
+
+	_, err := client.GET(context.Background(), "/")
+	if err == nil {
+		t.Fatal("Expected total timeout error, got nil")
+	}
+	if errors.Is(err, httpio.ErrHeaderTimeout) || errors.Is(err, httpio.ErrDialTimeout) {
+		t.Errorf("Expected total timeout not to match dial or header timeouts, got %v", err)
+	}
+}
+
+// slowStreamServer streams a line every 50ms for 300ms
+func slowStreamServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 6; i++ {
+			w.Write([]byte("tick\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+}
+
+func TestEffectiveDeadlineIsEarlierOfContextAndClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		clientTimeout time.Duration
+		ctxTimeout    time.Duration
+	}{
+		{"context deadline first", 5 * time.Second, 50 * time.Millisecond},
+		{"client timeout first", 50 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpio.New().WithTimeout(tt.clientTimeout)
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.ctxTimeout)
+			defer cancel()
+
+			start := time.Now()
+			_, err := client.GET(ctx, server.URL)
+			if err == nil {
+				t.Fatal("Expected a timeout error")
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected the earlier deadline to apply, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestStreamingRequestBoundedByContextDeadline(t *testing.T) {
+	server := slowStreamServer()
+	defer server.Close()
+
+	client := httpio.New().WithTimeout(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lines := 0
+	err := client.NewRequest("GET", server.URL).StreamLines(ctx, func(line []byte) error {
+		lines++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the stream to outlive the client timeout, got %v", err)
+	}
+	if lines != 6 {
+		t.Errorf("Expected 6 lines, got %d", lines)
+	}
+}
+
+func TestStreamingRequestWithoutDeadlineUsesClientTimeout(t *testing.T) {
+	server := slowStreamServer()
+	defer server.Close()
+
+	client := httpio.New().WithTimeout(100 * time.Millisecond)
+
+	err := client.NewRequest("GET", server.URL).StreamLines(context.Background(), func(line []byte) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected the client timeout to apply without a context deadline")
+	}
+}
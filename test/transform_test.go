@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware"
+	"github.com/anggasct/httpio/middleware/retry"
+	"github.com/anggasct/httpio/middleware/transform"
+)
+
+// reverseBytes returns a reversed copy of data
+func reverseBytes(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func TestTransformRoundTrip(t *testing.T) {
+	var received string
+	var receivedLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		receivedLength = r.ContentLength
+		// Echo the body back as received
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		transform    func([]byte) ([]byte, error)
+		wantReceived string
+	}{
+		{"identity", func(b []byte) ([]byte, error) { return b, nil }, "secret payload"},
+		{"reverse", reverseBytes, "daolyap terces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpio.New().
+				WithBaseURL(server.URL).
+				WithMiddleware(transform.New(&transform.Config{
+					Request:  tt.transform,
+					Response: tt.transform,
+				}))
+
+			resp, err := client.POST(context.Background(), "/", "secret payload")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if received != tt.wantReceived {
+				t.Errorf("Expected server to receive '%s', got '%s'", tt.wantReceived, received)
+			}
+			if receivedLength != int64(len(tt.wantReceived)) {
+				t.Errorf("Expected Content-Length %d, got %d", len(tt.wantReceived), receivedLength)
+			}
+
+			if got := resp.Header.Get("Content-Length"); got != strconv.Itoa(len("secret payload")) {
+				t.Errorf("Expected response Content-Length %d, got '%s'", len("secret payload"), got)
+			}
+			body, err := resp.String()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if body != "secret payload" {
+				t.Errorf("Expected round-tripped body 'secret payload', got '%s'", body)
+			}
+		})
+	}
+}
+
+func TestTransformSkipsStreamingResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(transform.New(&transform.Config{
+			Response:              reverseBytes,
+			StreamingContentTypes: []string{"text/event-stream"},
+		}))
+
+	resp, err := client.GET(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := resp.String()
+	if body != "data: hello\n\n" {
+		t.Errorf("Expected streaming body to be untouched, got '%s'", body)
+	}
+}
+
+func TestTransformSkipsStreamingResponsesByDefault(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(transform.New(&transform.Config{Response: reverseBytes}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := client.GET(ctx, "/events")
+	if err != nil {
+		t.Fatalf("Expected the stream to be returned before it ends, got %v", err)
+	}
+	defer resp.Close()
+	defer close(release)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: hello\n" {
+		t.Errorf("Expected the untouched first event, got %q (%v)", line, err)
+	}
+}
+
+func TestTransformRequestRetried(t *testing.T) {
+	var received []string
+	base := func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		received = append(received, string(body))
+		status := http.StatusOK
+		if len(received) == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	}
+
+	handler := middleware.Chain(base,
+		retry.New(&retry.Config{
+			MaxRetries:           2,
+			RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+			BaseDelay:            time.Millisecond,
+		}),
+		transform.New(&transform.Config{
+			Request: func(b []byte) ([]byte, error) { return append([]byte("ENC:"), b...), nil },
+		}),
+	)
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("x"))
+	resp, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if len(received) != 2 || received[0] != "ENC:x" || received[1] != "ENC:x" {
+		t.Errorf("Expected every attempt to send 'ENC:x', got %q", received)
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/anggasct/httpio"
+	"github.com/anggasct/httpio/middleware/urlnorm"
+)
+
+func TestURLNormalizationFixesMalformedURLs(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotQuery = r.URL.Query().Get("q")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpio.New().
+		WithBaseURL(server.URL).
+		WithMiddleware(urlnorm.New(nil))
+
+	resp, err := client.GET(context.Background(), "//users//jane doe?q=go lang")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Close()
+
+	if gotPath != "/users/jane%20doe" {
+		t.Errorf("Expected path /users/jane%%20doe, got %s", gotPath)
+	}
+	if gotQuery != "go lang" {
+		t.Errorf("Expected query 'go lang', got '%s'", gotQuery)
+	}
+}
+
+func TestURLNormalizationKeepsQueryOrder(t *testing.T) {
+	var got *url.URL
+	handler := urlnorm.New(nil).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		got = req.URL
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://api.example.com/search", nil)
+	req.URL.RawQuery = "z=1&a=b c&sig=x%2By&a=|"
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.RawQuery != "z=1&a=b%20c&sig=x%2By&a=%7C" {
+		t.Errorf("Expected only the invalid characters to be escaped in place, got %s", got.RawQuery)
+	}
+}
+
+func TestURLNormalizationLowercasesHost(t *testing.T) {
+	var got *url.URL
+	handler := urlnorm.New(nil).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		got = req.URL
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://API.Example.COM//files/a%2Fb", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Host != "api.example.com" {
+		t.Errorf("Expected host api.example.com, got %s", got.Host)
+	}
+	if got.EscapedPath() != "/files/a%2Fb" {
+		t.Errorf("Expected escaped slash to be kept, got %s", got.EscapedPath())
+	}
+}
+
+func TestURLNormalizationKeepsHostOverride(t *testing.T) {
+	var got *http.Request
+	handler := urlnorm.New(nil).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://10.0.0.1//users", nil)
+	req.Host = "api.example.com"
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.URL.Path != "/users" {
+		t.Errorf("Expected path /users, got %s", got.URL.Path)
+	}
+	if got.Host != "api.example.com" {
+		t.Errorf("Expected Host override api.example.com to be kept, got %q", got.Host)
+	}
+
+	// A Host taken from the URL follows the lowercased host
+	req, _ = http.NewRequest("GET", "http://API.Example.COM/users", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Host != "" || got.URL.Host != "api.example.com" {
+		t.Errorf("Expected host api.example.com, got Host %q and URL host %s", got.Host, got.URL.Host)
+	}
+}
+
+func TestURLNormalizationStrictRejects(t *testing.T) {
+	called := false
+	config := urlnorm.DefaultConfig()
+	config.Strict = true
+	handler := urlnorm.New(config).Handle(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	for _, rawURL := range []string{"http://example.com//users", "http://example.com/jane%20doe"} {
+		req, _ := http.NewRequest("GET", rawURL, nil)
+		_, err := handler(context.Background(), req)
+
+		var urlErr *urlnorm.Error
+		if !errors.Is(err, urlnorm.ErrMalformedURL) || !errors.As(err, &urlErr) {
+			t.Errorf("Expected *urlnorm.Error for %s, got %v", rawURL, err)
+		}
+	}
+	if called {
+		t.Error("Expected malformed requests not to be sent")
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com/users/42?q=go", nil)
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Errorf("Expected a well-formed URL to pass, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package httpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDialTimeout is returned when a connection to the server could not be established
+	// within the duration set by WithDialTimeout
+	ErrDialTimeout = errors.New("httpio: dial timeout")
+
+	// ErrHeaderTimeout is returned when the server did not send response headers within
+	// the duration set by WithResponseHeaderTimeout
+	ErrHeaderTimeout = errors.New("httpio: response header timeout")
+)
+
+// headerTimeoutMessage is the message of the error net/http returns when
+// Transport.ResponseHeaderTimeout elapses
+const headerTimeoutMessage = "timeout awaiting response headers"
+
+// WithDialTimeout limits how long establishing a connection may take.
+// Requests failing because of it return an error matching ErrDialTimeout.
+func (c *Client) WithDialTimeout(timeout time.Duration) *Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	c.transport().DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil && ctx.Err() == nil && isTimeout(err) {
+			return nil, fmt.Errorf("%w: %w", ErrDialTimeout, err)
+		}
+		return conn, err
+	}
+	return c
+}
+
+// WithResponseHeaderTimeout limits how long to wait for the response headers after the
+// request was written. Requests failing because of it return an error matching ErrHeaderTimeout.
+func (c *Client) WithResponseHeaderTimeout(timeout time.Duration) *Client {
+	c.transport().ResponseHeaderTimeout = timeout
+	return c
+}
+
+// WithTotalTimeout limits the whole exchange, from dialing to reading the response body.
+// It is equivalent to WithTimeout.
+func (c *Client) WithTotalTimeout(timeout time.Duration) *Client {
+	return c.WithTimeout(timeout)
+}
+
+// classifyTimeout wraps transport timeout errors with the matching sentinel error
+func classifyTimeout(err error) error {
+	if err == nil || errors.Is(err, ErrDialTimeout) || errors.Is(err, ErrHeaderTimeout) {
+		return err
+	}
+	if isTimeout(err) && strings.Contains(err.Error(), headerTimeoutMessage) {
+		return fmt.Errorf("%w: %w", ErrHeaderTimeout, err)
+	}
+	return err
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,48 @@
+package httpio
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// WithTransport replaces the client's transport. Options that affect the transport, such
+// as WithConnectionPool or WithDialTimeout, modify the given transport in place.
+func (c *Client) WithTransport(transport *http.Transport) *Client {
+	c.client.Transport = transport
+	return c
+}
+
+// ConfigureTransport calls configure with the client's transport so settings that have no
+// dedicated option, e.g. ForceAttemptHTTP2 or WriteBufferSize, can be inspected and changed
+func (c *Client) ConfigureTransport(configure func(*http.Transport)) *Client {
+	configure(c.transport())
+	return c
+}
+
+// WithUnixSocket sends every request over the unix domain socket at path, whatever the
+// host of the URL, e.g. to talk to the Docker daemon with http://unix/v1.41/containers/json.
+// Proxies are not used. A dial timeout set with WithDialTimeout beforehand still applies.
+func (c *Client) WithUnixSocket(path string) *Client {
+	transport := c.transport()
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dial(ctx, "unix", path)
+	}
+	transport.Proxy = nil
+	return c
+}
+
+// transport returns the client's *http.Transport, installing a clone of the default
+// transport when none is configured
+func (c *Client) transport() *http.Transport {
+	if transport, ok := c.client.Transport.(*http.Transport); ok {
+		return transport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c.client.Transport = transport
+	return transport
+}
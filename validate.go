@@ -0,0 +1,45 @@
+package httpio
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/anggasct/httpio/middleware"
+)
+
+// ErrDuplicateMiddleware is matched by the error returned by Validate when the same kind of
+// middleware was added to the chain more than once
+var ErrDuplicateMiddleware = errors.New("httpio: duplicate middleware")
+
+// Middlewares returns a copy of the client's middleware chain, outermost first
+func (c *Client) Middlewares() []middleware.Middleware {
+	return append([]middleware.Middleware(nil), c.middlewares...)
+}
+
+// Validate checks the client configuration for likely mistakes, such as the same middleware
+// type added several times, e.g. from inside a loop. Function-based middlewares created with
+// middleware.WrapMiddleware or middleware.Compose are not compared, since their type says
+// nothing about what they do.
+func (c *Client) Validate() error {
+	seen := make(map[reflect.Type]int)
+	for i, m := range c.middlewares {
+		t := reflect.TypeOf(m)
+		if isGenericMiddleware(t) {
+			continue
+		}
+		if first, ok := seen[t]; ok {
+			return fmt.Errorf("%w: %v at positions %d and %d", ErrDuplicateMiddleware, t, first, i)
+		}
+		seen[t] = i
+	}
+	return nil
+}
+
+// isGenericMiddleware reports whether t is one of the function adapters of the middleware package
+func isGenericMiddleware(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() == reflect.TypeOf((*middleware.Middleware)(nil)).Elem().PkgPath()
+}